@@ -12,6 +12,7 @@ func main() {
 		ServiceVersion: variables.ServiceVersion(),
 		Environment:    variables.Environment(),
 		LogLevel:       variables.LogLevel(),
+		RedactKeys:     variables.LogRedactKeys(),
 	})
 
 	defer logger.Sync()
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/health"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/app/transport/presenter"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(services *domain.Services) *HealthHandler {
+	return &HealthHandler{
+		checker: services.HealthChecker,
+	}
+}
+
+func (h *HealthHandler) Configure(server *echo.Echo) {
+	server.GET("/health/live", h.Live)
+	server.GET("/health/ready", h.Ready)
+}
+
+// Live answers as long as the process serves requests; it checks no
+// dependency, so an outage elsewhere never gets the service restarted.
+func (h *HealthHandler) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, outbound.HealthResponse{Healthy: true})
+}
+
+// Ready reports every dependency and answers 503 while a required one is
+// down, so traffic is routed elsewhere until it recovers.
+func (h *HealthHandler) Ready(c echo.Context) error {
+	report := h.checker.Check(c.Request().Context())
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	return c.JSON(status, presenter.Health(report))
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/account"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"lucassaraiva5/api-pay/internal/app/transport/mapper"
+	"lucassaraiva5/api-pay/internal/app/transport/presenter"
+	"lucassaraiva5/api-pay/internal/app/transport/validation"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MerchantHandler struct {
+	service        *merchant.Service
+	accounts       *account.Service
+	admin          echo.MiddlewareFunc
+	authentication echo.MiddlewareFunc
+}
+
+func NewMerchantHandler(services *domain.Services, admin echo.MiddlewareFunc, authentication echo.MiddlewareFunc) *MerchantHandler {
+	return &MerchantHandler{
+		service:        services.MerchantService,
+		accounts:       services.AccountService,
+		admin:          admin,
+		authentication: authentication,
+	}
+}
+
+func (h *MerchantHandler) Configure(server *echo.Echo) {
+	server.POST("/merchants", h.CreateMerchant, h.admin)
+	server.GET("/merchant", h.GetMerchant, h.authentication)
+	server.POST("/merchant/keys", h.IssueKey, h.authentication)
+	server.DELETE("/merchant/keys/:id", h.RevokeKey, h.authentication)
+	server.GET("/merchant/providers", h.ListProviderAccounts, h.authentication)
+	server.PUT("/merchant/providers/:provider", h.ConfigureProviderAccount, h.authentication)
+	server.DELETE("/merchant/providers/:provider", h.RemoveProviderAccount, h.authentication)
+}
+
+// CreateMerchant registers a merchant with its test and live keys. The keys
+// are only returned here.
+func (h *MerchantHandler) CreateMerchant(c echo.Context) error {
+	var request inbound.CreateMerchantRequest
+	if err := c.Bind(&request); err != nil {
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	if err := validation.CreateMerchant(&request); err != nil {
+		return err
+	}
+
+	created, err := h.service.Create(c.Request().Context(), request.Name)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, presenter.Merchant(created))
+}
+
+// GetMerchant returns the authenticated merchant and its keys, without their
+// values.
+func (h *MerchantHandler) GetMerchant(c echo.Context) error {
+	current, err := h.service.Current(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.Merchant(current))
+}
+
+// IssueKey creates a key in the mode of the key authenticating the request.
+// The key is only returned here.
+func (h *MerchantHandler) IssueKey(c echo.Context) error {
+	var request inbound.IssueKeyRequest
+	if err := c.Bind(&request); err != nil {
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	if err := validation.IssueKey(&request); err != nil {
+		return err
+	}
+
+	key, err := h.service.IssueKey(c.Request().Context(), merchant.KeyType(request.Type))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, presenter.APIKey(key))
+}
+
+func (h *MerchantHandler) RevokeKey(c echo.Context) error {
+	if err := h.service.RevokeKey(c.Request().Context(), c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListProviderAccounts returns the provider accounts of the mode of the key
+// authenticating the request, with their credentials masked.
+func (h *MerchantHandler) ListProviderAccounts(c echo.Context) error {
+	accounts, err := h.accounts.List(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.ProviderAccounts(accounts))
+}
+
+// ConfigureProviderAccount creates or replaces the merchant's account with a
+// provider, in the mode of the key authenticating the request.
+func (h *MerchantHandler) ConfigureProviderAccount(c echo.Context) error {
+	var request inbound.ConfigureProviderAccountRequest
+	if err := c.Bind(&request); err != nil {
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	if err := validation.ConfigureProviderAccount(&request); err != nil {
+		return err
+	}
+
+	configured, err := h.accounts.Configure(c.Request().Context(), c.Param("provider"), mapper.SettingsFromConfigureProviderAccountRequest(&request))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.ProviderAccount(configured))
+}
+
+func (h *MerchantHandler) RemoveProviderAccount(c echo.Context) error {
+	if err := h.accounts.Remove(c.Request().Context(), c.Param("provider")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
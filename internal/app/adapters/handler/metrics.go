@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"lucassaraiva5/api-pay/internal/infra/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MetricsHandler struct{}
+
+func NewMetricsHandler() *MetricsHandler {
+	return &MetricsHandler{}
+}
+
+// Configure exposes the metrics for Prometheus to scrape.
+func (h *MetricsHandler) Configure(server *echo.Echo) {
+	server.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+}
@@ -1,66 +1,148 @@
 package handler
 
 import (
+	"errors"
 	"lucassaraiva5/api-pay/internal/app/domain"
 	"net/http"
 
 	"lucassaraiva5/api-pay/internal/app/domain/payment"
 	"lucassaraiva5/api-pay/internal/app/transport/inbound"
 	"lucassaraiva5/api-pay/internal/app/transport/mapper"
+	"lucassaraiva5/api-pay/internal/app/transport/presenter"
+	"lucassaraiva5/api-pay/internal/app/transport/validation"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
 
 	"github.com/labstack/echo/v4"
 )
 
 type PaymentHandler struct {
-	service *payment.Service
+	service        *payment.Service
+	authentication echo.MiddlewareFunc
+	rateLimit      echo.MiddlewareFunc
+	idempotency    echo.MiddlewareFunc
 }
 
-func NewPaymentHandler(services *domain.Services) *PaymentHandler {
-	return &PaymentHandler{service: services.PaymentService}
+func NewPaymentHandler(services *domain.Services, authentication echo.MiddlewareFunc, rateLimit echo.MiddlewareFunc, idempotency echo.MiddlewareFunc) *PaymentHandler {
+	return &PaymentHandler{
+		service:        services.PaymentService,
+		authentication: authentication,
+		rateLimit:      rateLimit,
+		idempotency:    idempotency,
+	}
 }
 
 func (h *PaymentHandler) Configure(server *echo.Echo) {
-	server.POST("/payments", h.CreatePayment)
-	server.POST("/refunds", h.RefundPayment)
-	server.GET("/payments/:id", h.GetPayment)
+	server.POST("/payments", h.CreatePayment, h.authentication, h.rateLimit, h.idempotency)
+	server.POST("/refunds", h.RefundPayment, h.authentication, h.rateLimit, h.idempotency)
+	server.POST("/payments/:id/refunds", h.RefundPayment, h.authentication, h.rateLimit, h.idempotency)
+	server.POST("/payments/:id/capture", h.CapturePayment, h.authentication, h.rateLimit, h.idempotency)
+	server.POST("/payments/:id/cancel", h.CancelPayment, h.authentication, h.rateLimit, h.idempotency)
+	server.GET("/payments/:id", h.GetPayment, h.authentication, h.rateLimit)
+	server.GET("/payments/:id/events", h.GetStatusHistory, h.authentication, h.rateLimit)
 }
 
 func (h *PaymentHandler) CreatePayment(c echo.Context) error {
 	var request inbound.CreatePaymentRequest
 	if err := c.Bind(&request); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	if err := validation.CreatePayment(&request); err != nil {
+		return err
 	}
 
-	payment := mapper.PaymentFromCreatePaymentRequest(&request)
-	result, err := h.service.ProcessPayment(c.Request().Context(), payment)
+	newPayment, err := mapper.PaymentFromCreatePaymentRequest(&request)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return validation.ErrInvalidRequest.Wrap(err)
+	}
+
+	result, err := h.service.ProcessPayment(c.Request().Context(), newPayment)
+	if errors.Is(err, payment.ErrOutcomeUnknown) {
+		return c.JSON(http.StatusAccepted, presenter.Payment(result))
 	}
 
-	return c.JSON(http.StatusOK, result)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.Payment(result))
 }
 
+// RefundPayment refunds the payment named in the path, or in the body on the
+// legacy /refunds route. Without an amount the remaining balance is refunded.
 func (h *PaymentHandler) RefundPayment(c echo.Context) error {
-	var refund payment.Refund
-	if err := c.Bind(&refund); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	var request inbound.RefundRequest
+	if err := c.Bind(&request); err != nil {
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	paymentID := request.ID
+	if id := c.Param("id"); id != "" {
+		paymentID = id
+	}
+
+	result, err := h.service.RefundPayment(c.Request().Context(), paymentID, request.Amount, request.Reason)
+	if errors.Is(err, payment.ErrOutcomeUnknown) {
+		return c.JSON(http.StatusAccepted, presenter.Payment(result))
 	}
 
-	paymentID := refund.ID
-	result, err := h.service.RefundPayment(c.Request().Context(), paymentID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
-	return c.JSON(http.StatusOK, result)
+	return c.JSON(http.StatusOK, presenter.Payment(result))
+}
+
+// CapturePayment captures an authorized payment. Without an amount the whole
+// authorization is captured.
+func (h *PaymentHandler) CapturePayment(c echo.Context) error {
+	var request inbound.CaptureRequest
+	if err := c.Bind(&request); err != nil {
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	result, err := h.service.CapturePayment(c.Request().Context(), c.Param("id"), request.Amount)
+	if errors.Is(err, payment.ErrOutcomeUnknown) {
+		return c.JSON(http.StatusAccepted, presenter.Payment(result))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.Payment(result))
+}
+
+func (h *PaymentHandler) CancelPayment(c echo.Context) error {
+	result, err := h.service.CancelPayment(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, payment.ErrOutcomeUnknown) {
+		return c.JSON(http.StatusAccepted, presenter.Payment(result))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.Payment(result))
 }
 
 func (h *PaymentHandler) GetPayment(c echo.Context) error {
 	paymentID := c.Param("id")
 	result, err := h.service.GetPayment(c.Request().Context(), paymentID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.Payment(result))
+}
+
+// GetStatusHistory returns every status the payment went through, oldest
+// first.
+func (h *PaymentHandler) GetStatusHistory(c echo.Context) error {
+	history, err := h.service.StatusHistory(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, result)
+	return c.JSON(http.StatusOK, presenter.StatusHistory(history))
 }
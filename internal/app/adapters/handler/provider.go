@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"io"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxWebhookSize bounds the provider webhook bodies read into memory.
+const maxWebhookSize = 1 << 20
+
+type ProviderHandler struct {
+	service        *payment.Service
+	authentication echo.MiddlewareFunc
+}
+
+// NewProviderHandler takes the authentication of the provider status; the
+// provider webhooks authenticate with their own signatures.
+func NewProviderHandler(services *domain.Services, authentication echo.MiddlewareFunc) *ProviderHandler {
+	return &ProviderHandler{
+		service:        services.PaymentService,
+		authentication: authentication,
+	}
+}
+
+func (h *ProviderHandler) Configure(server *echo.Echo) {
+	server.GET("/providers/status", h.GetStatus, h.authentication)
+
+	for _, provider := range h.service.Providers.Ordered() {
+		if receiver, ok := provider.(payment.WebhookReceiver); ok {
+			server.POST("/webhooks/"+provider.Name(), h.ReceiveWebhook(receiver))
+		}
+	}
+}
+
+// GetStatus lists the circuit breaker state of every configured provider.
+func (h *ProviderHandler) GetStatus(c echo.Context) error {
+	statuses := make([]payment.BreakerStatus, 0)
+	for _, provider := range h.service.Providers.Ordered() {
+		status := payment.BreakerStatus{Provider: provider.Name(), State: payment.BreakerClosed}
+		if h.service.Breakers != nil {
+			status = h.service.Breakers.For(provider.Name()).Status()
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return c.JSON(http.StatusOK, statuses)
+}
+
+// ReceiveWebhook authenticates a provider webhook and applies it to its
+// payment. Events for unknown payments are acknowledged so the provider
+// stops retrying them; failures to apply one are not, so it is sent again.
+func (h *ProviderHandler) ReceiveWebhook(receiver payment.WebhookReceiver) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookSize))
+		if err != nil {
+			return apperror.ErrMalformedRequest.Wrap(err)
+		}
+
+		event, err := receiver.ParseWebhook(c.Request().Header, body)
+		if err != nil {
+			return err
+		}
+
+		outcome, err := h.service.HandleProviderEvent(c.Request().Context(), event)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, outbound.ProviderWebhookResponse{Outcome: outcome})
+	}
+}
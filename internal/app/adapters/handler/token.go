@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"lucassaraiva5/api-pay/internal/app/transport/mapper"
+	"lucassaraiva5/api-pay/internal/app/transport/presenter"
+	"lucassaraiva5/api-pay/internal/app/transport/validation"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TokenHandler struct {
+	service        *vault.Service
+	authentication echo.MiddlewareFunc
+	rateLimit      echo.MiddlewareFunc
+	idempotency    echo.MiddlewareFunc
+}
+
+func NewTokenHandler(services *domain.Services, authentication echo.MiddlewareFunc, rateLimit echo.MiddlewareFunc, idempotency echo.MiddlewareFunc) *TokenHandler {
+	return &TokenHandler{
+		service:        services.VaultService,
+		authentication: authentication,
+		rateLimit:      rateLimit,
+		idempotency:    idempotency,
+	}
+}
+
+func (h *TokenHandler) Configure(server *echo.Echo) {
+	server.POST("/tokens", h.CreateToken, h.authentication, h.rateLimit, h.idempotency)
+}
+
+// CreateToken stores the card in the vault and returns the token to charge
+// it with. The response never carries the card number or CVV.
+func (h *TokenHandler) CreateToken(c echo.Context) error {
+	var request inbound.CreateTokenRequest
+	if err := c.Bind(&request); err != nil {
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	if err := validation.CreateToken(&request); err != nil {
+		return err
+	}
+
+	token, err := h.service.Tokenize(c.Request().Context(), mapper.CardFromCreateTokenRequest(&request))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, presenter.Token(token))
+}
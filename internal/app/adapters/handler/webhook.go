@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/webhook"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"lucassaraiva5/api-pay/internal/app/transport/presenter"
+	"lucassaraiva5/api-pay/internal/app/transport/validation"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// deliveriesLimit bounds the delivery log listed for an endpoint.
+const deliveriesLimit = 100
+
+type WebhookHandler struct {
+	service        *webhook.Service
+	authentication echo.MiddlewareFunc
+}
+
+func NewWebhookHandler(services *domain.Services, authentication echo.MiddlewareFunc) *WebhookHandler {
+	return &WebhookHandler{
+		service:        services.WebhookService,
+		authentication: authentication,
+	}
+}
+
+func (h *WebhookHandler) Configure(server *echo.Echo) {
+	server.POST("/webhooks/endpoints", h.CreateEndpoint, h.authentication)
+	server.GET("/webhooks/endpoints", h.ListEndpoints, h.authentication)
+	server.DELETE("/webhooks/endpoints/:id", h.DeleteEndpoint, h.authentication)
+	server.GET("/webhooks/endpoints/:id/deliveries", h.ListDeliveries, h.authentication)
+	server.GET("/webhooks/deliveries/:id", h.GetDelivery, h.authentication)
+	server.POST("/webhooks/deliveries/:id/redeliver", h.Redeliver, h.authentication)
+}
+
+// CreateEndpoint registers a merchant URL for payment events. The signing
+// secret is only returned here.
+func (h *WebhookHandler) CreateEndpoint(c echo.Context) error {
+	var request inbound.CreateWebhookEndpointRequest
+	if err := c.Bind(&request); err != nil {
+		return apperror.ErrMalformedRequest.Wrap(err)
+	}
+
+	if err := validation.CreateWebhookEndpoint(&request); err != nil {
+		return err
+	}
+
+	endpoint, err := h.service.CreateEndpoint(c.Request().Context(), request.URL, request.Events)
+	if err != nil {
+		return err
+	}
+
+	response := presenter.WebhookEndpoint(endpoint)
+	response.Secret = endpoint.Secret
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+func (h *WebhookHandler) ListEndpoints(c echo.Context) error {
+	endpoints, err := h.service.Endpoints(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.WebhookEndpoints(endpoints))
+}
+
+func (h *WebhookHandler) DeleteEndpoint(c echo.Context) error {
+	if err := h.service.DeleteEndpoint(c.Request().Context(), c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListDeliveries returns the latest deliveries of an endpoint, newest first.
+func (h *WebhookHandler) ListDeliveries(c echo.Context) error {
+	deliveries, err := h.service.Deliveries(c.Request().Context(), c.Param("id"), deliveriesLimit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.WebhookDeliveries(deliveries))
+}
+
+// GetDelivery returns a delivery with the log of its attempts.
+func (h *WebhookHandler) GetDelivery(c echo.Context) error {
+	delivery, err := h.service.Delivery(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenter.WebhookDelivery(delivery))
+}
+
+// Redeliver queues the delivery to be sent again by the dispatcher.
+func (h *WebhookHandler) Redeliver(c echo.Context) error {
+	delivery, err := h.service.Redeliver(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, presenter.WebhookDelivery(delivery))
+}
@@ -1,22 +1,56 @@
 package adapters
 
 import (
+	"context"
 	"lucassaraiva5/api-pay/internal/app/adapters/handler"
 	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
 
 	"github.com/labstack/echo/v4"
 )
 
 type Handlers struct {
-	payment *handler.PaymentHandler
+	health   *handler.HealthHandler
+	merchant *handler.MerchantHandler
+	metrics  *handler.MetricsHandler
+	payment  *handler.PaymentHandler
+	provider *handler.ProviderHandler
+	token    *handler.TokenHandler
+	webhook  *handler.WebhookHandler
 }
 
-func NewHandlers(services *domain.Services) *Handlers {
+func NewHandlers(services *domain.Services, databases *database.Databases) *Handlers {
+	// Publishable keys are meant for browsers and apps, so they may only
+	// tokenize cards; everything else needs a secret key.
+	anyKey := middleware.ConfigAuthentication(services.MerchantService.Authenticate)
+	secretKey := middleware.ConfigAuthentication(services.MerchantService.AuthenticateSecret)
+	admin := middleware.ConfigAuthentication(services.MerchantService.AuthenticateAdmin)
+
+	idempotency := middleware.ConfigIdempotency(databases.Redis, func(ctx context.Context) string {
+		return merchant.ScopeFrom(ctx).String()
+	})
+
+	rateLimit := middleware.ConfigRateLimit(databases.Redis, rateLimits(services.VaultService)...)
+
 	return &Handlers{
-		payment: handler.NewPaymentHandler(services),
+		health:   handler.NewHealthHandler(services),
+		merchant: handler.NewMerchantHandler(services, admin, secretKey),
+		metrics:  handler.NewMetricsHandler(),
+		payment:  handler.NewPaymentHandler(services, secretKey, rateLimit, idempotency),
+		provider: handler.NewProviderHandler(services, secretKey),
+		token:    handler.NewTokenHandler(services, anyKey, rateLimit, idempotency),
+		webhook:  handler.NewWebhookHandler(services, secretKey),
 	}
 }
 
 func (h *Handlers) Configure(server *echo.Echo) {
+	h.health.Configure(server)
+	h.merchant.Configure(server)
+	h.metrics.Configure(server)
 	h.payment.Configure(server)
+	h.provider.Configure(server)
+	h.token.Configure(server)
+	h.webhook.Configure(server)
 }
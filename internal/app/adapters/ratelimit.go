@@ -0,0 +1,72 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
+	"lucassaraiva5/api-pay/internal/infra/variables"
+
+	"github.com/labstack/echo/v4"
+)
+
+// rateLimits counts requests per merchant key and per IP, and the cards
+// tokenized or charged per card, so a leaked key cannot be used to test
+// stolen cards.
+func rateLimits(vaultService *vault.Service) []middleware.RateLimit {
+	return []middleware.RateLimit{
+		{Name: "merchant", Limit: variables.RateLimitMerchant(), Window: variables.RateLimitWindow(), Key: merchantKey},
+		{Name: "ip", Limit: variables.RateLimitIP(), Window: variables.RateLimitWindow(), Key: ipKey},
+		{Name: "card", Limit: variables.RateLimitCard(), Window: variables.RateLimitCardWindow(), Key: cardKey(vaultService)},
+	}
+}
+
+func merchantKey(c echo.Context) (string, error) {
+	return merchant.ScopeFrom(c.Request().Context()).String(), nil
+}
+
+func ipKey(c echo.Context) (string, error) {
+	return c.RealIP(), nil
+}
+
+// cardKey reads the card of token and payment requests, by number or token,
+// leaving the body for the handler. Requests without a card, or with a body
+// the handler will refuse, are not counted.
+func cardKey(vaultService *vault.Service) func(c echo.Context) (string, error) {
+	return func(c echo.Context) (string, error) {
+		req := c.Request()
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", apperror.ErrMalformedRequest.Wrap(err)
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var card struct {
+			Number string         `json:"number"`
+			Method inbound.Method `json:"method"`
+		}
+
+		if len(body) == 0 || json.Unmarshal(body, &card) != nil {
+			return "", nil
+		}
+
+		number := card.Number
+		if number == "" {
+			number = card.Method.Card.Number
+		}
+
+		fingerprint, err := vaultService.CardFingerprint(req.Context(), number, card.Method.Token)
+		if err != nil || fingerprint == "" {
+			return "", err
+		}
+
+		// Test cards are shared by every merchant, so cards are counted per
+		// merchant and mode.
+		return merchant.ScopeFrom(req.Context()).String() + ":" + fingerprint, nil
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"lucassaraiva5/api-pay/internal/app/adapters"
 	"lucassaraiva5/api-pay/internal/app/domain"
 	"lucassaraiva5/api-pay/internal/infra/aws"
+	"lucassaraiva5/api-pay/internal/infra/database"
 	"lucassaraiva5/api-pay/internal/infra/logger"
 	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/metrics"
 	"lucassaraiva5/api-pay/internal/infra/server"
+	"lucassaraiva5/api-pay/internal/infra/tracing"
 	"lucassaraiva5/api-pay/internal/infra/variables"
 	"sync"
 	"time"
@@ -21,9 +24,10 @@ type App struct {
 	running bool
 	locker  sync.Mutex
 
-	server   *echo.Echo
-	handlers *adapters.Handlers
-	services *domain.Services
+	server    *echo.Echo
+	handlers  *adapters.Handlers
+	services  *domain.Services
+	databases *database.Databases
 }
 
 var app = new(App)
@@ -99,14 +103,48 @@ func (app *App) startServer(start time.Time) {
 }
 
 func (app *App) build() {
+	if err := tracing.Init(&tracing.Option{
+		ServiceName:    variables.ServiceName(),
+		ServiceVersion: variables.ServiceVersion(),
+		Environment:    variables.Environment(),
+		Exporter:       variables.TracingExporter(),
+		Endpoint:       variables.TracingEndpoint(),
+		SampleRatio:    variables.TracingSampleRate(),
+	}); err != nil {
+		logger.Fatal(context.Background(), "Error configuring tracing", attributes.New().WithError(err))
+	}
+
+	app.databases = database.NewDatabases()
+
+	if err := database.Migrate(context.Background(), app.databases.Write); err != nil {
+		logger.Fatal(context.Background(), "Error applying database migrations", attributes.New().WithError(err))
+	}
+
+	metrics.RegisterDatabase("read", app.databases.Read.Connection())
+	metrics.RegisterDatabase("write", app.databases.Write.Connection())
 
-	app.services = domain.NewServices()
-	app.handlers = adapters.NewHandlers(app.services)
+	app.services = domain.NewServices(app.databases)
+	if !variables.IsLambda() {
+		app.services.Reconciler.Start()
+		app.services.HealthProber.Start()
+		app.services.Dispatcher.Start()
+	}
+	app.handlers = adapters.NewHandlers(app.services, app.databases)
 	app.server = server.New()
 	app.handlers.Configure(app.server)
 }
 
 func (app *App) dispose() {
+	app.services.Reconciler.Stop()
+	app.services.HealthProber.Stop()
+	app.services.Dispatcher.Stop()
+	app.databases.Close()
+
+	if err := tracing.Shutdown(context.Background()); err != nil {
+		logger.Error(context.Background(), "Error flushing traces", attributes.New().WithError(err))
+	}
+
+	app.databases = nil
 	app.server = nil
 	app.handlers = nil
 	app.services = nil
@@ -0,0 +1,28 @@
+package account
+
+import "lucassaraiva5/api-pay/internal/app/domain/payment"
+
+// StoredAccount is a merchant's provider account as persisted: its
+// credentials are encrypted by the key KeyID and never stored in clear.
+type StoredAccount struct {
+	MerchantID          string `json:"merchantId"`
+	Livemode            bool   `json:"livemode"`
+	Provider            string `json:"provider"`
+	BaseURL             string `json:"baseUrl"`
+	Enabled             bool   `json:"enabled"`
+	Priority            int    `json:"priority"`
+	StatementDescriptor string `json:"statementDescriptor"`
+	KeyID               string `json:"keyId"`
+	Ciphertext          []byte `json:"ciphertext"`
+	CreatedAt           string `json:"createdAt"`
+	UpdatedAt           string `json:"updatedAt"`
+}
+
+// Settings configure a merchant's account with a provider.
+type Settings struct {
+	BaseURL             string
+	Credentials         payment.Credentials
+	Enabled             bool
+	Priority            int
+	StatementDescriptor string
+}
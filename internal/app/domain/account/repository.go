@@ -0,0 +1,31 @@
+package account
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrNotFound          = apperror.New(http.StatusNotFound, "provider_account_not_found", "provider account not found")
+	ErrUnknownProvider   = apperror.New(http.StatusNotFound, "provider_not_found", "provider is not configured")
+	ErrBaseURLNotAllowed = apperror.New(http.StatusUnprocessableEntity, "base_url_not_allowed", "baseUrl is not an allowed host of the provider")
+)
+
+type Repository interface {
+	// Save creates the account of the merchant, mode and provider or
+	// replaces it.
+	Save(ctx context.Context, account *StoredAccount) error
+	Find(ctx context.Context, scope merchant.Scope) ([]StoredAccount, error)
+	Delete(ctx context.Context, scope merchant.Scope, provider string) error
+}
+
+// Cache keeps the stored accounts of a scope for a while, so payments do not
+// read them from the database each time. Get reports false on a miss.
+type Cache interface {
+	Get(ctx context.Context, scope merchant.Scope) ([]StoredAccount, bool, error)
+	Set(ctx context.Context, scope merchant.Scope, accounts []StoredAccount, ttl time.Duration) error
+	Invalidate(ctx context.Context, scope merchant.Scope) error
+}
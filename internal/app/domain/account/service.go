@@ -0,0 +1,208 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// DefaultCacheTTL bounds how long a change made on another instance takes to
+// be seen when the cache could not be invalidated.
+const DefaultCacheTTL = 5 * time.Minute
+
+// Service keeps the accounts merchants have with the providers, so each
+// merchant can charge on its own provider accounts. Credentials are
+// encrypted with the vault keys.
+type Service struct {
+	Repository Repository
+	// Cache, when set, keeps the accounts of a scope between payments. The
+	// cached accounts are still encrypted.
+	Cache     Cache
+	Keys      *vault.Keyring
+	Providers *payment.Registry
+	CacheTTL  time.Duration
+	// BaseURLHosts lists, by provider, the hosts an account may send its
+	// calls to instead of the provider default. Card data and credentials go
+	// to the BaseURL, so it is refused outside them and must be https.
+	BaseURLHosts map[string][]string
+}
+
+func New(repository Repository, cache Cache, keys *vault.Keyring, providers *payment.Registry) *Service {
+	return &Service{
+		Repository: repository,
+		Cache:      cache,
+		Keys:       keys,
+		Providers:  providers,
+		CacheTTL:   DefaultCacheTTL,
+	}
+}
+
+// Configure creates or replaces the account of the merchant and mode of ctx
+// with provider.
+func (s *Service) Configure(ctx context.Context, provider string, settings Settings) (*payment.Account, error) {
+	if _, ok := s.Providers.Get(provider); !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	if settings.BaseURL != "" && !s.allowedBaseURL(provider, settings.BaseURL) {
+		return nil, ErrBaseURLNotAllowed
+	}
+
+	credentials, err := json.Marshal(settings.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := merchant.ScopeFrom(ctx)
+	stored := &StoredAccount{
+		MerchantID:          scope.MerchantID,
+		Livemode:            scope.Livemode,
+		Provider:            provider,
+		BaseURL:             settings.BaseURL,
+		Enabled:             settings.Enabled,
+		Priority:            settings.Priority,
+		StatementDescriptor: settings.StatementDescriptor,
+		UpdatedAt:           time.Now().UTC().Format(time.RFC3339),
+	}
+
+	stored.KeyID, stored.Ciphertext, err = s.Keys.Encrypt(credentials, additionalData(stored))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.Repository.Save(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, scope)
+
+	logger.Info(ctx, "Provider account configured", attributes.Attributes{"provider": provider, "enabled": stored.Enabled, "key_id": stored.KeyID})
+	return &payment.Account{
+		Provider:            provider,
+		BaseURL:             settings.BaseURL,
+		Credentials:         settings.Credentials,
+		Enabled:             settings.Enabled,
+		Priority:            settings.Priority,
+		StatementDescriptor: settings.StatementDescriptor,
+	}, nil
+}
+
+// List returns the accounts of the merchant and mode of ctx.
+func (s *Service) List(ctx context.Context) ([]payment.Account, error) {
+	return s.Accounts(ctx, merchant.ScopeFrom(ctx))
+}
+
+// Remove deletes the account of the merchant and mode of ctx with provider;
+// its payments go back to the provider defaults.
+func (s *Service) Remove(ctx context.Context, provider string) error {
+	scope := merchant.ScopeFrom(ctx)
+	if err := s.Repository.Delete(ctx, scope, provider); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, scope)
+
+	logger.Info(ctx, "Provider account removed", attributes.Attributes{"provider": provider})
+	return nil
+}
+
+// Accounts returns the decrypted accounts of scope, from the cache when it
+// has them.
+func (s *Service) Accounts(ctx context.Context, scope merchant.Scope) ([]payment.Account, error) {
+	stored, err := s.load(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]payment.Account, 0, len(stored))
+	for i := range stored {
+		plaintext, err := s.Keys.Decrypt(stored[i].KeyID, stored[i].Ciphertext, additionalData(&stored[i]))
+		if err != nil {
+			return nil, err
+		}
+
+		account := payment.Account{
+			Provider:            stored[i].Provider,
+			BaseURL:             stored[i].BaseURL,
+			Enabled:             stored[i].Enabled,
+			Priority:            stored[i].Priority,
+			StatementDescriptor: stored[i].StatementDescriptor,
+		}
+
+		// Hosts may be taken off the list after accounts were set to them.
+		if account.BaseURL != "" && !s.allowedBaseURL(account.Provider, account.BaseURL) {
+			logger.Warn(ctx, "Provider account base URL no longer allowed, using the provider default", attributes.Attributes{"provider": account.Provider})
+			account.BaseURL = ""
+		}
+
+		if err = json.Unmarshal(plaintext, &account.Credentials); err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// allowedBaseURL reports whether baseURL is an https URL on one of the hosts
+// allowed for provider.
+func (s *Service) allowedBaseURL(provider string, baseURL string) bool {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil {
+		return false
+	}
+
+	return slices.Contains(s.BaseURLHosts[provider], parsed.Host)
+}
+
+// load reads the accounts of scope from the cache, falling back to the
+// database when it misses or is unavailable.
+func (s *Service) load(ctx context.Context, scope merchant.Scope) ([]StoredAccount, error) {
+	if s.Cache != nil {
+		accounts, ok, err := s.Cache.Get(ctx, scope)
+		if err != nil {
+			logger.Warn(ctx, "Error reading cached provider accounts", attributes.Attributes{"scope": scope.String()}.WithError(err))
+		} else if ok {
+			return accounts, nil
+		}
+	}
+
+	accounts, err := s.Repository.Find(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Cache != nil {
+		if err = s.Cache.Set(ctx, scope, accounts, s.CacheTTL); err != nil {
+			logger.Warn(ctx, "Error caching provider accounts", attributes.Attributes{"scope": scope.String()}.WithError(err))
+		}
+	}
+
+	return accounts, nil
+}
+
+// invalidate drops the cached accounts of scope. A failure leaves them stale
+// until CacheTTL, so it is logged as an error.
+func (s *Service) invalidate(ctx context.Context, scope merchant.Scope) {
+	if s.Cache == nil {
+		return
+	}
+
+	if err := s.Cache.Invalidate(ctx, scope); err != nil {
+		logger.Error(ctx, "Error invalidating cached provider accounts", attributes.Attributes{"scope": scope.String()}.WithError(err))
+	}
+}
+
+// additionalData binds the credentials to their account, so ciphertexts
+// cannot be swapped between merchants, modes or providers.
+func additionalData(account *StoredAccount) []byte {
+	return []byte(merchant.Scope{MerchantID: account.MerchantID, Livemode: account.Livemode}.String() + ":" + account.Provider)
+}
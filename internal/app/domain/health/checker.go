@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Dependency is something the service needs to answer requests, such as a
+// database or a payment provider. A required dependency being down makes the
+// service not ready; the others only degrade it.
+type Dependency struct {
+	Name     string
+	Required bool
+	Ping     func(ctx context.Context) error
+}
+
+type Result struct {
+	Name     string
+	Status   string
+	Required bool
+	Latency  time.Duration
+}
+
+// Report is the outcome of checking every dependency. Ready is false when a
+// required dependency is down.
+type Report struct {
+	Ready   bool
+	Results []Result
+}
+
+type Checker struct {
+	dependencies []Dependency
+	timeout      time.Duration
+}
+
+// NewChecker checks dependencies, giving each ping at most timeout.
+func NewChecker(timeout time.Duration, dependencies ...Dependency) *Checker {
+	return &Checker{
+		dependencies: dependencies,
+		timeout:      timeout,
+	}
+}
+
+// Check pings every dependency concurrently, so the report takes about as
+// long as the slowest one, bounded by the timeout. Ping errors are logged
+// rather than reported, since they may name internal hosts.
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]Result, len(c.dependencies))
+
+	var wg sync.WaitGroup
+	for i, dependency := range c.dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.ping(ctx, dependency)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Results: results}
+	for _, result := range results {
+		if result.Required && result.Status == StatusDown {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) ping(ctx context.Context, dependency Dependency) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	// A ping that ignores its context still cannot hold the report past
+	// the timeout.
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- dependency.Ping(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Name: dependency.Name, Status: StatusUp, Required: dependency.Required, Latency: time.Since(start)}
+
+	if err != nil {
+		logger.Warn(ctx, "Health check failed", attributes.Attributes{"dependency": dependency.Name, "required": dependency.Required}.WithError(err))
+		result.Status = StatusDown
+	}
+
+	return result
+}
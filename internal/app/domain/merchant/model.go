@@ -0,0 +1,54 @@
+package merchant
+
+type KeyType string
+
+const (
+	// KeySecret authenticates server-side calls and may do anything the
+	// merchant can.
+	KeySecret KeyType = "secret"
+	// KeyPublishable is safe to ship in browsers and apps: it only creates
+	// card tokens.
+	KeyPublishable KeyType = "publishable"
+)
+
+const (
+	ModeLive = "live"
+	ModeTest = "test"
+)
+
+type Merchant struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	CreatedAt string   `json:"createdAt"`
+	Keys      []APIKey `json:"keys,omitempty"`
+}
+
+// APIKey authenticates a merchant in one mode. Only the SHA-256 hash of the
+// key is stored; Value is set once, when the key is issued, and never again.
+type APIKey struct {
+	ID         string  `json:"id"`
+	MerchantID string  `json:"merchantId"`
+	Type       KeyType `json:"type"`
+	Livemode   bool    `json:"livemode"`
+	Hash       string  `json:"-"`
+	Last4      string  `json:"last4"`
+	Value      string  `json:"-"`
+	CreatedAt  string  `json:"createdAt"`
+	RevokedAt  string  `json:"revokedAt,omitempty"`
+}
+
+func (k *APIKey) Mode() string {
+	return mode(k.Livemode)
+}
+
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != ""
+}
+
+func mode(livemode bool) string {
+	if livemode {
+		return ModeLive
+	}
+
+	return ModeTest
+}
@@ -0,0 +1,23 @@
+package merchant
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+)
+
+var (
+	ErrNotFound    = apperror.New(http.StatusNotFound, "merchant_not_found", "merchant not found")
+	ErrKeyNotFound = apperror.New(http.StatusNotFound, "api_key_not_found", "API key not found")
+)
+
+type Repository interface {
+	Create(ctx context.Context, merchant *Merchant) error
+	FindByID(ctx context.Context, id string) (*Merchant, error)
+	CreateKey(ctx context.Context, key *APIKey) error
+	// FindKeyByHash returns the key, revoked or not, whose hash is hash.
+	FindKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	FindKeys(ctx context.Context, merchantID string) ([]APIKey, error)
+	// RevokeKey revokes a key of the merchant that is not revoked yet.
+	RevokeKey(ctx context.Context, merchantID string, keyID string) error
+}
@@ -0,0 +1,40 @@
+package merchant
+
+import "context"
+
+type scopeKey struct{}
+
+// Scope is the merchant and mode a request acts for. Payments, tokens and
+// webhook endpoints belong to the scope they were created in and are only
+// visible from it: test keys never see live data and the other way round.
+//
+// The zero Scope owns the data created without authentication, such as the
+// rows stored before merchants existed.
+type Scope struct {
+	MerchantID string
+	Livemode   bool
+}
+
+// WithScope returns ctx acting for scope.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope ctx acts for, the zero Scope when it was not
+// authenticated.
+func ScopeFrom(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
+
+// Owns reports whether a resource created in the given merchant and mode
+// belongs to the scope.
+func (s Scope) Owns(merchantID string, livemode bool) bool {
+	return s.MerchantID == merchantID && s.Livemode == livemode
+}
+
+// String identifies the scope in keys shared by every merchant, such as
+// idempotency keys.
+func (s Scope) String() string {
+	return s.MerchantID + ":" + mode(s.Livemode)
+}
@@ -0,0 +1,237 @@
+package merchant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// keySize is the number of random bytes of a key, hex encoded after its
+// prefix.
+const keySize = 24
+
+// keyPrefixes tell the type and mode of a key at a glance, as in
+// sk_live_… or pk_test_….
+var keyPrefixes = map[KeyType]map[bool]string{
+	KeySecret:      {true: "sk_live_", false: "sk_test_"},
+	KeyPublishable: {true: "pk_live_", false: "pk_test_"},
+}
+
+var (
+	ErrMissingAPIKey      = apperror.New(http.StatusUnauthorized, "missing_api_key", "an API key is required, sent as Authorization: Bearer <key>")
+	ErrInvalidAPIKey      = apperror.New(http.StatusUnauthorized, "invalid_api_key", "API key is invalid or revoked")
+	ErrSecretKeyRequired  = apperror.New(http.StatusForbidden, "secret_key_required", "publishable keys can only create card tokens")
+	ErrInvalidAdminKey    = apperror.New(http.StatusUnauthorized, "invalid_admin_key", "admin key is invalid")
+	ErrAdminNotConfigured = apperror.New(http.StatusServiceUnavailable, "admin_not_configured", "merchant administration is not configured")
+	ErrInvalidKeyType     = apperror.New(http.StatusBadRequest, "invalid_key_type", "key type must be secret or publishable")
+)
+
+// Service manages merchants and their API keys, and authenticates requests
+// into the scope of the merchant owning the key.
+type Service struct {
+	Repository Repository
+	// AdminKey authenticates the administration of merchants. Without it
+	// merchants cannot be created through the API.
+	AdminKey string
+}
+
+func New(repository Repository, adminKey string) *Service {
+	return &Service{
+		Repository: repository,
+		AdminKey:   adminKey,
+	}
+}
+
+// Create registers a merchant with a secret and a publishable key in each
+// mode. The returned keys are the only ones carrying their value.
+func (s *Service) Create(ctx context.Context, name string) (*Merchant, error) {
+	merchant := &Merchant{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedAt: now(),
+	}
+
+	for _, livemode := range []bool{false, true} {
+		for _, keyType := range []KeyType{KeySecret, KeyPublishable} {
+			key, err := newKey(merchant.ID, keyType, livemode)
+			if err != nil {
+				return nil, err
+			}
+
+			merchant.Keys = append(merchant.Keys, *key)
+		}
+	}
+
+	if err := s.Repository.Create(ctx, merchant); err != nil {
+		return nil, err
+	}
+
+	logger.Info(ctx, "Merchant created", attributes.Attributes{"merchant_id": merchant.ID})
+	return merchant, nil
+}
+
+// Current returns the merchant of the scope in ctx with all its keys.
+func (s *Service) Current(ctx context.Context) (*Merchant, error) {
+	merchant, err := s.Repository.FindByID(ctx, ScopeFrom(ctx).MerchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if merchant.Keys, err = s.Repository.FindKeys(ctx, merchant.ID); err != nil {
+		return nil, err
+	}
+
+	return merchant, nil
+}
+
+// IssueKey creates another key of the merchant in the mode of the scope,
+// so keys can be rolled without downtime: issue, deploy, revoke the old one.
+func (s *Service) IssueKey(ctx context.Context, keyType KeyType) (*APIKey, error) {
+	if _, ok := keyPrefixes[keyType]; !ok {
+		return nil, ErrInvalidKeyType
+	}
+
+	scope := ScopeFrom(ctx)
+	key, err := newKey(scope.MerchantID, keyType, scope.Livemode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.Repository.CreateKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	logger.Info(ctx, "API key issued", attributes.Attributes{"key_id": key.ID, "type": key.Type, "mode": key.Mode()})
+	return key, nil
+}
+
+// RevokeKey revokes a key of the merchant in the mode of the scope. Requests
+// made with it are refused from then on.
+func (s *Service) RevokeKey(ctx context.Context, keyID string) error {
+	scope := ScopeFrom(ctx)
+	keys, err := s.Repository.FindKeys(ctx, scope.MerchantID)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.ID == keyID && key.Livemode == scope.Livemode {
+			if err = s.Repository.RevokeKey(ctx, scope.MerchantID, keyID); err != nil {
+				return err
+			}
+
+			logger.Info(ctx, "API key revoked", attributes.Attributes{"key_id": keyID})
+			return nil
+		}
+	}
+
+	return ErrKeyNotFound
+}
+
+// Authenticate returns ctx acting for the merchant and mode of key, which may
+// be secret or publishable.
+func (s *Service) Authenticate(ctx context.Context, value string) (context.Context, error) {
+	key, err := s.findKey(ctx, value)
+	if err != nil {
+		return ctx, err
+	}
+
+	return withKey(ctx, key), nil
+}
+
+// AuthenticateSecret is Authenticate refusing every key but secret ones. The
+// type stored with the key decides, not the prefix of its value.
+func (s *Service) AuthenticateSecret(ctx context.Context, value string) (context.Context, error) {
+	key, err := s.findKey(ctx, value)
+	if err != nil {
+		return ctx, err
+	}
+
+	if key.Type != KeySecret {
+		return ctx, ErrSecretKeyRequired
+	}
+
+	return withKey(ctx, key), nil
+}
+
+// AuthenticateAdmin checks value is the admin key.
+func (s *Service) AuthenticateAdmin(ctx context.Context, value string) (context.Context, error) {
+	if s.AdminKey == "" {
+		return ctx, ErrAdminNotConfigured
+	}
+
+	if subtle.ConstantTimeCompare([]byte(value), []byte(s.AdminKey)) != 1 {
+		return ctx, ErrInvalidAdminKey
+	}
+
+	return ctx, nil
+}
+
+// withKey scopes ctx to the merchant and mode of the key.
+func withKey(ctx context.Context, key *APIKey) context.Context {
+	ctx = WithScope(ctx, Scope{MerchantID: key.MerchantID, Livemode: key.Livemode})
+	return correlation.WithMerchantID(ctx, key.MerchantID)
+}
+
+func (s *Service) findKey(ctx context.Context, value string) (*APIKey, error) {
+	if value == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	key, err := s.Repository.FindKeyByHash(ctx, hashKey(value))
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Revoked() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return key, nil
+}
+
+func newKey(merchantID string, keyType KeyType, livemode bool) (*APIKey, error) {
+	random := make([]byte, keySize)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	value := keyPrefixes[keyType][livemode] + hex.EncodeToString(random)
+
+	return &APIKey{
+		ID:         uuid.New().String(),
+		MerchantID: merchantID,
+		Type:       keyType,
+		Livemode:   livemode,
+		Hash:       hashKey(value),
+		Last4:      value[len(value)-4:],
+		Value:      value,
+		CreatedAt:  now(),
+	}, nil
+}
+
+// hashKey hashes a key for storage. Keys are long and random, so a plain
+// SHA-256 is enough and lets them be looked up by hash.
+func hashKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,160 @@
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrTooPrecise    = errors.New("amount has more decimals than the currency allows")
+	ErrTooLarge      = errors.New("amount is too large for the currency")
+)
+
+// maxDigits keeps every decimal within int64. Converting it to minor units
+// may still overflow, which In refuses with ErrTooLarge.
+const maxDigits = 18
+
+// Decimal is an exact decimal amount in major units, as written by clients.
+// It only becomes Money once its currency is known.
+type Decimal struct {
+	unscaled int64
+	scale    int
+}
+
+// ParseDecimal parses a plain decimal such as "19.99" or "-5" exactly.
+// Exponents, thousands separators and more than 18 digits are rejected.
+func ParseDecimal(value string) (Decimal, error) {
+	digits := strings.TrimPrefix(value, "-")
+	negative := len(digits) != len(value)
+
+	integer, fraction, hasPoint := strings.Cut(digits, ".")
+	if integer == "" && fraction == "" || hasPoint && fraction == "" || len(integer)+len(fraction) > maxDigits {
+		return Decimal{}, fmt.Errorf("%w %q", ErrInvalidAmount, value)
+	}
+
+	for _, digit := range integer + fraction {
+		if digit < '0' || digit > '9' {
+			return Decimal{}, fmt.Errorf("%w %q", ErrInvalidAmount, value)
+		}
+	}
+
+	unscaled, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("%w %q", ErrInvalidAmount, value)
+	}
+
+	if negative {
+		unscaled = -unscaled
+	}
+
+	return Decimal{unscaled: unscaled, scale: len(fraction)}, nil
+}
+
+func (d Decimal) IsZero() bool {
+	return d.unscaled == 0
+}
+
+func (d Decimal) Sign() int {
+	switch {
+	case d.unscaled < 0:
+		return -1
+	case d.unscaled > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Cmp compares d and other, returning -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	return d.rat().Cmp(other.rat())
+}
+
+// In converts the amount to Money in the given currency. Trailing zeros past
+// the currency exponent are dropped; any other extra digit is an error.
+func (d Decimal) In(currency string) (Money, error) {
+	exponent, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	unscaled, scale := d.unscaled, d.scale
+	for scale > exponent && unscaled%10 == 0 {
+		unscaled /= 10
+		scale--
+	}
+
+	if scale > exponent {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrTooPrecise, d, strings.ToUpper(currency))
+	}
+
+	for ; scale < exponent; scale++ {
+		if unscaled > math.MaxInt64/10 || unscaled < math.MinInt64/10 {
+			return Money{}, fmt.Errorf("%w: %s %s", ErrTooLarge, d, strings.ToUpper(currency))
+		}
+
+		unscaled *= 10
+	}
+
+	return New(unscaled, currency), nil
+}
+
+func (d Decimal) String() string {
+	return format(d.unscaled, d.scale)
+}
+
+// UnmarshalJSON accepts the amount as a JSON number or string, reading its
+// digits verbatim so no float rounding is involved.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*d = Decimal{}
+		return nil
+	}
+
+	value := string(data)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	parsed, err := ParseDecimal(value)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d Decimal) rat() *big.Rat {
+	denominator := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)
+	return new(big.Rat).SetFrac(big.NewInt(d.unscaled), denominator)
+}
+
+func format(unscaled int64, scale int) string {
+	sign := ""
+	if unscaled < 0 {
+		sign = "-"
+		unscaled = -unscaled
+	}
+
+	digits := strconv.FormatInt(unscaled, 10)
+	if scale == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
@@ -0,0 +1,49 @@
+package money
+
+import (
+	"math"
+	"strings"
+)
+
+// Money is an amount in the minor units of its ISO-4217 currency, so 19.99
+// USD is 1999 and 500 JPY is 500.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Parse reads an exact decimal amount in major units of the currency.
+func Parse(value string, currency string) (Money, error) {
+	decimal, err := ParseDecimal(value)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return decimal.In(currency)
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Decimal returns the amount in major units.
+func (m Money) Decimal() Decimal {
+	exponent, _ := Exponent(m.Currency)
+	return Decimal{unscaled: m.Amount, scale: exponent}
+}
+
+// String returns the amount in major units, such as "19.99".
+func (m Money) String() string {
+	return m.Decimal().String()
+}
+
+// Float64 returns the amount in major units for estimates such as fee
+// comparisons. Never use it for amounts that are charged or refunded.
+func (m Money) Float64() float64 {
+	exponent, _ := Exponent(m.Currency)
+	return float64(m.Amount) / math.Pow10(exponent)
+}
@@ -0,0 +1,158 @@
+package payment
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"sort"
+)
+
+// Account is a merchant's own account with a provider. Calls made for the
+// merchant's payments go to its BaseURL with its Credentials instead of the
+// provider defaults.
+type Account struct {
+	Provider    string
+	BaseURL     string
+	Credentials Credentials
+	// Enabled providers are the only ones new payments of the merchant are
+	// routed to, by increasing Priority.
+	Enabled  bool
+	Priority int
+	// StatementDescriptor is used for charges that do not set their own.
+	StatementDescriptor string
+}
+
+// Credentials authenticate calls on an account; each provider reads the ones
+// its API uses.
+type Credentials struct {
+	APIKey       string `json:"apiKey,omitempty"`
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+// AccountStore returns the provider accounts a merchant configured in a mode.
+type AccountStore interface {
+	Accounts(ctx context.Context, scope merchant.Scope) ([]Account, error)
+}
+
+type accountKey struct{}
+
+// WithAccount returns ctx making provider calls on account.
+func WithAccount(ctx context.Context, account *Account) context.Context {
+	return context.WithValue(ctx, accountKey{}, account)
+}
+
+// AccountFrom returns the account of provider the calls made with ctx use,
+// or nil for the provider defaults.
+func AccountFrom(ctx context.Context, provider string) *Account {
+	account, _ := ctx.Value(accountKey{}).(*Account)
+	if account == nil || account.Provider != provider {
+		return nil
+	}
+
+	return account
+}
+
+// accountProvider makes every call of a provider on a merchant account.
+type accountProvider struct {
+	Provider
+	account *Account
+	scope   merchant.Scope
+}
+
+// breakerName is the breaker guarding calls on provider. Each merchant
+// account has its own, so a broken account cannot open the breaker of the
+// provider for everyone else.
+func breakerName(provider Provider) string {
+	if onAccount, ok := provider.(*accountProvider); ok {
+		return onAccount.Name() + ":" + onAccount.scope.String()
+	}
+
+	return provider.Name()
+}
+
+func (p *accountProvider) Charge(ctx context.Context, request ChargeRequest) (ChargeResult, error) {
+	if request.StatementDescriptor == "" {
+		request.StatementDescriptor = p.account.StatementDescriptor
+	}
+
+	return p.Provider.Charge(WithAccount(ctx, p.account), request)
+}
+
+func (p *accountProvider) Refund(ctx context.Context, request RefundRequest) (ChargeResult, error) {
+	return p.Provider.Refund(WithAccount(ctx, p.account), request)
+}
+
+func (p *accountProvider) Capture(ctx context.Context, request CaptureRequest) (ChargeResult, error) {
+	return p.Provider.Capture(WithAccount(ctx, p.account), request)
+}
+
+func (p *accountProvider) Cancel(ctx context.Context, request CancelRequest) (ChargeResult, error) {
+	return p.Provider.Cancel(WithAccount(ctx, p.account), request)
+}
+
+func (p *accountProvider) Get(ctx context.Context, providerPaymentID string) (ChargeResult, error) {
+	return p.Provider.Get(WithAccount(ctx, p.account), providerPaymentID)
+}
+
+func (p *accountProvider) FindByReference(ctx context.Context, reference string) (ChargeResult, error) {
+	return p.Provider.FindByReference(WithAccount(ctx, p.account), reference)
+}
+
+func (p *accountProvider) Ping(ctx context.Context) error {
+	return p.Provider.Ping(WithAccount(ctx, p.account))
+}
+
+// providersFor narrows the routed providers to the accounts the merchant of
+// scope enabled, by increasing priority and then route order, each calling
+// on its account. Merchants without accounts use the routed providers as is.
+func (s *Service) providersFor(ctx context.Context, scope merchant.Scope, routed []Provider) ([]Provider, error) {
+	if s.Accounts == nil {
+		return routed, nil
+	}
+
+	accounts, err := s.Accounts.Accounts(ctx, scope)
+	if err != nil || len(accounts) == 0 {
+		return routed, err
+	}
+
+	byProvider := make(map[string]*Account, len(accounts))
+	for i := range accounts {
+		byProvider[accounts[i].Provider] = &accounts[i]
+	}
+
+	providers := make([]Provider, 0, len(routed))
+	for _, provider := range routed {
+		if account, ok := byProvider[provider.Name()]; ok && account.Enabled {
+			providers = append(providers, &accountProvider{Provider: provider, account: account, scope: scope})
+		}
+	}
+
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providers[i].(*accountProvider).account.Priority < providers[j].(*accountProvider).account.Priority
+	})
+
+	return providers, nil
+}
+
+// onAccount returns provider calling on the account the merchant of payment
+// has with it. Disabled accounts are still used, so payments made before
+// disabling one can be refunded; without an account the defaults are.
+func (s *Service) onAccount(ctx context.Context, payment *Payment, provider Provider) (Provider, error) {
+	if s.Accounts == nil {
+		return provider, nil
+	}
+
+	scope := merchant.Scope{MerchantID: payment.MerchantID, Livemode: payment.Livemode}
+	accounts, err := s.Accounts.Accounts(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range accounts {
+		if accounts[i].Provider == provider.Name() {
+			return &accountProvider{Provider: provider, account: &accounts[i], scope: scope}, nil
+		}
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,334 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/metrics"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+var breakerStates = []string{string(BreakerClosed), string(BreakerOpen), string(BreakerHalfOpen)}
+
+var ErrNoProviderAvailable = apperror.NewRetryable(http.StatusServiceUnavailable, "provider_unavailable", "no payment provider available")
+
+type BreakerConfig struct {
+	// WindowSize is how many recent calls the rates are computed over.
+	WindowSize int
+	// MinimumCalls is how many calls the window needs before it can trip.
+	MinimumCalls int
+	// FailureRate and SlowCallRate trip the breaker when reached (0 to 1).
+	FailureRate  float64
+	SlowCallRate float64
+	// SlowCall is the latency above which a call counts as slow.
+	SlowCall time.Duration
+	// OpenTimeout is how long an open breaker rejects calls before letting a
+	// trial call through.
+	OpenTimeout time.Duration
+}
+
+type BreakerStatus struct {
+	Provider     string       `json:"provider"`
+	State        BreakerState `json:"state"`
+	Calls        int          `json:"calls"`
+	FailureRate  float64      `json:"failureRate"`
+	SlowCallRate float64      `json:"slowCallRate"`
+	OpenedAt     string       `json:"openedAt,omitempty"`
+}
+
+type breakerCall struct {
+	failed bool
+	slow   bool
+}
+
+// CircuitBreaker tracks the health of one provider from the outcome and
+// latency of its recent calls. Only infrastructure failures count; declines
+// and invalid requests say nothing about the provider's health.
+type CircuitBreaker struct {
+	provider string
+	config   BreakerConfig
+	mu       sync.Mutex
+	state    BreakerState
+	calls    []breakerCall
+	next     int
+	openedAt time.Time
+	trial    bool
+}
+
+func NewCircuitBreaker(provider string, config BreakerConfig) *CircuitBreaker {
+	metrics.SetBreakerState(provider, string(BreakerClosed), breakerStates...)
+
+	return &CircuitBreaker{
+		provider: provider,
+		config:   config,
+		state:    BreakerClosed,
+		calls:    make([]breakerCall, 0, config.WindowSize),
+	}
+}
+
+// Allow reports whether a call may be sent to the provider now. An open
+// breaker lets a single trial call through once OpenTimeout has elapsed.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.config.OpenTimeout {
+		b.transition(BreakerHalfOpen)
+	}
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if b.trial {
+			return false
+		}
+
+		b.trial = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Record feeds the outcome of a call allowed by Allow back into the breaker.
+func (b *CircuitBreaker) Record(err error, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	call := breakerCall{failed: isInfrastructureError(err), slow: latency >= b.config.SlowCall}
+
+	if b.state == BreakerHalfOpen {
+		b.trial = false
+		if call.failed || call.slow {
+			b.transition(BreakerOpen)
+		} else {
+			b.transition(BreakerClosed)
+		}
+
+		return
+	}
+
+	if b.state != BreakerClosed {
+		return
+	}
+
+	if len(b.calls) < b.config.WindowSize {
+		b.calls = append(b.calls, call)
+	} else {
+		b.calls[b.next] = call
+	}
+	b.next = (b.next + 1) % b.config.WindowSize
+
+	if len(b.calls) < b.config.MinimumCalls {
+		return
+	}
+
+	failureRate, slowCallRate := b.rates()
+	if failureRate >= b.config.FailureRate || slowCallRate >= b.config.SlowCallRate {
+		b.transition(BreakerOpen)
+	}
+}
+
+// ProbeSucceeded moves an open breaker to half-open so the next call is
+// used as a trial without waiting for OpenTimeout.
+func (b *CircuitBreaker) ProbeSucceeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		b.transition(BreakerHalfOpen)
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failureRate, slowCallRate := b.rates()
+	status := BreakerStatus{
+		Provider:     b.provider,
+		State:        b.state,
+		Calls:        len(b.calls),
+		FailureRate:  failureRate,
+		SlowCallRate: slowCallRate,
+	}
+
+	if b.state != BreakerClosed {
+		status.OpenedAt = b.openedAt.UTC().Format(time.RFC3339)
+	}
+
+	return status
+}
+
+func (b *CircuitBreaker) rates() (float64, float64) {
+	if len(b.calls) == 0 {
+		return 0, 0
+	}
+
+	failed, slow := 0, 0
+	for _, call := range b.calls {
+		if call.failed {
+			failed++
+		}
+
+		if call.slow {
+			slow++
+		}
+	}
+
+	total := float64(len(b.calls))
+	return float64(failed) / total, float64(slow) / total
+}
+
+func (b *CircuitBreaker) transition(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	attrs := attributes.Attributes{"provider": b.provider, "breaker.from": string(b.state), "breaker.to": string(state)}
+	if state == BreakerOpen {
+		logger.Warn(context.Background(), fmt.Sprintf("Circuit breaker for [%s] opened", b.provider), attrs)
+	} else {
+		logger.Info(context.Background(), fmt.Sprintf("Circuit breaker for [%s] is %s", b.provider, state), attrs)
+	}
+
+	switch state {
+	case BreakerOpen:
+		b.openedAt = time.Now()
+	case BreakerClosed:
+		b.calls = b.calls[:0]
+		b.next = 0
+	}
+
+	b.state = state
+	metrics.SetBreakerState(b.provider, string(state), breakerStates...)
+}
+
+func isInfrastructureError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	kind, ok := ErrorKindOf(err)
+	return !ok || kind == ErrorNetwork || kind == ErrorTimeout || kind == ErrorProvider
+}
+
+// CircuitBreakers holds one breaker per provider, shared by every request.
+type CircuitBreakers struct {
+	config   BreakerConfig
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+func NewCircuitBreakers(config BreakerConfig) *CircuitBreakers {
+	return &CircuitBreakers{
+		config:   config,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+func (c *CircuitBreakers) For(provider string) *CircuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	breaker, ok := c.breakers[provider]
+	if !ok {
+		breaker = NewCircuitBreaker(provider, c.config)
+		c.breakers[provider] = breaker
+	}
+
+	return breaker
+}
+
+// HealthProber pings the providers whose breaker is open through their
+// health endpoint, so a recovered provider is tried again without waiting for
+// the full OpenTimeout.
+type HealthProber struct {
+	providers *Registry
+	breakers  *CircuitBreakers
+	interval  time.Duration
+	timeout   time.Duration
+	stop      chan struct{}
+	done      sync.WaitGroup
+}
+
+func NewHealthProber(providers *Registry, breakers *CircuitBreakers, interval time.Duration, timeout time.Duration) *HealthProber {
+	return &HealthProber{
+		providers: providers,
+		breakers:  breakers,
+		interval:  interval,
+		timeout:   timeout,
+	}
+}
+
+func (p *HealthProber) Start() {
+	p.stop = make(chan struct{})
+	p.done.Add(1)
+
+	go func() {
+		defer p.done.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.Probe(context.Background())
+			}
+		}
+	}()
+}
+
+func (p *HealthProber) Stop() {
+	if p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	p.done.Wait()
+	p.stop = nil
+}
+
+// Probe pings every provider with an open breaker once.
+func (p *HealthProber) Probe(ctx context.Context) {
+	for _, provider := range p.providers.Ordered() {
+		breaker := p.breakers.For(provider.Name())
+		if breaker.State() != BreakerOpen {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		err := provider.Ping(probeCtx)
+		cancel()
+
+		if err != nil {
+			logger.Debug(ctx, "Provider health probe failed", attributes.Attributes{"provider": provider.Name()}.WithError(err))
+			continue
+		}
+
+		breaker.ProbeSucceeded()
+	}
+}
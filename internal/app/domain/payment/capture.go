@@ -0,0 +1,195 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"net/http"
+	"time"
+)
+
+// CancelReasonExpired tells the provider an authorization is released because
+// it outlived AuthorizationTTL rather than on request.
+const CancelReasonExpired = "expired"
+
+var (
+	ErrNotAuthorized        = apperror.New(http.StatusConflict, "payment_not_authorized", "payment is not an authorization awaiting capture")
+	ErrAuthorizationExpired = apperror.New(http.StatusConflict, "authorization_expired", "payment authorization expired")
+	ErrInvalidCaptureAmount = apperror.New(http.StatusUnprocessableEntity, "invalid_capture_amount", "capture amount must be positive and within the authorized amount")
+)
+
+// CapturePayment captures amount of an authorized payment and releases the
+// rest of the hold. A zero amount captures the whole authorization. An
+// authorization past its expiry is expired instead of captured.
+func (s *Service) CapturePayment(ctx context.Context, paymentID string, amount money.Decimal) (*Payment, error) {
+	ctx = correlation.WithPaymentID(ctx, paymentID)
+
+	stored, provider, err := s.findAuthorization(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	captureAmount := stored.Amount
+	if !amount.IsZero() {
+		if captureAmount, err = amount.In(stored.Amount.Currency); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCaptureAmount, err)
+		}
+	}
+
+	if captureAmount.Amount <= 0 || captureAmount.Amount > stored.Amount.Amount {
+		return nil, ErrInvalidCaptureAmount
+	}
+
+	result, err := provider.Capture(ctx, CaptureRequest{
+		PaymentID:         stored.ID,
+		ProviderPaymentID: stored.ProviderPaymentID,
+		Amount:            captureAmount,
+	})
+	ctx = context.WithoutCancel(ctx)
+
+	if errors.Is(err, ErrOutcomeUnknown) {
+		logger.Warn(ctx, "Capture outcome unknown, payment will be refreshed from provider", attributes.Attributes{"payment_id": stored.ID, "provider": stored.Provider}.WithError(err))
+		return stored, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = stored.Transition(StatusCaptured); err != nil {
+		return nil, err
+	}
+
+	stored.CapturedAmount = result.CapturedAmount
+	stored.AuthorizationExpiresAt = ""
+	s.save(ctx, stored)
+
+	return stored, nil
+}
+
+// CancelPayment releases an authorization that was not captured.
+func (s *Service) CancelPayment(ctx context.Context, paymentID string) (*Payment, error) {
+	ctx = correlation.WithPaymentID(ctx, paymentID)
+
+	stored, provider, err := s.findAuthorization(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = provider.Cancel(ctx, CancelRequest{PaymentID: stored.ID, ProviderPaymentID: stored.ProviderPaymentID})
+	ctx = context.WithoutCancel(ctx)
+
+	if errors.Is(err, ErrOutcomeUnknown) {
+		logger.Warn(ctx, "Cancel outcome unknown, payment will be refreshed from provider", attributes.Attributes{"payment_id": stored.ID, "provider": stored.Provider}.WithError(err))
+		return stored, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = stored.Transition(StatusCanceled); err != nil {
+		return nil, err
+	}
+
+	stored.AuthorizationExpiresAt = ""
+	s.save(ctx, stored)
+
+	return stored, nil
+}
+
+// ExpireAuthorizations expires up to limit authorizations older than
+// AuthorizationTTL and returns how many were expired.
+func (s *Service) ExpireAuthorizations(ctx context.Context, limit int) (int, error) {
+	if s.AuthorizationTTL <= 0 {
+		return 0, nil
+	}
+
+	payments, err := s.Repository.FindByStatus(ctx, StatusAuthorized, time.Now().Add(-s.AuthorizationTTL), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, payment := range payments {
+		provider, ok := s.Providers.Get(payment.Provider)
+		if !ok {
+			logger.Warn(ctx, "Cannot expire authorization of unconfigured provider", attributes.Attributes{"payment_id": payment.ID, "provider": payment.Provider})
+			continue
+		}
+
+		if provider, err = s.onAccount(ctx, payment, provider); err != nil {
+			logger.Warn(ctx, "Error loading provider account to expire authorization", attributes.Attributes{"payment_id": payment.ID, "provider": payment.Provider}.WithError(err))
+			continue
+		}
+
+		s.expire(ctx, payment, provider)
+		expired++
+	}
+
+	return expired, nil
+}
+
+// findAuthorization loads a payment that may still be captured or canceled,
+// expiring it first when its authorization is past due.
+func (s *Service) findAuthorization(ctx context.Context, paymentID string) (*Payment, Provider, error) {
+	stored, provider, err := s.findWithProvider(ctx, paymentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if stored.Status != StatusAuthorized {
+		return nil, nil, ErrNotAuthorized
+	}
+
+	if s.authorizationExpired(stored) {
+		s.expire(context.WithoutCancel(ctx), stored, provider)
+		return nil, nil, ErrAuthorizationExpired
+	}
+
+	return stored, provider, nil
+}
+
+// expire asks the provider to release the hold and cancels the payment. The
+// hold lapses on the provider side anyway, so a failed release is only
+// logged.
+func (s *Service) expire(ctx context.Context, payment *Payment, provider Provider) {
+	ctx = correlation.WithPaymentID(ctx, payment.ID)
+
+	_, err := provider.Cancel(ctx, CancelRequest{PaymentID: payment.ID, ProviderPaymentID: payment.ProviderPaymentID, Reason: CancelReasonExpired})
+	if err != nil {
+		logger.Warn(ctx, "Error releasing expired authorization", attributes.Attributes{"payment_id": payment.ID, "provider": payment.Provider}.WithError(err))
+	}
+
+	logger.Info(ctx, "Authorization expired", attributes.Attributes{"payment_id": payment.ID, "provider": payment.Provider})
+	payment.Status = StatusCanceled
+	payment.AuthorizationExpiresAt = ""
+	s.save(ctx, payment)
+}
+
+// setAuthorizationExpiry fills AuthorizationExpiresAt for authorizations
+// awaiting capture.
+func (s *Service) setAuthorizationExpiry(payment *Payment) {
+	payment.AuthorizationExpiresAt = ""
+	if payment.Status != StatusAuthorized || s.AuthorizationTTL <= 0 {
+		return
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, payment.CreatedAt); err == nil {
+		payment.AuthorizationExpiresAt = createdAt.Add(s.AuthorizationTTL).Format(time.RFC3339)
+	}
+}
+
+func (s *Service) authorizationExpired(payment *Payment) bool {
+	if payment.Status != StatusAuthorized || s.AuthorizationTTL <= 0 {
+		return false
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, payment.CreatedAt)
+	return err == nil && time.Since(createdAt) >= s.AuthorizationTTL
+}
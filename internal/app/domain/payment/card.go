@@ -0,0 +1,98 @@
+package payment
+
+import "strings"
+
+const (
+	BrandVisa       = "visa"
+	BrandMastercard = "mastercard"
+	BrandAmex       = "amex"
+	BrandDiscover   = "discover"
+	BrandElo        = "elo"
+	BrandUnknown    = "unknown"
+)
+
+var eloPrefixes = []string{"401178", "401179", "431274", "438935", "451416", "457393", "457631", "457632", "504175", "506699", "5067", "509", "627780", "636297", "636368", "6500", "6504", "6505", "6516", "6550"}
+
+// CardBrand infers the card brand from the leading digits of the number.
+func CardBrand(number string) string {
+	switch {
+	case hasAnyPrefix(number, eloPrefixes...):
+		return BrandElo
+	case hasAnyPrefix(number, "34", "37"):
+		return BrandAmex
+	case hasAnyPrefix(number, "4"):
+		return BrandVisa
+	case inPrefixRange(number, 2, 51, 55) || inPrefixRange(number, 4, 2221, 2720):
+		return BrandMastercard
+	case hasAnyPrefix(number, "6011", "65") || inPrefixRange(number, 3, 644, 649):
+		return BrandDiscover
+	default:
+		return BrandUnknown
+	}
+}
+
+// LuhnValid reports whether the number is all digits and passes the Luhn
+// checksum every card number carries in its last digit.
+func LuhnValid(number string) bool {
+	if number == "" {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// maskCardNumber keeps the first six and last four digits of the number, as
+// much as is needed to tell its brand and show it to the cardholder.
+func maskCardNumber(number string) string {
+	if len(number) < 10 {
+		return ""
+	}
+
+	return number[:6] + strings.Repeat("*", len(number)-10) + number[len(number)-4:]
+}
+
+func hasAnyPrefix(value string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inPrefixRange(value string, digits int, from int, to int) bool {
+	if len(value) < digits {
+		return false
+	}
+
+	prefix := 0
+	for _, digit := range value[:digits] {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+
+		prefix = prefix*10 + int(digit-'0')
+	}
+
+	return prefix >= from && prefix <= to
+}
@@ -0,0 +1,148 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+)
+
+// ErrorKind classifies why a provider call failed, which decides whether the
+// payment may safely be retried on another provider.
+type ErrorKind string
+
+const (
+	// ErrorDeclined means the acquirer refused the charge.
+	ErrorDeclined ErrorKind = "declined"
+	// ErrorInvalidRequest means the acquirer rejected the request itself.
+	ErrorInvalidRequest ErrorKind = "invalid_request"
+	// ErrorNetwork means the request never reached the acquirer.
+	ErrorNetwork ErrorKind = "network"
+	// ErrorTimeout means the request was sent but no usable answer came back.
+	ErrorTimeout ErrorKind = "timeout"
+	// ErrorProvider means the acquirer answered with a server error.
+	ErrorProvider ErrorKind = "provider_error"
+)
+
+// ProviderError is the error every provider returns for a failed call.
+type ProviderError struct {
+	Provider   string
+	Kind       ErrorKind
+	StatusCode int
+	Err        error
+}
+
+func NewProviderError(provider string, kind ErrorKind, statusCode int, err error) *ProviderError {
+	return &ProviderError{
+		Provider:   provider,
+		Kind:       kind,
+		StatusCode: statusCode,
+		Err:        err,
+	}
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s %s (status %d): %v", e.Provider, e.Kind, e.StatusCode, e.Err)
+	}
+
+	return fmt.Sprintf("%s %s: %v", e.Provider, e.Kind, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes timeouts match ErrOutcomeUnknown, since the acquirer may have
+// applied a request it never answered.
+func (e *ProviderError) Is(target error) bool {
+	return target == ErrOutcomeUnknown && e.Kind == ErrorTimeout
+}
+
+// APIError maps the failure to what clients may see: whether the card was
+// declined or the acquirer failed, never the acquirer's own message.
+func (e *ProviderError) APIError() *apperror.Error {
+	switch e.Kind {
+	case ErrorDeclined:
+		return apperror.New(http.StatusPaymentRequired, "payment_declined", "payment was declined").Wrap(e)
+	case ErrorInvalidRequest:
+		return apperror.New(http.StatusUnprocessableEntity, "provider_rejected_request", "payment provider rejected the request").Wrap(e)
+	case ErrorTimeout:
+		return apperror.New(http.StatusBadGateway, "provider_timeout", "payment provider did not answer in time").Wrap(e)
+	default:
+		return apperror.NewRetryable(http.StatusBadGateway, "provider_error", "payment provider failed").Wrap(e)
+	}
+}
+
+// ErrorKindForStatus maps an acquirer HTTP status to its error kind.
+func ErrorKindForStatus(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusPaymentRequired:
+		return ErrorDeclined
+	case statusCode >= http.StatusInternalServerError:
+		return ErrorProvider
+	case statusCode >= http.StatusBadRequest:
+		return ErrorInvalidRequest
+	default:
+		return ErrorProvider
+	}
+}
+
+// ErrorKindOf returns the kind of a provider error and false for errors that
+// were not classified by a provider.
+func ErrorKindOf(err error) (ErrorKind, bool) {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.Kind, true
+	}
+
+	return "", false
+}
+
+// FailoverPolicy lists the error kinds after which a payment may be retried
+// on the next provider.
+type FailoverPolicy struct {
+	kinds map[ErrorKind]bool
+}
+
+// DefaultFailoverPolicy only fails over when the request provably never
+// reached the acquirer.
+var DefaultFailoverPolicy = NewFailoverPolicy(ErrorNetwork)
+
+func NewFailoverPolicy(kinds ...ErrorKind) FailoverPolicy {
+	policy := FailoverPolicy{kinds: make(map[ErrorKind]bool, len(kinds))}
+	for _, kind := range kinds {
+		policy.kinds[kind] = true
+	}
+
+	return policy
+}
+
+// Allows reports whether failing over after err cannot charge the payment
+// twice. Unclassified errors and unknown outcomes never fail over.
+func (p FailoverPolicy) Allows(err error) bool {
+	if errors.Is(err, ErrOutcomeUnknown) {
+		return false
+	}
+
+	kind, ok := ErrorKindOf(err)
+	return ok && p.kinds[kind]
+}
+
+// Outcome names how a provider call ended, for metrics: succeeded, not_found
+// or the kind of its error.
+func Outcome(err error) string {
+	if err == nil {
+		return "succeeded"
+	}
+
+	if errors.Is(err, ErrChargeNotFound) {
+		return "not_found"
+	}
+
+	if kind, ok := ErrorKindOf(err); ok {
+		return string(kind)
+	}
+
+	return "error"
+}
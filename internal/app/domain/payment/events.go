@@ -0,0 +1,110 @@
+package payment
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventAuthorized = "payment.authorized"
+	EventSucceeded  = "payment.succeeded"
+	EventFailed     = "payment.failed"
+	EventCanceled   = "payment.canceled"
+	EventRefunded   = "payment.refunded"
+	EventDisputed   = "payment.disputed"
+
+	// eventLease keeps claimed events from other publishers for longer than
+	// publishing them may take.
+	eventLease = time.Minute
+)
+
+// EventTypes lists every event merchants may subscribe to.
+var EventTypes = []string{EventAuthorized, EventSucceeded, EventFailed, EventCanceled, EventRefunded, EventDisputed}
+
+// statusEvents names the event published when a payment is stored with a
+// status. Pending payments are not published.
+var statusEvents = map[Status]string{
+	StatusAuthorized:        EventAuthorized,
+	StatusCaptured:          EventSucceeded,
+	StatusFailed:            EventFailed,
+	StatusCanceled:          EventCanceled,
+	StatusPartiallyRefunded: EventRefunded,
+	StatusRefunded:          EventRefunded,
+	StatusDisputed:          EventDisputed,
+}
+
+// Event reports a change of a payment. It is stored by Repository.Update
+// along with the change and published from there, so a change is never
+// stored without its event.
+type Event struct {
+	ID   string
+	Type string
+	// Payment is the payment as stored by the change, its card number masked.
+	Payment   *Payment
+	CreatedAt string
+}
+
+// NewEvent returns the event reporting the payment in its current status, or
+// false when that status is not published.
+func NewEvent(payment *Payment) (*Event, bool) {
+	eventType, ok := statusEvents[payment.Status]
+	if !ok {
+		return nil, false
+	}
+
+	snapshot := *payment
+	snapshot.Attempts = slices.Clone(payment.Attempts)
+	snapshot.Refunds = slices.Clone(payment.Refunds)
+	snapshot.Method.Card.Number = maskCardNumber(payment.Method.Card.Number)
+	snapshot.Method.Card.CVV = ""
+
+	return &Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Payment:   &snapshot,
+		CreatedAt: now(),
+	}, true
+}
+
+// EventPublisher is told about every change of a payment, so merchants can
+// be notified instead of polling it. An event may be published more than
+// once, when it could not be marked published, so publishers must drop the
+// events they already have.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// PublishEvents publishes up to limit stored events and returns how many were
+// published. Events that fail are published again once their claim runs out.
+func (s *Service) PublishEvents(ctx context.Context, limit int) (int, error) {
+	if s.Events == nil {
+		return 0, nil
+	}
+
+	events, err := s.Repository.ClaimEvents(ctx, time.Now().UTC(), eventLease, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		if err := s.Events.Publish(ctx, &event); err != nil {
+			logger.Error(ctx, "Error publishing payment event", attributes.Attributes{"payment_id": event.Payment.ID, "event": event.Type}.WithError(err))
+			continue
+		}
+
+		if err := s.Repository.MarkEventPublished(ctx, event.ID); err != nil {
+			logger.Error(ctx, "Error marking payment event published", attributes.Attributes{"payment_id": event.Payment.ID, "event": event.Type}.WithError(err))
+			continue
+		}
+
+		published++
+	}
+
+	return published, nil
+}
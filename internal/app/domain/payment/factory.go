@@ -1,9 +1,10 @@
 package payment
 
-func NewPayment(amount float64, currency string, description string, method Method) *Payment {
+import "lucassaraiva5/api-pay/internal/app/domain/money"
+
+func NewPayment(amount money.Money, description string, method Method) *Payment {
 	return &Payment{
 		Amount:      amount,
-		Currency:    currency,
 		Description: description,
 		Method:      method,
 	}
@@ -26,6 +27,13 @@ func NewCard(number string, holder string, cvv string, expiration string, instal
 	}
 }
 
-func NewService() *Service {
-	return &Service{}
+func NewChargeRequest(payment *Payment) ChargeRequest {
+	return ChargeRequest{
+		PaymentID:           payment.ID,
+		Amount:              payment.Amount,
+		Description:         payment.Description,
+		StatementDescriptor: payment.StatementDescriptor,
+		Method:              payment.Method,
+		AuthorizeOnly:       payment.AuthorizeOnly,
+	}
 }
@@ -1,31 +1,83 @@
 package payment
 
+import "lucassaraiva5/api-pay/internal/app/domain/money"
+
+const (
+	AttemptSucceeded = "succeeded"
+	AttemptFailed    = "failed"
+	AttemptUnknown   = "unknown"
+
+	// RefundPending is a refund sent to the provider and not answered yet;
+	// its amount is held against the refundable balance meanwhile.
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+	RefundUnknown   = "unknown"
+)
+
 type Payment struct {
-	ID                  string  `json:"id"`
-	Amount              float64 `json:"amount"`
-	Currency            string  `json:"currency"`
-	Description         string  `json:"description"`
-	Status              string  `json:"status"`
-	CreatedAt           string  `json:"createdAt"`
-	StatementDescriptor string  `json:"statementDescriptor,omitempty"`
-	PaymentType         string  `json:"paymentType,omitempty"`
-	CardID              string  `json:"cardId,omitempty"`
-	Method              Method  `json:"method"`
+	ID                     string      `json:"id"`
+	Amount                 money.Money `json:"amount"`
+	CapturedAmount         money.Money `json:"capturedAmount"`
+	RefundedAmount         money.Money `json:"refundedAmount"`
+	Description            string      `json:"description"`
+	Status                 Status      `json:"status"`
+	CreatedAt              string      `json:"createdAt"`
+	StatementDescriptor    string      `json:"statementDescriptor,omitempty"`
+	PaymentType            string      `json:"paymentType,omitempty"`
+	CardID                 string      `json:"cardId,omitempty"`
+	Provider               string      `json:"provider,omitempty"`
+	ProviderPaymentID      string      `json:"-"`
+	Method                 Method      `json:"method"`
+	Attempts               []Attempt   `json:"attempts,omitempty"`
+	Refunds                []Refund    `json:"refunds,omitempty"`
+	AuthorizationExpiresAt string      `json:"authorizationExpiresAt,omitempty"`
+	// MerchantID and Livemode are the scope owning the payment; only keys of
+	// that merchant and mode reach it.
+	MerchantID string `json:"-"`
+	Livemode   bool   `json:"livemode"`
+	// AuthorizeOnly holds the funds without capturing them; the payment is
+	// captured or canceled later.
+	AuthorizeOnly bool `json:"-"`
 }
 
 type Method struct {
 	Type string `json:"type"`
-	Card Card   `json:"card"`
+	// Token names a card stored in the vault. The card is only filled from
+	// the vault right before it is charged.
+	Token string `json:"token,omitempty"`
+	Card  Card   `json:"card"`
 }
 
+// Card holds raw card data while a payment is charged. Number and CVV are
+// never encoded, so a payment serialized by mistake cannot leak them.
 type Card struct {
-	Number            string `json:"number"`
+	Number            string `json:"-"`
 	Holder            string `json:"holder"`
-	CVV               string `json:"cvv"`
+	CVV               string `json:"-"`
 	Expiration        string `json:"expiration"`
 	InstallmentNumber int    `json:"installmentNumber"`
 }
 
+// Refund is a single, possibly partial, refund of a payment.
 type Refund struct {
-	ID string `json:"id"` // ID do pagamento original
+	ID        string      `json:"id"`
+	PaymentID string      `json:"paymentId"`
+	Amount    money.Money `json:"amount"`
+	Reason    string      `json:"reason,omitempty"`
+	Status    string      `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	ErrorKind ErrorKind   `json:"errorKind,omitempty"`
+	CreatedAt string      `json:"createdAt"`
+}
+
+type Attempt struct {
+	ID                string    `json:"id"`
+	PaymentID         string    `json:"paymentId"`
+	Provider          string    `json:"provider"`
+	ProviderPaymentID string    `json:"providerPaymentId,omitempty"`
+	Status            string    `json:"status"`
+	Error             string    `json:"error,omitempty"`
+	ErrorKind         ErrorKind `json:"errorKind,omitempty"`
+	CreatedAt         string    `json:"createdAt"`
 }
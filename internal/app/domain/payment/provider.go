@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+)
+
+var (
+	// ErrOutcomeUnknown matches provider calls that failed after the request
+	// was sent, so it cannot be told whether it was applied.
+	ErrOutcomeUnknown = apperror.New(http.StatusBadGateway, "payment_outcome_unknown", "provider outcome unknown")
+	ErrChargeNotFound = errors.New("charge not found on provider")
+)
+
+// Provider is the contract every acquirer integration implements. Each
+// provider package owns the mapping between these types and its own API and
+// reports failures as *ProviderError.
+// Charges carry the payment ID as reference so FindByReference can recover
+// them when a charge outcome is unknown.
+type Provider interface {
+	Name() string
+	Charge(ctx context.Context, request ChargeRequest) (ChargeResult, error)
+	Refund(ctx context.Context, request RefundRequest) (ChargeResult, error)
+	Capture(ctx context.Context, request CaptureRequest) (ChargeResult, error)
+	Cancel(ctx context.Context, request CancelRequest) (ChargeResult, error)
+	Get(ctx context.Context, providerPaymentID string) (ChargeResult, error)
+	FindByReference(ctx context.Context, reference string) (ChargeResult, error)
+	Ping(ctx context.Context) error
+}
+
+// ChargeRequest charges the payment method. With AuthorizeOnly the funds are
+// only held, to be captured or canceled later.
+type ChargeRequest struct {
+	PaymentID   string
+	Amount      money.Money
+	Description string
+	// StatementDescriptor is what the customer sees on their statement;
+	// providers fall back to the description when it is empty.
+	StatementDescriptor string
+	Method              Method
+	AuthorizeOnly       bool
+}
+
+// RefundRequest returns Amount of the charge to the customer. Providers
+// refund the whole remaining balance when Amount is zero.
+type RefundRequest struct {
+	PaymentID         string
+	ProviderPaymentID string
+	Amount            money.Money
+	Reason            string
+}
+
+// CaptureRequest captures Amount of an authorization, releasing the rest.
+// Providers capture the whole authorization when Amount is zero.
+type CaptureRequest struct {
+	PaymentID         string
+	ProviderPaymentID string
+	Amount            money.Money
+}
+
+// CancelRequest releases an authorization that was not captured. Reason is
+// CancelReasonExpired when the authorization outlived its TTL.
+type CancelRequest struct {
+	PaymentID         string
+	ProviderPaymentID string
+	Reason            string
+}
+
+type ChargeResult struct {
+	ProviderPaymentID string
+	Status            Status
+	Amount            money.Money
+	CapturedAmount    money.Money
+	RefundedAmount    money.Money
+	Description       string
+	CreatedAt         string
+}
@@ -0,0 +1,118 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"net/http"
+)
+
+const (
+	ProviderEventProcessed = "processed"
+	ProviderEventDuplicate = "duplicate"
+	ProviderEventIgnored   = "ignored"
+)
+
+var (
+	ErrInvalidWebhookSignature = apperror.New(http.StatusUnauthorized, "invalid_webhook_signature", "webhook signature is invalid")
+	ErrMalformedWebhook        = apperror.New(http.StatusBadRequest, "malformed_webhook", "webhook payload is malformed")
+	ErrWebhookNotConfigured    = apperror.New(http.StatusServiceUnavailable, "webhook_not_configured", "provider webhooks are not configured")
+)
+
+// WebhookReceiver is implemented by providers that notify charge changes
+// asynchronously, such as refunds settled later. ParseWebhook authenticates
+// the request with the provider signature scheme before decoding it.
+type WebhookReceiver interface {
+	ParseWebhook(header http.Header, body []byte) (ProviderEvent, error)
+}
+
+// ProviderEvent is a charge change notified by a provider. Result holds the
+// charge as the provider reports it after the change.
+type ProviderEvent struct {
+	ID       string
+	Provider string
+	Type     string
+	// PaymentID is the reference the charge was created with.
+	PaymentID string
+	Result    ChargeResult
+}
+
+// HandleProviderEvent applies a provider event to the payment it belongs to
+// and reports whether it was processed, a duplicate, or ignored because it
+// matches no payment charged on that provider. Events are deduplicated by
+// provider and ID; applying the same charge state twice changes nothing.
+func (s *Service) HandleProviderEvent(ctx context.Context, event ProviderEvent) (string, error) {
+	seen, err := s.Repository.ProviderEventSeen(ctx, event.Provider, event.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if seen {
+		return ProviderEventDuplicate, nil
+	}
+
+	outcome, err := s.applyProviderEvent(ctx, event)
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.Repository.AddProviderEvent(ctx, &event); err != nil {
+		logger.Warn(ctx, "Error recording provider event", attributes.Attributes{"provider": event.Provider, "event_id": event.ID}.WithError(err))
+	}
+
+	return outcome, nil
+}
+
+func (s *Service) applyProviderEvent(ctx context.Context, event ProviderEvent) (string, error) {
+	ctx = correlation.WithPaymentID(ctx, event.PaymentID)
+
+	attr := attributes.Attributes{"provider": event.Provider, "event_id": event.ID, "event_type": event.Type, "payment_id": event.PaymentID}
+
+	stored, err := s.Repository.FindByID(ctx, event.PaymentID)
+	if errors.Is(err, ErrNotFound) {
+		logger.Warn(ctx, "Provider event for unknown payment ignored", attr)
+		return ProviderEventIgnored, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	// The charge response settles a payment still being charged, whatever
+	// the order in which it and the webhook arrive.
+	if stored.Status == StatusPending && stored.Provider == "" {
+		return ProviderEventIgnored, nil
+	}
+
+	// A payment failed over to another provider, or charged under another ID,
+	// is not the one the event is about.
+	if (stored.Provider != "" && stored.Provider != event.Provider) ||
+		(stored.ProviderPaymentID != "" && stored.ProviderPaymentID != event.Result.ProviderPaymentID) {
+		logger.Warn(ctx, "Provider event for another charge ignored", attr)
+		return ProviderEventIgnored, nil
+	}
+
+	result := event.Result
+	if result.Status == stored.Status && result.CapturedAmount == stored.CapturedAmount && result.RefundedAmount == stored.RefundedAmount && stored.ProviderPaymentID != "" {
+		return ProviderEventProcessed, nil
+	}
+
+	attr["status"] = result.Status
+	if err = stored.Transition(result.Status); err != nil {
+		logger.Warn(ctx, "Provider event for a status the payment cannot move to ignored", attr.WithError(err))
+		return ProviderEventIgnored, nil
+	}
+
+	logger.Info(ctx, "Applying provider event", attr)
+	stored.CapturedAmount = result.CapturedAmount
+	stored.RefundedAmount = result.RefundedAmount
+	stored.Provider = event.Provider
+	stored.ProviderPaymentID = result.ProviderPaymentID
+	s.setAuthorizationExpiry(stored)
+	s.save(ctx, stored)
+
+	return ProviderEventProcessed, nil
+}
@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"sync"
+	"time"
+)
+
+// ReconcilePayment settles a pending payment whose charge outcome is unknown
+// by looking the charge up on its provider through the payment reference. A
+// charge the provider never saw fails the payment; lookup errors leave it
+// pending so it is retried later.
+func (s *Service) ReconcilePayment(ctx context.Context, payment *Payment) error {
+	ctx = correlation.WithPaymentID(ctx, payment.ID)
+
+	if !awaitsReconciliation(payment) {
+		return nil
+	}
+
+	provider, ok := s.Providers.Get(payment.Provider)
+	if !ok {
+		return fmt.Errorf("provider %s is not configured", payment.Provider)
+	}
+
+	provider, err := s.onAccount(ctx, payment, provider)
+	if err != nil {
+		return err
+	}
+
+	result, err := provider.FindByReference(ctx, payment.ID)
+	if errors.Is(err, ErrChargeNotFound) {
+		logger.Info(ctx, "Reconciliation found no charge, payment failed", attributes.Attributes{"payment_id": payment.ID, "provider": payment.Provider})
+		payment.Status = StatusFailed
+		s.save(ctx, payment)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = payment.Transition(result.Status); err != nil {
+		return err
+	}
+
+	logger.Info(ctx, "Reconciliation recovered provider charge", attributes.Attributes{"payment_id": payment.ID, "provider": payment.Provider, "provider_payment_id": result.ProviderPaymentID})
+	payment.CapturedAmount = result.CapturedAmount
+	payment.ProviderPaymentID = result.ProviderPaymentID
+	s.setAuthorizationExpiry(payment)
+	s.save(ctx, payment)
+
+	return nil
+}
+
+// ReconcilePending reconciles up to limit payments that have been pending
+// for longer than ReconcileAfter and returns how many were settled.
+func (s *Service) ReconcilePending(ctx context.Context, limit int) (int, error) {
+	payments, err := s.Repository.FindByStatus(ctx, StatusPending, time.Now().Add(-s.ReconcileAfter), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, payment := range payments {
+		if !awaitsReconciliation(payment) {
+			continue
+		}
+
+		if err = s.ReconcilePayment(ctx, payment); err != nil {
+			logger.Warn(ctx, "Error reconciling payment", attributes.Attributes{"payment_id": payment.ID, "provider": payment.Provider}.WithError(err))
+			continue
+		}
+
+		settled++
+	}
+
+	return settled, nil
+}
+
+// awaitsReconciliation reports whether a pending payment was sent to a
+// provider that never told its outcome. Pending payments without a provider
+// are still being charged.
+func awaitsReconciliation(payment *Payment) bool {
+	return payment.Status == StatusPending && payment.Provider != "" && payment.ProviderPaymentID == ""
+}
+
+func (s *Service) reconcileDue(payment *Payment) bool {
+	if !awaitsReconciliation(payment) {
+		return false
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, payment.CreatedAt)
+	return err == nil && time.Since(createdAt) >= s.ReconcileAfter
+}
+
+// Reconciler periodically settles payments left with an unknown outcome and
+// expires authorizations that were never captured.
+type Reconciler struct {
+	service   *Service
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      sync.WaitGroup
+}
+
+func NewReconciler(service *Service, interval time.Duration, batchSize int) *Reconciler {
+	return &Reconciler{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (r *Reconciler) Start() {
+	r.stop = make(chan struct{})
+	r.done.Add(1)
+
+	go func() {
+		defer r.done.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.run()
+			}
+		}
+	}()
+}
+
+func (r *Reconciler) Stop() {
+	if r.stop == nil {
+		return
+	}
+
+	close(r.stop)
+	r.done.Wait()
+	r.stop = nil
+}
+
+func (r *Reconciler) run() {
+	// Every run gets its own correlation id, so its logs and provider calls
+	// can be told apart from the other runs.
+	ctx := correlation.WithCID(context.Background(), correlation.NewCID())
+
+	settled, err := r.service.ReconcilePending(ctx, r.batchSize)
+	if err != nil {
+		logger.Error(ctx, "Error loading payments to reconcile", attributes.New().WithError(err))
+		return
+	}
+
+	if settled > 0 {
+		logger.Info(ctx, fmt.Sprintf("Reconciled [%d] payments", settled), nil)
+	}
+
+	expired, err := r.service.ExpireAuthorizations(ctx, r.batchSize)
+	if err != nil {
+		logger.Error(ctx, "Error loading authorizations to expire", attributes.New().WithError(err))
+		return
+	}
+
+	if expired > 0 {
+		logger.Info(ctx, fmt.Sprintf("Expired [%d] authorizations", expired), nil)
+	}
+}
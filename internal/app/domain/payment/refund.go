@@ -0,0 +1,138 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/metrics"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefundAmount  = apperror.New(http.StatusUnprocessableEntity, "invalid_refund_amount", "invalid refund amount")
+	ErrRefundExceedsBalance = apperror.New(http.StatusUnprocessableEntity, "refund_exceeds_balance", "refund amount exceeds the refundable balance")
+	ErrNotCaptured          = apperror.New(http.StatusConflict, "payment_not_captured", "payment is not captured, cancel the authorization instead")
+)
+
+// RefundPayment refunds amount of the payment on the provider that charged
+// it, using the provider-side ID recorded when the charge was accepted. A zero
+// amount refunds the whole remaining balance; several partial refunds may be
+// made until the captured amount is exhausted.
+// The refund is reserved against the balance before the provider is called,
+// so concurrent refunds of the same payment cannot exceed it. When the
+// refund outcome is unknown the refund is recorded as such and the stored
+// payment is returned untouched; the next GetPayment refreshes it from the
+// provider.
+func (s *Service) RefundPayment(ctx context.Context, paymentID string, amount money.Decimal, reason string) (*Payment, error) {
+	ctx = correlation.WithPaymentID(ctx, paymentID)
+
+	stored, provider, err := s.findWithProvider(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.Status == StatusAuthorized {
+		return nil, ErrNotCaptured
+	}
+
+	if !stored.Status.CanTransitionTo(StatusRefunded) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, stored.Status, StatusRefunded)
+	}
+
+	balance := refundableBalance(stored)
+	refundAmount := balance
+	if !amount.IsZero() {
+		if refundAmount, err = amount.In(stored.Amount.Currency); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRefundAmount, err)
+		}
+
+		if refundAmount.Amount <= 0 {
+			return nil, fmt.Errorf("%w: must be positive", ErrInvalidRefundAmount)
+		}
+	}
+
+	if balance.Amount <= 0 || refundAmount.Amount > balance.Amount {
+		return nil, ErrRefundExceedsBalance
+	}
+
+	refund := &Refund{
+		ID:        uuid.New().String(),
+		PaymentID: stored.ID,
+		Amount:    refundAmount,
+		Reason:    reason,
+		Status:    RefundPending,
+		CreatedAt: now(),
+	}
+
+	if err = s.Repository.ReserveRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	_, err = provider.Refund(ctx, RefundRequest{
+		PaymentID:         stored.ID,
+		ProviderPaymentID: stored.ProviderPaymentID,
+		Amount:            refundAmount,
+		Reason:            reason,
+	})
+	ctx = context.WithoutCancel(ctx)
+
+	refund.Status = RefundSucceeded
+	switch {
+	case errors.Is(err, ErrOutcomeUnknown):
+		refund.Status = RefundUnknown
+	case err != nil:
+		refund.Status = RefundFailed
+	}
+
+	if err != nil {
+		refund.Error = err.Error()
+		refund.ErrorKind, _ = ErrorKindOf(err)
+	}
+
+	metrics.CountRefund(stored.Provider, refund.Status)
+	if err == nil {
+		metrics.AddRefundedAmount(stored.Provider, refundAmount.Currency, refundAmount.Float64())
+	}
+
+	stored.Refunds = append(stored.Refunds, *refund)
+	refunded, recordErr := s.Repository.SettleRefund(ctx, refund)
+	if recordErr != nil {
+		logger.Error(ctx, "Error recording refund", attributes.Attributes{"payment_id": stored.ID, "refund_id": refund.ID}.WithError(recordErr))
+		refunded = money.New(stored.RefundedAmount.Amount+refundAmount.Amount, stored.Amount.Currency)
+	}
+
+	if errors.Is(err, ErrOutcomeUnknown) {
+		logger.Warn(ctx, "Refund outcome unknown, payment will be refreshed from provider", attributes.Attributes{"payment_id": stored.ID, "provider": stored.Provider}.WithError(err))
+		return stored, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The settled amount includes the refunds made concurrently, so the
+	// status reflects all of them.
+	stored.RefundedAmount = refunded
+	status := StatusPartiallyRefunded
+	if refundableBalance(stored).Amount <= 0 {
+		status = StatusRefunded
+	}
+
+	if err = stored.Transition(status); err != nil {
+		return nil, err
+	}
+	s.save(ctx, stored)
+
+	return stored, nil
+}
+
+func refundableBalance(payment *Payment) money.Money {
+	return money.New(payment.CapturedAmount.Amount-payment.RefundedAmount.Amount, payment.Amount.Currency)
+}
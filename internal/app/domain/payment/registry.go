@@ -0,0 +1,41 @@
+package payment
+
+// Registry keeps the configured providers in failover order.
+type Registry struct {
+	providers []Provider
+	byName    map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{byName: make(map[string]Provider, len(providers))}
+
+	for _, provider := range providers {
+		registry.Register(provider)
+	}
+
+	return registry
+}
+
+func (r *Registry) Register(provider Provider) {
+	if provider == nil {
+		return
+	}
+
+	if _, exists := r.byName[provider.Name()]; exists {
+		return
+	}
+
+	r.providers = append(r.providers, provider)
+	r.byName[provider.Name()] = provider
+}
+
+func (r *Registry) Get(name string) (Provider, bool) {
+	provider, ok := r.byName[name]
+	return provider, ok
+}
+
+func (r *Registry) Ordered() []Provider {
+	providers := make([]Provider, len(r.providers))
+	copy(providers, r.providers)
+	return providers
+}
@@ -0,0 +1,40 @@
+package payment
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+	"time"
+)
+
+var ErrNotFound = apperror.New(http.StatusNotFound, "payment_not_found", "payment not found")
+
+type Repository interface {
+	Create(ctx context.Context, payment *Payment) error
+	// Update stores the payment, refusing with ErrInvalidTransition a status
+	// the stored one cannot move to, and appends status changes to the
+	// history. The event NewEvent returns for the payment, if any, is stored
+	// in the same transaction, to be claimed by ClaimEvents.
+	Update(ctx context.Context, payment *Payment) error
+	// ClaimEvents returns up to limit unpublished events, oldest first, that
+	// no one else claimed until now, and claims them for lease.
+	ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Event, error)
+	MarkEventPublished(ctx context.Context, id string) error
+	FindByID(ctx context.Context, id string) (*Payment, error)
+	FindByStatus(ctx context.Context, status Status, createdBefore time.Time, limit int) ([]*Payment, error)
+	FindStatusHistory(ctx context.Context, paymentID string) ([]StatusChange, error)
+	AddAttempt(ctx context.Context, attempt *Attempt) error
+	FindAttempts(ctx context.Context, paymentID string) ([]Attempt, error)
+	// ReserveRefund records the refund as pending when its amount fits in the
+	// captured amount left by the refunds made or pending, and refuses it
+	// with ErrRefundExceedsBalance otherwise. The check and the record are
+	// atomic, so concurrent refunds cannot together exceed the balance.
+	ReserveRefund(ctx context.Context, refund *Refund) error
+	// SettleRefund records the outcome of a reserved refund, adding a
+	// succeeded one to the refunded amount of the payment, which it returns.
+	SettleRefund(ctx context.Context, refund *Refund) (money.Money, error)
+	FindRefunds(ctx context.Context, paymentID string) ([]Refund, error)
+	ProviderEventSeen(ctx context.Context, provider string, eventID string) (bool, error)
+	AddProviderEvent(ctx context.Context, event *ProviderEvent) error
+}
@@ -0,0 +1,227 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// RouteOrdered tries the rule providers in the listed order.
+	RouteOrdered = "ordered"
+	// RouteWeighted picks the first provider by weight, the rest follow by
+	// decreasing weight.
+	RouteWeighted = "weighted"
+	// RouteCost tries the cheapest provider for the payment first.
+	RouteCost = "cost"
+
+	defaultRouteName = "default"
+)
+
+// Fee is what a provider charges for a payment: a percentage of the amount
+// plus a fixed part in major units. Fees only rank providers, so they are
+// estimated in floating point.
+type Fee struct {
+	Percent float64 `json:"percent"`
+	Fixed   float64 `json:"fixed"`
+}
+
+func (f Fee) For(amount money.Money) float64 {
+	return amount.Float64()*f.Percent/100 + f.Fixed
+}
+
+// RoutingRule selects providers for the payments matching every criteria it
+// sets. Unset criteria match any payment.
+type RoutingRule struct {
+	Name            string         `json:"name"`
+	Currencies      []string       `json:"currencies,omitempty"`
+	MinAmount       money.Decimal  `json:"minAmount"`
+	MaxAmount       money.Decimal  `json:"maxAmount"`
+	BINs            []string       `json:"bins,omitempty"`
+	Brands          []string       `json:"brands,omitempty"`
+	MinInstallments int            `json:"minInstallments,omitempty"`
+	MaxInstallments int            `json:"maxInstallments,omitempty"`
+	Strategy        string         `json:"strategy"`
+	Providers       []string       `json:"providers,omitempty"`
+	Weights         map[string]int `json:"weights,omitempty"`
+}
+
+type RoutingConfig struct {
+	Fees  map[string]Fee `json:"fees,omitempty"`
+	Rules []RoutingRule  `json:"rules"`
+}
+
+// Route is the ordered provider list chosen for a payment and the rule that
+// produced it.
+type Route struct {
+	Rule      string
+	Providers []Provider
+}
+
+// Router turns routing rules into an ordered provider list per payment. The
+// first matching rule wins; payments matching none use the registry order.
+type Router struct {
+	providers *Registry
+	rules     []RoutingRule
+	fees      map[string]Fee
+	random    func() float64
+}
+
+func NewRouter(providers *Registry, config RoutingConfig) (*Router, error) {
+	for _, rule := range config.Rules {
+		if err := validateRule(providers, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range config.Fees {
+		if _, ok := providers.Get(name); !ok {
+			return nil, fmt.Errorf("fee configured for unknown provider %q", name)
+		}
+	}
+
+	return &Router{
+		providers: providers,
+		rules:     config.Rules,
+		fees:      config.Fees,
+		random:    rand.Float64,
+	}, nil
+}
+
+func (r *Router) Route(payment *Payment) Route {
+	for _, rule := range r.rules {
+		if rule.matches(payment) {
+			return Route{Rule: rule.Name, Providers: r.order(rule, payment)}
+		}
+	}
+
+	return Route{Rule: defaultRouteName, Providers: r.providers.Ordered()}
+}
+
+func (r *Router) order(rule RoutingRule, payment *Payment) []Provider {
+	names := rule.Providers
+
+	switch rule.Strategy {
+	case RouteWeighted:
+		names = r.weighted(rule.Weights)
+	case RouteCost:
+		names = r.cheapest(names, payment.Amount)
+	}
+
+	providers := make([]Provider, 0, len(names))
+	for _, name := range names {
+		if provider, ok := r.providers.Get(name); ok {
+			providers = append(providers, provider)
+		}
+	}
+
+	return providers
+}
+
+func (r *Router) weighted(weights map[string]int) []string {
+	names := make([]string, 0, len(weights))
+	total := 0
+	for name, weight := range weights {
+		names = append(names, name)
+		total += weight
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if weights[names[i]] != weights[names[j]] {
+			return weights[names[i]] > weights[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	if total == 0 {
+		return names
+	}
+
+	pick := r.random() * float64(total)
+	for i, name := range names {
+		if pick -= float64(weights[name]); pick < 0 {
+			return append([]string{name}, append(slices.Clone(names[:i]), names[i+1:]...)...)
+		}
+	}
+
+	return names
+}
+
+func (r *Router) cheapest(names []string, amount money.Money) []string {
+	if len(names) == 0 {
+		for _, provider := range r.providers.Ordered() {
+			names = append(names, provider.Name())
+		}
+	}
+
+	names = slices.Clone(names)
+	sort.SliceStable(names, func(i, j int) bool {
+		return r.fees[names[i]].For(amount) < r.fees[names[j]].For(amount)
+	})
+
+	return names
+}
+
+func (rule RoutingRule) matches(payment *Payment) bool {
+	card := payment.Method.Card
+
+	switch {
+	case len(rule.Currencies) > 0 && !slices.ContainsFunc(rule.Currencies, func(currency string) bool { return strings.EqualFold(currency, payment.Amount.Currency) }):
+		return false
+	case !rule.MinAmount.IsZero() && payment.Amount.Decimal().Cmp(rule.MinAmount) < 0:
+		return false
+	case !rule.MaxAmount.IsZero() && payment.Amount.Decimal().Cmp(rule.MaxAmount) > 0:
+		return false
+	case len(rule.BINs) > 0 && !hasAnyPrefix(card.Number, rule.BINs...):
+		return false
+	case len(rule.Brands) > 0 && !slices.Contains(rule.Brands, CardBrand(card.Number)):
+		return false
+	case rule.MinInstallments > 0 && card.InstallmentNumber < rule.MinInstallments:
+		return false
+	case rule.MaxInstallments > 0 && card.InstallmentNumber > rule.MaxInstallments:
+		return false
+	default:
+		return true
+	}
+}
+
+func validateRule(providers *Registry, rule RoutingRule) error {
+	if rule.Name == "" {
+		return errors.New("routing rule without name")
+	}
+
+	names := rule.Providers
+	switch rule.Strategy {
+	case RouteOrdered:
+		if len(names) == 0 {
+			return fmt.Errorf("routing rule %q lists no providers", rule.Name)
+		}
+	case RouteWeighted:
+		if len(rule.Weights) == 0 {
+			return fmt.Errorf("routing rule %q has no weights", rule.Name)
+		}
+
+		names = make([]string, 0, len(rule.Weights))
+		for name, weight := range rule.Weights {
+			if weight < 0 {
+				return fmt.Errorf("routing rule %q has a negative weight for %q", rule.Name, name)
+			}
+			names = append(names, name)
+		}
+	case RouteCost:
+	default:
+		return fmt.Errorf("routing rule %q has unknown strategy %q", rule.Name, rule.Strategy)
+	}
+
+	for _, name := range names {
+		if _, ok := providers.Get(name); !ok {
+			return fmt.Errorf("routing rule %q uses unknown provider %q", rule.Name, name)
+		}
+	}
+
+	return nil
+}
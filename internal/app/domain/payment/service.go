@@ -3,190 +3,350 @@ package payment
 import (
 	"context"
 	"errors"
-	"lucassaraiva5/api-pay/internal/app/domain/paypal"
-	"lucassaraiva5/api-pay/internal/app/domain/stripe"
-	paypalProvider "lucassaraiva5/api-pay/internal/app/providers/paypal"
-	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
-	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/metrics"
+	"lucassaraiva5/api-pay/internal/infra/tracing"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrNoProviderCharge = apperror.New(http.StatusConflict, "payment_not_charged", "payment was not charged by any provider")
+	ErrVaultUnavailable = apperror.New(http.StatusServiceUnavailable, "vault_unavailable", "card tokens are not supported")
+	ErrNoProviders      = apperror.New(http.StatusServiceUnavailable, "no_provider_configured", "no payment provider configured")
 )
 
 type Service struct {
-	PrimaryProvider   Provider
-	SecondaryProvider Provider
+	Providers  *Registry
+	Repository Repository
+	Failover   FailoverPolicy
+	// Router, when set, picks the providers to try for each payment. Without
+	// it the registry order is used.
+	Router *Router
+	// Breakers, when set, skip providers whose circuit is open.
+	Breakers *CircuitBreakers
+	// ReconcileAfter is how long a payment with an unknown outcome is left
+	// alone before its provider is asked about it, so late charges land first.
+	ReconcileAfter time.Duration
+	// AuthorizationTTL is how long an authorization may wait for capture
+	// before it is expired. Zero never expires authorizations.
+	AuthorizationTTL time.Duration
+	// Vault resolves card tokens. Without it only raw cards can be charged.
+	Vault CardVault
+	// Events, when set, is told about every stored change of a payment by
+	// PublishEvents.
+	Events EventPublisher
+	// Accounts, when set, holds the merchants' own provider accounts.
+	// Without it every payment uses the provider defaults.
+	Accounts AccountStore
 }
 
-func New(paypalProvider *paypalProvider.Provider, stripeProvider *stripeProvider.Provider) *Service {
-	return &Service{
-		PrimaryProvider:   paypalProvider,
-		SecondaryProvider: stripeProvider,
-	}
+// CardVault returns the card stored behind a token.
+type CardVault interface {
+	Detokenize(ctx context.Context, token string) (Card, error)
 }
 
-type Provider interface {
-	CreatePayment(req interface{}) (interface{}, error)
-	Refund(paymentID string) (interface{}, error)
-	GetPayment(paymentID string) (interface{}, error)
+func New(repository Repository, providers *Registry) *Service {
+	return &Service{
+		Providers:  providers,
+		Repository: repository,
+		Failover:   DefaultFailoverPolicy,
+	}
 }
 
 func (s *Service) ProcessPayment(ctx context.Context, payment *Payment) (*Payment, error) {
-	result, err := s.processWithProvider(s.PrimaryProvider, payment)
-	if err == nil {
-		return result, nil
+	if err := s.resolveCard(ctx, payment); err != nil {
+		return nil, err
 	}
 
-	result, err = s.processWithProvider(s.SecondaryProvider, payment)
+	scope := merchant.ScopeFrom(ctx)
+	payment.ID = uuid.New().String()
+	payment.MerchantID = scope.MerchantID
+	payment.Livemode = scope.Livemode
+	ctx = correlation.WithPaymentID(ctx, payment.ID)
+	payment.Status = StatusPending
+	payment.CapturedAmount = money.New(0, payment.Amount.Currency)
+	payment.RefundedAmount = money.New(0, payment.Amount.Currency)
+	payment.CreatedAt = now()
+
+	route := s.route(payment)
+	providers, err := s.providersFor(ctx, scope, route.Providers)
 	if err != nil {
-		return nil, errors.New("both providers failed: " + err.Error())
+		return nil, err
+	}
+
+	if err = s.Repository.Create(ctx, payment); err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	logger.Info(ctx, "Payment routed", attributes.Attributes{"payment_id": payment.ID, "rule": route.Rule, "providers": providerNames(providers)})
+
+	err = ErrNoProviders
+	for i, provider := range providers {
+		if !s.allow(provider) {
+			logger.Warn(ctx, "Skipping provider with open circuit breaker", attributes.Attributes{"payment_id": payment.ID, "provider": provider.Name()})
+			if errors.Is(err, ErrNoProviders) {
+				err = ErrNoProviderAvailable
+			}
+			continue
+		}
+
+		err = s.attemptWithProvider(ctx, provider, payment)
+		if err == nil {
+			return payment, nil
+		}
+
+		// The provider may have charged the card, so trying the next one could
+		// charge it twice. The payment is left for reconciliation instead.
+		if errors.Is(err, ErrOutcomeUnknown) {
+			return payment, err
+		}
+
+		if !s.Failover.Allows(err) {
+			logger.Info(ctx, "Provider failure is not safe to fail over", attributes.Attributes{"payment_id": payment.ID, "provider": provider.Name()}.WithError(err))
+			break
+		}
+
+		if i < len(providers)-1 {
+			metrics.CountFailover(provider.Name())
+		}
+	}
+
+	payment.Status = StatusFailed
+	s.save(context.WithoutCancel(ctx), payment)
+
+	return nil, fmt.Errorf("payment failed: %w", err)
 }
 
-func (s *Service) RefundPayment(ctx context.Context, paymentID string) (*Payment, error) {
-	result, err := s.refundWithProvider(s.PrimaryProvider, paymentID)
-	if err == nil {
-		return result, nil
+// resolveCard fills the card of a tokenized payment from the vault, keeping
+// the installments chosen for this payment.
+func (s *Service) resolveCard(ctx context.Context, payment *Payment) error {
+	if payment.Method.Token == "" {
+		return nil
+	}
+
+	if s.Vault == nil {
+		return ErrVaultUnavailable
 	}
 
-	result, err = s.refundWithProvider(s.SecondaryProvider, paymentID)
+	card, err := s.Vault.Detokenize(ctx, payment.Method.Token)
 	if err != nil {
-		return nil, errors.New("both providers failed to refund: " + err.Error())
+		return err
 	}
 
-	return result, nil
+	card.InstallmentNumber = payment.Method.Card.InstallmentNumber
+	payment.Method.Card = card
+	return nil
 }
 
-func (s *Service) GetPayment(ctx context.Context, paymentID string) (*Payment, error) {
-	result, err := s.getWithProvider(s.PrimaryProvider, paymentID)
-	if err == nil {
-		return result, nil
+func (s *Service) route(payment *Payment) Route {
+	if s.Router == nil {
+		return Route{Rule: defaultRouteName, Providers: s.Providers.Ordered()}
 	}
 
-	result, err = s.getWithProvider(s.SecondaryProvider, paymentID)
-	if err != nil {
-		return nil, errors.New("both providers failed to retrieve payment: " + err.Error())
+	return s.Router.Route(payment)
+}
+
+func providerNames(providers []Provider) []string {
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name())
 	}
 
-	return result, nil
+	return names
 }
 
-func (s *Service) processWithProvider(provider Provider, payment *Payment) (*Payment, error) {
-	if provider == nil {
-		return nil, errors.New("provider is nil")
-	}
-
-	var request interface{}
-
-	switch provider.(type) {
-	case *paypalProvider.Provider:
-		request = &paypal.PaymentRequest{
-			Amount:      payment.Amount,
-			Currency:    payment.Currency,
-			Description: payment.Description,
-			PaymentMethod: paypal.PaymentMethod{
-				Type: "card",
-				Card: paypal.Card{
-					Number:       payment.Method.Card.Number,
-					HolderName:   payment.Method.Card.Holder,
-					CVV:          payment.Method.Card.CVV,
-					Expiration:   payment.Method.Card.Expiration,
-					Installments: payment.Method.Card.InstallmentNumber,
-				},
-			},
-		}
-	case *stripeProvider.Provider:
-		request = &stripe.PaymentRequest{
-			Amount:              payment.Amount,
-			Currency:            payment.Currency,
-			StatementDescriptor: payment.Description,
-			PaymentType:         "card",
-			Card: stripe.Card{
-				Number:            payment.Method.Card.Number,
-				Holder:            payment.Method.Card.Holder,
-				CVV:               payment.Method.Card.CVV,
-				Expiration:        payment.Method.Card.Expiration,
-				InstallmentNumber: payment.Method.Card.InstallmentNumber,
-			},
+// GetPayment returns the stored payment refreshed with the status reported by
+// the provider that owns it. When the provider cannot be reached the stored
+// state is returned as is.
+func (s *Service) GetPayment(ctx context.Context, paymentID string) (*Payment, error) {
+	ctx = correlation.WithPaymentID(ctx, paymentID)
+
+	stored, provider, err := s.findWithProvider(ctx, paymentID)
+	if errors.Is(err, ErrNoProviderCharge) {
+		if s.reconcileDue(stored) {
+			if err = s.ReconcilePayment(ctx, stored); err != nil {
+				logger.Warn(ctx, "Error reconciling payment", attributes.Attributes{"payment_id": stored.ID, "provider": stored.Provider}.WithError(err))
+			}
 		}
-	default:
-		return nil, errors.New("unsupported provider type")
+
+		return stored, nil
 	}
 
-	response, err := provider.CreatePayment(request)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.adaptResponse(response)
-}
+	if s.authorizationExpired(stored) {
+		s.expire(ctx, stored, provider)
+		return stored, nil
+	}
 
-func (s *Service) refundWithProvider(provider Provider, paymentID string) (*Payment, error) {
-	if provider == nil {
-		return nil, errors.New("provider is nil")
+	result, err := provider.Get(ctx, stored.ProviderPaymentID)
+	if err != nil {
+		logger.Warn(ctx, "Error refreshing payment from provider", attributes.Attributes{"payment_id": stored.ID, "provider": stored.Provider}.WithError(err))
+		return stored, nil
 	}
 
-	response, err := provider.Refund(paymentID)
+	if result.Status != stored.Status || result.CapturedAmount != stored.CapturedAmount || result.RefundedAmount != stored.RefundedAmount {
+		if err = stored.Transition(result.Status); err != nil {
+			logger.Warn(ctx, "Ignoring provider status the payment cannot move to", attributes.Attributes{"payment_id": stored.ID, "provider": stored.Provider}.WithError(err))
+			return stored, nil
+		}
+
+		stored.CapturedAmount = result.CapturedAmount
+		stored.RefundedAmount = result.RefundedAmount
+		s.setAuthorizationExpiry(stored)
+		s.save(ctx, stored)
+	}
+
+	return stored, nil
+}
+
+// find loads a payment of the merchant and mode ctx acts for. Payments of
+// other scopes are reported missing, so their IDs cannot be probed.
+func (s *Service) find(ctx context.Context, paymentID string) (*Payment, error) {
+	stored, err := s.Repository.FindByID(ctx, paymentID)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.adaptResponse(response)
+	if !merchant.ScopeFrom(ctx).Owns(stored.MerchantID, stored.Livemode) {
+		return nil, ErrNotFound
+	}
+
+	return stored, nil
 }
 
-func (s *Service) getWithProvider(provider Provider, paymentID string) (*Payment, error) {
-	if provider == nil {
-		return nil, errors.New("provider is nil")
+func (s *Service) findWithProvider(ctx context.Context, paymentID string) (*Payment, Provider, error) {
+	stored, err := s.find(ctx, paymentID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	response, err := provider.GetPayment(paymentID)
-	if err != nil {
-		return nil, err
+	if stored.Attempts, err = s.Repository.FindAttempts(ctx, stored.ID); err != nil {
+		return nil, nil, err
 	}
 
-	return s.adaptResponse(response)
+	if stored.Refunds, err = s.Repository.FindRefunds(ctx, stored.ID); err != nil {
+		return nil, nil, err
+	}
+
+	s.setAuthorizationExpiry(stored)
+
+	if stored.Provider == "" || stored.ProviderPaymentID == "" {
+		return stored, nil, ErrNoProviderCharge
+	}
+
+	provider, ok := s.Providers.Get(stored.Provider)
+	if !ok {
+		return stored, nil, fmt.Errorf("provider %s is not configured", stored.Provider)
+	}
+
+	if provider, err = s.onAccount(ctx, stored, provider); err != nil {
+		return stored, nil, err
+	}
+
+	return stored, provider, nil
 }
 
-func (s *Service) adaptResponse(response interface{}) (*Payment, error) {
-	switch res := response.(type) {
-	case *outbound.PaypalPaymentResponse:
-		return &Payment{
-			ID:          res.ID,
-			Amount:      res.Amount,
-			Currency:    res.Currency,
-			Status:      res.Status,
-			Description: res.Description,
-			CreatedAt:   res.CreatedAt,
-			Method: Method{
-				Type: res.Method.Type,
-				Card: Card{
-					Number:            res.Method.Card.Number,
-					Holder:            res.Method.Card.HolderName,
-					CVV:               res.Method.Card.CVV,
-					Expiration:        res.Method.Card.Expiration,
-					InstallmentNumber: res.Method.Card.Installments,
-				},
-			},
-		}, nil
-	case *outbound.StripePaymentResponse:
-		return &Payment{
-			ID:          res.ID,
-			Amount:      res.Amount,
-			Currency:    res.Currency,
-			Status:      res.Status,
-			Description: res.Description,
-			CreatedAt:   res.CreatedAt,
-			Method: Method{
-				Type: res.PaymentType,
-				Card: Card{
-					Number:            res.Card.Number,
-					Holder:            res.Card.Holder,
-					CVV:               res.Card.CVV,
-					Expiration:        res.Card.Expiration,
-					InstallmentNumber: res.Card.InstallmentNumber,
-				},
-			},
-		}, nil
+// attemptWithProvider charges the payment on a single provider and records
+// the attempt. Once the provider has answered, persistence failures are only
+// logged and no longer bound to the request context, so the caller never
+// retries an already captured payment and a cancelled request still leaves
+// its outcome on record.
+func (s *Service) attemptWithProvider(ctx context.Context, provider Provider, payment *Payment) (err error) {
+	ctx, span := tracing.Start(ctx, "payment.attempt", trace.SpanKindInternal,
+		attribute.String("payment.id", payment.ID),
+		attribute.String("provider", provider.Name()),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	result, err := provider.Charge(ctx, NewChargeRequest(payment))
+	ctx = context.WithoutCancel(ctx)
+	s.record(provider, err, time.Since(start))
+	metrics.CountPaymentAttempt(provider.Name(), Outcome(err))
+	span.SetAttributes(attribute.String("outcome", Outcome(err)))
+
+	attempt := &Attempt{
+		ID:        uuid.New().String(),
+		PaymentID: payment.ID,
+		Provider:  provider.Name(),
+		Status:    AttemptSucceeded,
+		CreatedAt: now(),
+	}
+
+	switch {
+	case errors.Is(err, ErrOutcomeUnknown):
+		attempt.Status = AttemptUnknown
+		attempt.Error = err.Error()
+		attempt.ErrorKind, _ = ErrorKindOf(err)
+	case err != nil:
+		attempt.Status = AttemptFailed
+		attempt.Error = err.Error()
+		attempt.ErrorKind, _ = ErrorKindOf(err)
 	default:
-		return nil, errors.New("unsupported response type")
+		attempt.ProviderPaymentID = result.ProviderPaymentID
 	}
+
+	payment.Attempts = append(payment.Attempts, *attempt)
+	if recordErr := s.Repository.AddAttempt(ctx, attempt); recordErr != nil {
+		logger.Error(ctx, "Error recording payment attempt", attributes.Attributes{"payment_id": payment.ID, "provider": attempt.Provider}.WithError(recordErr))
+	}
+
+	if errors.Is(err, ErrOutcomeUnknown) {
+		logger.Warn(ctx, "Payment outcome unknown, queued for reconciliation", attributes.Attributes{"payment_id": payment.ID, "provider": attempt.Provider}.WithError(err))
+		payment.Provider = provider.Name()
+		s.save(ctx, payment)
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if transitionErr := payment.Transition(result.Status); transitionErr != nil {
+		logger.Warn(ctx, "Provider charged the payment with an unexpected status, left pending", attributes.Attributes{"payment_id": payment.ID, "provider": attempt.Provider}.WithError(transitionErr))
+	}
+
+	payment.CapturedAmount = result.CapturedAmount
+	payment.Provider = provider.Name()
+	payment.ProviderPaymentID = result.ProviderPaymentID
+	s.setAuthorizationExpiry(payment)
+	s.save(ctx, payment)
+
+	return nil
+}
+
+func (s *Service) allow(provider Provider) bool {
+	return s.Breakers == nil || s.Breakers.For(breakerName(provider)).Allow()
+}
+
+func (s *Service) record(provider Provider, err error, latency time.Duration) {
+	if s.Breakers != nil {
+		s.Breakers.For(breakerName(provider)).Record(err, latency)
+	}
+}
+
+// save stores a change of the payment. Its event is stored along with it and
+// published later by PublishEvents.
+func (s *Service) save(ctx context.Context, payment *Payment) {
+	if err := s.Repository.Update(ctx, payment); err != nil {
+		logger.Error(ctx, "Error updating stored payment", attributes.Attributes{"payment_id": payment.ID, "status": payment.Status}.WithError(err))
+	}
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
 }
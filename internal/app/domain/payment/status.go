@@ -0,0 +1,92 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+)
+
+// Status is the canonical state of a payment. Providers report charges in
+// their own vocabulary; each provider maps it onto these statuses.
+type Status string
+
+const (
+	// StatusPending is a payment whose charge outcome is not known yet, either
+	// because it is being charged or because its provider must be reconciled.
+	StatusPending Status = "pending"
+	// StatusAuthorized is an authorization holding funds until it is captured
+	// or canceled.
+	StatusAuthorized Status = "authorized"
+	StatusCaptured   Status = "captured"
+	// StatusPartiallyRefunded and StatusRefunded follow a successful refund,
+	// depending on whether part or all of the captured amount was returned.
+	StatusPartiallyRefunded Status = "partially_refunded"
+	StatusRefunded          Status = "refunded"
+	StatusFailed            Status = "failed"
+	// StatusCanceled is an authorization released on request or because it
+	// expired before being captured.
+	StatusCanceled Status = "canceled"
+	// StatusDisputed is a captured payment the cardholder charged back. The
+	// dispute settles it back to captured when won, or refunded when lost.
+	StatusDisputed Status = "disputed"
+)
+
+var ErrInvalidTransition = apperror.New(http.StatusConflict, "invalid_status_transition", "payment cannot move to the requested status")
+
+// transitions lists the statuses each status may move to. A provider may
+// report a charge a few steps ahead of the stored payment, so statuses reach
+// every later status a refresh can observe, never an earlier one. Refunded,
+// failed and canceled payments are final.
+var transitions = map[Status][]Status{
+	StatusPending:           {StatusAuthorized, StatusCaptured, StatusPartiallyRefunded, StatusRefunded, StatusFailed, StatusCanceled},
+	StatusAuthorized:        {StatusCaptured, StatusPartiallyRefunded, StatusRefunded, StatusCanceled},
+	StatusCaptured:          {StatusPartiallyRefunded, StatusRefunded, StatusDisputed},
+	StatusPartiallyRefunded: {StatusRefunded, StatusDisputed},
+	StatusDisputed:          {StatusCaptured, StatusPartiallyRefunded, StatusRefunded},
+}
+
+// StatusChange is an entry of the append-only status history of a payment.
+type StatusChange struct {
+	PaymentID string `json:"paymentId"`
+	// From is empty for the status the payment was created with.
+	From      Status `json:"from,omitempty"`
+	To        Status `json:"to"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// CanTransitionTo reports whether a payment may move from s to next. Staying
+// in the same status is always allowed, since amounts may still change.
+func (s Status) CanTransitionTo(next Status) bool {
+	if s == next {
+		return true
+	}
+
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Transition moves the payment to next, refusing moves the state machine does
+// not allow.
+func (p *Payment) Transition(next Status) error {
+	if !p.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, p.Status, next)
+	}
+
+	p.Status = next
+	return nil
+}
+
+// StatusHistory returns every status the payment went through, oldest first.
+func (s *Service) StatusHistory(ctx context.Context, paymentID string) ([]StatusChange, error) {
+	if _, err := s.find(ctx, paymentID); err != nil {
+		return nil, err
+	}
+
+	return s.Repository.FindStatusHistory(ctx, paymentID)
+}
@@ -1,5 +1,7 @@
 package paypal
 
+import "lucassaraiva5/api-pay/internal/app/domain/money"
+
 type PaymentMethod struct {
 	Type string `json:"type"`
 	Card Card   `json:"card"`
@@ -14,8 +16,7 @@ type Card struct {
 }
 
 type PaymentRequest struct {
-	Amount        float64       `json:"amount"`
-	Currency      string        `json:"currency"`
+	Amount        money.Money   `json:"amount"`
 	Description   string        `json:"description"`
 	PaymentMethod PaymentMethod `json:"paymentMethod"`
 }
@@ -1,20 +1,245 @@
 package domain
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/account"
+	"lucassaraiva5/api-pay/internal/app/domain/health"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
 	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	"lucassaraiva5/api-pay/internal/app/domain/webhook"
 	paypalProvider "lucassaraiva5/api-pay/internal/app/providers/paypal"
 	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	accountRepository "lucassaraiva5/api-pay/internal/app/repositories/account"
+	merchantRepository "lucassaraiva5/api-pay/internal/app/repositories/merchant"
+	paymentRepository "lucassaraiva5/api-pay/internal/app/repositories/payment"
+	vaultRepository "lucassaraiva5/api-pay/internal/app/repositories/vault"
+	webhookRepository "lucassaraiva5/api-pay/internal/app/repositories/webhook"
+	"lucassaraiva5/api-pay/internal/app/transport/presenter"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/variables"
+	"os"
+	"strings"
+	"time"
 )
 
 type Services struct {
-	PaymentService *payment.Service
+	AccountService  *account.Service
+	MerchantService *merchant.Service
+	PaymentService  *payment.Service
+	VaultService    *vault.Service
+	WebhookService  *webhook.Service
+	Reconciler      *payment.Reconciler
+	HealthProber    *payment.HealthProber
+	HealthChecker   *health.Checker
+	Dispatcher      *webhook.Dispatcher
 }
 
-func NewServices() *Services {
-	paypal := paypalProvider.New()
-	stripe := stripeProvider.New()
-	paymentService := payment.New(paypal, stripe)
+var providerFactories = map[string]func() payment.Provider{
+	"paypal": func() payment.Provider { return paypalProvider.New() },
+	"stripe": func() payment.Provider { return stripeProvider.New() },
+}
+
+// failoverKinds are the error kinds that may be configured for failover.
+// Timeouts are left out on purpose: the first provider may have charged.
+var failoverKinds = map[string]payment.ErrorKind{
+	string(payment.ErrorDeclined):       payment.ErrorDeclined,
+	string(payment.ErrorInvalidRequest): payment.ErrorInvalidRequest,
+	string(payment.ErrorNetwork):        payment.ErrorNetwork,
+	string(payment.ErrorProvider):       payment.ErrorProvider,
+}
+
+var ErrNoProvidersConfigured = errors.New("no payment provider configured")
+
+func NewServices(databases *database.Databases) *Services {
+	providers, err := NewProviderRegistry(variables.PaymentProviders())
+	if err != nil {
+		logger.Fatal(context.Background(), "Invalid payment providers configuration", attributes.Attributes{"providers": variables.PaymentProviders()}.WithError(err))
+	}
+
+	failover, err := NewFailoverPolicy(variables.FailoverOn())
+	if err != nil {
+		logger.Fatal(context.Background(), "Invalid failover configuration", attributes.Attributes{"failover_on": variables.FailoverOn()}.WithError(err))
+	}
+
+	router, err := NewRouter(providers, variables.RoutingConfig())
+	if err != nil {
+		logger.Fatal(context.Background(), "Invalid routing configuration", attributes.Attributes{"routing_config": variables.RoutingConfig()}.WithError(err))
+	}
+
+	keyring, err := NewKeyring(variables.VaultKeys())
+	if err != nil {
+		logger.Fatal(context.Background(), "Invalid vault keys configuration", attributes.New().WithError(err))
+	}
+
+	fingerprintKey, err := base64.StdEncoding.DecodeString(variables.VaultFingerprintKey())
+	if err != nil || len(fingerprintKey) < 32 {
+		logger.Fatal(context.Background(), "Invalid vault fingerprint key, expected at least 32 base64 encoded bytes", attributes.New().WithError(err))
+	}
+
+	healthChecker, err := NewHealthChecker(databases, providers, variables.HealthRequired(), variables.HealthCheckTimeout())
+	if err != nil {
+		logger.Fatal(context.Background(), "Invalid health configuration", attributes.Attributes{"health_required": variables.HealthRequired()}.WithError(err))
+	}
+
+	vaultService := vault.New(vaultRepository.New(databases), vaultRepository.NewCVVStore(databases.Redis), keyring, fingerprintKey)
+	vaultService.CVVTTL = variables.VaultCVVTTL()
+
+	webhookService := webhook.New(webhookRepository.New(databases), webhook.NewClient(variables.WebhookTimeout()))
+	webhookService.Render = func(p *payment.Payment) any { return presenter.Payment(p) }
+	webhookService.MaxAttempts = variables.WebhookMaxAttempts()
+	webhookService.Backoff = variables.WebhookBackoff()
+	webhookService.MaxBackoff = variables.WebhookMaxBackoff()
+
+	accountService := account.New(accountRepository.New(databases), accountRepository.NewCache(databases.Redis), keyring, providers)
+	accountService.CacheTTL = variables.ProviderAccountCacheTTL()
+	accountService.BaseURLHosts = map[string][]string{
+		"stripe": variables.StripeAccountHosts(),
+		"paypal": variables.PaypalAccountHosts(),
+	}
+
+	paymentService := payment.New(paymentRepository.New(databases), providers)
+	paymentService.Accounts = accountService
+	paymentService.Vault = vaultService
+	paymentService.Events = webhookService
+	paymentService.Router = router
+	paymentService.Failover = failover
+	paymentService.ReconcileAfter = variables.ReconciliationDelay()
+	paymentService.AuthorizationTTL = variables.AuthorizationTTL()
+	paymentService.Breakers = payment.NewCircuitBreakers(payment.BreakerConfig{
+		WindowSize:   variables.BreakerWindowSize(),
+		MinimumCalls: variables.BreakerMinimumCalls(),
+		FailureRate:  variables.BreakerFailureRate(),
+		SlowCallRate: variables.BreakerSlowCallRate(),
+		SlowCall:     variables.BreakerSlowCall(),
+		OpenTimeout:  variables.BreakerOpenTimeout(),
+	})
+
 	return &Services{
-		PaymentService: paymentService,
+		AccountService:  accountService,
+		MerchantService: merchant.New(merchantRepository.New(databases), variables.AdminAPIKey()),
+		PaymentService:  paymentService,
+		VaultService:    vaultService,
+		WebhookService:  webhookService,
+		Reconciler:      payment.NewReconciler(paymentService, variables.ReconciliationInterval(), variables.ReconciliationBatchSize()),
+		HealthProber:    payment.NewHealthProber(providers, paymentService.Breakers, variables.HealthProbeInterval(), variables.HealthProbeTimeout()),
+		HealthChecker:   healthChecker,
+		Dispatcher:      webhook.NewDispatcher(webhookService, paymentService, variables.WebhookInterval(), variables.WebhookBatchSize()),
+	}
+}
+
+// NewProviderRegistry builds the registry for the configured provider names,
+// keeping their order for failover.
+func NewProviderRegistry(names []string) (*payment.Registry, error) {
+	if len(names) == 0 {
+		return nil, ErrNoProvidersConfigured
+	}
+
+	registry := payment.NewRegistry()
+
+	for _, name := range names {
+		factory, ok := providerFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown payment provider %q", name)
+		}
+
+		registry.Register(factory())
 	}
+
+	return registry, nil
+}
+
+// NewHealthChecker checks the databases, Redis and every provider, requiring
+// the named ones to be up for the service to be ready.
+func NewHealthChecker(databases *database.Databases, providers *payment.Registry, required []string, timeout time.Duration) (*health.Checker, error) {
+	dependencies := []health.Dependency{
+		{Name: "read_db", Ping: databases.Read.Ping},
+		{Name: "write_db", Ping: databases.Write.Ping},
+		{Name: "redis", Ping: databases.Redis.Ping},
+	}
+
+	for _, provider := range providers.Ordered() {
+		dependencies = append(dependencies, health.Dependency{Name: provider.Name(), Ping: provider.Ping})
+	}
+
+	for _, name := range required {
+		found := false
+		for i := range dependencies {
+			if dependencies[i].Name == name {
+				dependencies[i].Required = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown health dependency %q", name)
+		}
+	}
+
+	return health.NewChecker(timeout, dependencies...), nil
+}
+
+// NewRouter builds the payment router from the routing rules file at path.
+// Without a file every payment uses the registry order.
+func NewRouter(providers *payment.Registry, path string) (*payment.Router, error) {
+	var config payment.RoutingConfig
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("decoding routing config: %w", err)
+		}
+	}
+
+	return payment.NewRouter(providers, config)
+}
+
+// NewKeyring builds the vault keyring from id:base64 key specs, the first one
+// being the current key.
+func NewKeyring(specs []string) (*vault.Keyring, error) {
+	keys := make([]vault.Key, 0, len(specs))
+
+	for i, spec := range specs {
+		id, encoded, ok := strings.Cut(spec, ":")
+		if !ok {
+			// The spec is not echoed, it may be a bare secret.
+			return nil, fmt.Errorf("vault key #%d is not id:base64", i+1)
+		}
+
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decoding vault key %q: %w", id, err)
+		}
+
+		keys = append(keys, vault.Key{ID: id, Secret: secret})
+	}
+
+	return vault.NewKeyring(keys...)
+}
+
+// NewFailoverPolicy builds the failover policy from the configured error kind
+// names.
+func NewFailoverPolicy(names []string) (payment.FailoverPolicy, error) {
+	kinds := make([]payment.ErrorKind, 0, len(names))
+
+	for _, name := range names {
+		kind, ok := failoverKinds[name]
+		if !ok {
+			return payment.FailoverPolicy{}, fmt.Errorf("unknown failover error kind %q", name)
+		}
+
+		kinds = append(kinds, kind)
+	}
+
+	return payment.NewFailoverPolicy(kinds...), nil
 }
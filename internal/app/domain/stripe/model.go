@@ -1,5 +1,7 @@
 package stripe
 
+import "lucassaraiva5/api-pay/internal/app/domain/money"
+
 type Card struct {
 	Number            string `json:"number"`
 	Holder            string `json:"holder"`
@@ -9,10 +11,9 @@ type Card struct {
 }
 
 type PaymentRequest struct {
-	Amount              float64 `json:"amount"`
-	Currency            string  `json:"currency"`
-	StatementDescriptor string  `json:"statementDescriptor"`
-	PaymentType         string  `json:"paymentType"`
-	Description         string  `json:"description"`
-	Card                Card    `json:"card"`
+	Amount              money.Money `json:"amount"`
+	StatementDescriptor string      `json:"statementDescriptor"`
+	PaymentType         string      `json:"paymentType"`
+	Description         string      `json:"description"`
+	Card                Card        `json:"card"`
 }
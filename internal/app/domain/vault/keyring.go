@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownKey = errors.New("vault key not configured")
+
+// Key is one AES-256 key of the keyring, named so stored cards remember which
+// key encrypted them.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring encrypts with its current key and decrypts with any of its keys.
+// Rotating means adding a new key in front and keeping the old ones until
+// every card encrypted with them has been re-encrypted.
+type Keyring struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring builds a keyring whose first key is the current one.
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("vault keyring needs at least one key")
+	}
+
+	keyring := &Keyring{current: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("vault key without id")
+		}
+
+		if len(key.Secret) != 32 {
+			return nil, fmt.Errorf("vault key %q must be 32 bytes, got %d", key.ID, len(key.Secret))
+		}
+
+		if _, ok := keyring.aeads[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate vault key %q", key.ID)
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		keyring.aeads[key.ID] = aead
+	}
+
+	return keyring, nil
+}
+
+// CurrentKey is the ID of the key new cards are encrypted with.
+func (k *Keyring) CurrentKey() string {
+	return k.current
+}
+
+// Encrypt seals plaintext with the current key. The additional data is
+// authenticated but not stored, so a ciphertext only opens for the same data.
+func (k *Keyring) Encrypt(plaintext []byte, additionalData []byte) (string, []byte, error) {
+	aead := k.aeads[k.current]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+
+	return k.current, aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (k *Keyring) Decrypt(keyID string, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("vault ciphertext too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, additionalData)
+}
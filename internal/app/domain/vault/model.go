@@ -0,0 +1,25 @@
+package vault
+
+// Token stands for a stored card. It only carries what is safe to show:
+// the fingerprint identifies the same card number across tokens without
+// revealing it.
+type Token struct {
+	ID          string `json:"id"`
+	Fingerprint string `json:"fingerprint"`
+	Last4       string `json:"last4"`
+	Brand       string `json:"brand"`
+	Holder      string `json:"holder"`
+	Expiration  string `json:"expiration"`
+	CreatedAt   string `json:"createdAt"`
+	// MerchantID and Livemode are the scope the card was tokenized in; only
+	// payments of that scope may charge it.
+	MerchantID string `json:"-"`
+	Livemode   bool   `json:"livemode"`
+}
+
+// StoredCard is a token with its card number encrypted by the key KeyID.
+type StoredCard struct {
+	Token
+	KeyID      string
+	Ciphertext []byte
+}
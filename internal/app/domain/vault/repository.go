@@ -0,0 +1,24 @@
+package vault
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+	"time"
+)
+
+var ErrTokenNotFound = apperror.New(http.StatusUnprocessableEntity, "token_not_found", "card token not found")
+
+type Repository interface {
+	Create(ctx context.Context, card *StoredCard) error
+	FindByID(ctx context.Context, id string) (*StoredCard, error)
+	UpdateEncryption(ctx context.Context, card *StoredCard) error
+}
+
+// CVVStore keeps security codes for a short time only; they must never be
+// persisted. Take removes the code, returning "" once it expired or was
+// already taken.
+type CVVStore interface {
+	Save(ctx context.Context, tokenID string, cvv string, ttl time.Duration) error
+	Take(ctx context.Context, tokenID string) (string, error)
+}
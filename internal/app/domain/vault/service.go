@@ -0,0 +1,175 @@
+package vault
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	tokenPrefix = "tok_"
+
+	// DefaultCVVTTL bounds how long a token can be charged with its security
+	// code; later charges go without it.
+	DefaultCVVTTL = 15 * time.Minute
+)
+
+// Service stores cards encrypted and hands out tokens for them, so raw card
+// numbers only exist in memory while a token is charged.
+type Service struct {
+	Repository Repository
+	CVVs       CVVStore
+	Keys       *Keyring
+	// FingerprintKey keys the HMAC of card numbers. It must outlive key
+	// rotations, or the same card would get a new fingerprint.
+	FingerprintKey []byte
+	CVVTTL         time.Duration
+}
+
+func New(repository Repository, cvvs CVVStore, keys *Keyring, fingerprintKey []byte) *Service {
+	return &Service{
+		Repository:     repository,
+		CVVs:           cvvs,
+		Keys:           keys,
+		FingerprintKey: fingerprintKey,
+		CVVTTL:         DefaultCVVTTL,
+	}
+}
+
+// Tokenize stores the card number encrypted and the CVV in the short-lived
+// store, and returns the token charging the card.
+func (s *Service) Tokenize(ctx context.Context, card payment.Card) (*Token, error) {
+	scope := merchant.ScopeFrom(ctx)
+	stored := &StoredCard{
+		Token: Token{
+			ID:          tokenPrefix + uuid.New().String(),
+			Fingerprint: s.fingerprint(card.Number),
+			Last4:       last4(card.Number),
+			Brand:       payment.CardBrand(card.Number),
+			Holder:      card.Holder,
+			Expiration:  card.Expiration,
+			CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+			MerchantID:  scope.MerchantID,
+			Livemode:    scope.Livemode,
+		},
+	}
+
+	var err error
+	stored.KeyID, stored.Ciphertext, err = s.Keys.Encrypt([]byte(card.Number), []byte(stored.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.Repository.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	if card.CVV != "" {
+		if err = s.CVVs.Save(ctx, stored.ID, card.CVV, s.CVVTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Info(ctx, "Card tokenized", attributes.Attributes{"token": stored.ID, "brand": stored.Brand, "key_id": stored.KeyID})
+	return &stored.Token, nil
+}
+
+// Detokenize returns the card behind the token for a charge. The CVV is
+// handed out once; a card encrypted with an older key is re-encrypted with
+// the current one on the way. Tokens of another scope are not found.
+func (s *Service) Detokenize(ctx context.Context, tokenID string) (payment.Card, error) {
+	stored, err := s.Repository.FindByID(ctx, tokenID)
+	if err != nil {
+		return payment.Card{}, err
+	}
+
+	if !merchant.ScopeFrom(ctx).Owns(stored.MerchantID, stored.Livemode) {
+		return payment.Card{}, ErrTokenNotFound
+	}
+
+	number, err := s.Keys.Decrypt(stored.KeyID, stored.Ciphertext, []byte(stored.ID))
+	if err != nil {
+		return payment.Card{}, err
+	}
+
+	if stored.KeyID != s.Keys.CurrentKey() {
+		s.reencrypt(ctx, stored, number)
+	}
+
+	cvv, err := s.CVVs.Take(ctx, stored.ID)
+	if err != nil {
+		logger.Warn(ctx, "Error taking card security code, charging without it", attributes.Attributes{"token": stored.ID}.WithError(err))
+	}
+
+	return payment.Card{
+		Number:     string(number),
+		Holder:     stored.Holder,
+		CVV:        cvv,
+		Expiration: stored.Expiration,
+	}, nil
+}
+
+// CardFingerprint returns the fingerprint of the card with number, or of the
+// card behind the token when there is no number. It is "" when neither names
+// a card of the scope.
+func (s *Service) CardFingerprint(ctx context.Context, number string, tokenID string) (string, error) {
+	if number != "" {
+		return s.fingerprint(number), nil
+	}
+
+	if tokenID == "" {
+		return "", nil
+	}
+
+	stored, err := s.Repository.FindByID(ctx, tokenID)
+	if errors.Is(err, ErrTokenNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if !merchant.ScopeFrom(ctx).Owns(stored.MerchantID, stored.Livemode) {
+		return "", nil
+	}
+
+	return stored.Fingerprint, nil
+}
+
+func (s *Service) reencrypt(ctx context.Context, stored *StoredCard, number []byte) {
+	previous := stored.KeyID
+
+	keyID, ciphertext, err := s.Keys.Encrypt(number, []byte(stored.ID))
+	if err == nil {
+		stored.KeyID, stored.Ciphertext = keyID, ciphertext
+		err = s.Repository.UpdateEncryption(ctx, stored)
+	}
+
+	if err != nil {
+		logger.Warn(ctx, "Error re-encrypting card with the current key", attributes.Attributes{"token": stored.ID, "key_id": previous}.WithError(err))
+	}
+}
+
+func (s *Service) fingerprint(number string) string {
+	mac := hmac.New(sha256.New, s.FingerprintKey)
+	mac.Write([]byte(number))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func last4(number string) string {
+	if len(number) < 4 {
+		return number
+	}
+
+	return number[len(number)-4:]
+}
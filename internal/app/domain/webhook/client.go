@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrPrivateAddress = errors.New("webhook endpoint resolves to a non-public address")
+
+// sharedAddressSpace is the carrier-grade NAT range, private in practice
+// though netip does not report it so.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewClient returns the client deliveries are sent with. It refuses to
+// connect to loopback, private, link-local and other non-public addresses,
+// checked on the address actually dialed, so an endpoint cannot reach the
+// internal network through its DNS answers or redirects.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublicOnly}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be the address dialed instead of the endpoint.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func dialPublicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if ip = ip.Unmap(); !publicAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+	}
+
+	return nil
+}
+
+func publicAddress(ip netip.Addr) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"sync"
+	"time"
+)
+
+// Outbox holds the events stored with the changes they report, until they
+// are published.
+type Outbox interface {
+	PublishEvents(ctx context.Context, limit int) (int, error)
+}
+
+// Dispatcher periodically publishes the stored payment events and sends the
+// webhook deliveries that are due.
+type Dispatcher struct {
+	service   *Service
+	outbox    Outbox
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      sync.WaitGroup
+}
+
+func NewDispatcher(service *Service, outbox Outbox, interval time.Duration, batchSize int) *Dispatcher {
+	return &Dispatcher{
+		service:   service,
+		outbox:    outbox,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (d *Dispatcher) Start() {
+	d.stop = make(chan struct{})
+	d.done.Add(1)
+
+	go func() {
+		defer d.done.Done()
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				d.run()
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) Stop() {
+	if d.stop == nil {
+		return
+	}
+
+	close(d.stop)
+	d.done.Wait()
+	d.stop = nil
+}
+
+func (d *Dispatcher) run() {
+	// Every run gets its own correlation id, so its logs and provider calls
+	// can be told apart from the other runs.
+	ctx := correlation.WithCID(context.Background(), correlation.NewCID())
+
+	if published, err := d.outbox.PublishEvents(ctx, d.batchSize); err != nil {
+		logger.Error(ctx, "Error loading payment events", attributes.New().WithError(err))
+	} else if published > 0 {
+		logger.Info(ctx, fmt.Sprintf("Published [%d] payment events", published), nil)
+	}
+
+	delivered, err := d.service.Dispatch(ctx, d.batchSize)
+	if err != nil {
+		logger.Error(ctx, "Error loading webhook deliveries", attributes.New().WithError(err))
+		return
+	}
+
+	if delivered > 0 {
+		logger.Info(ctx, fmt.Sprintf("Delivered [%d] webhooks", delivered), nil)
+	}
+}
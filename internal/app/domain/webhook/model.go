@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Endpoint is a merchant URL receiving payment events, signed with Secret.
+// An endpoint without Events receives every event. It only receives the
+// events of payments made in its merchant and mode.
+type Endpoint struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"-"`
+	Events     []string `json:"events"`
+	MerchantID string   `json:"-"`
+	Livemode   bool     `json:"livemode"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+func (e *Endpoint) Subscribed(eventType string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, eventType)
+}
+
+// Event is the body posted to endpoints. Data holds the payment as the API
+// returns it when the event happened.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	PaymentID string          `json:"paymentId"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt string          `json:"createdAt"`
+}
+
+// Delivery tracks sending one event to one endpoint. It stays pending, with
+// NextAttemptAt pushed back after each failure, until the endpoint accepts
+// it or the attempts run out.
+type Delivery struct {
+	ID            string    `json:"id"`
+	EventID       string    `json:"eventId"`
+	EndpointID    string    `json:"endpointId"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt string    `json:"nextAttemptAt,omitempty"`
+	LastError     string    `json:"lastError,omitempty"`
+	CreatedAt     string    `json:"createdAt"`
+	Log           []Attempt `json:"log,omitempty"`
+}
+
+// Attempt is one entry of the delivery log.
+type Attempt struct {
+	ID             string `json:"id"`
+	DeliveryID     string `json:"deliveryId"`
+	ResponseStatus int    `json:"responseStatus,omitempty"`
+	Error          string `json:"error,omitempty"`
+	DurationMs     int64  `json:"durationMs"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+// Job is a delivery claimed for sending, with what is needed to send it.
+type Job struct {
+	Delivery Delivery
+	Event    Event
+	URL      string
+	Secret   string
+}
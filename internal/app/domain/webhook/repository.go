@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrEndpointNotFound = apperror.New(http.StatusNotFound, "webhook_endpoint_not_found", "webhook endpoint not found")
+	ErrDeliveryNotFound = apperror.New(http.StatusNotFound, "webhook_delivery_not_found", "webhook delivery not found")
+)
+
+type Repository interface {
+	CreateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	// FindEndpoint returns the endpoint, even once deleted, so its delivery
+	// log stays reachable.
+	FindEndpoint(ctx context.Context, id string) (*Endpoint, error)
+	// FindEndpoints returns the endpoints of the scope that are not deleted.
+	FindEndpoints(ctx context.Context, scope merchant.Scope) ([]Endpoint, error)
+	DeleteEndpoint(ctx context.Context, id string) error
+	// CreateEvent stores the event together with its deliveries. They form
+	// the outbox the dispatcher sends from. An event already stored is kept
+	// as is, without adding the deliveries again.
+	CreateEvent(ctx context.Context, event *Event, deliveries []Delivery) error
+	// ClaimDue returns up to limit pending deliveries due at now and moves
+	// their next attempt to now+lease, so other dispatchers leave them alone
+	// while they are sent.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Job, error)
+	UpdateDelivery(ctx context.Context, delivery *Delivery) error
+	FindDelivery(ctx context.Context, id string) (*Delivery, error)
+	FindDeliveries(ctx context.Context, endpointID string, limit int) ([]Delivery, error)
+	AddAttempt(ctx context.Context, attempt *Attempt) error
+	FindAttempts(ctx context.Context, deliveryID string) ([]Attempt, error)
+}
@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 30 * time.Second
+	DefaultMaxBackoff  = 6 * time.Hour
+
+	secretPrefix = "whsec_"
+	eventPrefix  = "evt_"
+)
+
+// Service notifies merchant endpoints of payment events. Events are stored
+// with one delivery per subscribed endpoint and sent later by Dispatch, so a
+// slow or failing endpoint never holds up a payment.
+type Service struct {
+	Repository Repository
+	Client     *http.Client
+	// Render turns a payment into the event data. Without it the payment is
+	// encoded as is.
+	Render func(*payment.Payment) any
+	// MaxAttempts bounds the sends of a delivery before it is failed. Failed
+	// sends are retried after Backoff, doubled on each attempt up to
+	// MaxBackoff.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+func New(repository Repository, client *http.Client) *Service {
+	return &Service{
+		Repository:  repository,
+		Client:      client,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+	}
+}
+
+// CreateEndpoint registers url for the given events, all of them when empty,
+// and generates the secret its deliveries are signed with.
+func (s *Service) CreateEndpoint(ctx context.Context, url string, events []string) (*Endpoint, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	scope := merchant.ScopeFrom(ctx)
+	endpoint := &Endpoint{
+		ID:         uuid.New().String(),
+		URL:        url,
+		Secret:     secretPrefix + hex.EncodeToString(secret),
+		Events:     events,
+		MerchantID: scope.MerchantID,
+		Livemode:   scope.Livemode,
+		CreatedAt:  now().Format(time.RFC3339),
+	}
+
+	if err := s.Repository.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+func (s *Service) Endpoints(ctx context.Context) ([]Endpoint, error) {
+	return s.Repository.FindEndpoints(ctx, merchant.ScopeFrom(ctx))
+}
+
+func (s *Service) DeleteEndpoint(ctx context.Context, id string) error {
+	if _, err := s.endpoint(ctx, id); err != nil {
+		return err
+	}
+
+	return s.Repository.DeleteEndpoint(ctx, id)
+}
+
+// endpoint loads an endpoint of the scope ctx acts for. Endpoints of other
+// scopes are reported missing.
+func (s *Service) endpoint(ctx context.Context, id string) (*Endpoint, error) {
+	endpoint, err := s.Repository.FindEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !merchant.ScopeFrom(ctx).Owns(endpoint.MerchantID, endpoint.Livemode) {
+		return nil, ErrEndpointNotFound
+	}
+
+	return endpoint, nil
+}
+
+// delivery loads a delivery to an endpoint of the scope ctx acts for.
+func (s *Service) delivery(ctx context.Context, id string) (*Delivery, error) {
+	delivery, err := s.Repository.FindDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.endpoint(ctx, delivery.EndpointID); errors.Is(err, ErrEndpointNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Publish stores the event for every endpoint of the payment's scope
+// subscribed to it. It makes Service a payment.EventPublisher: the webhook
+// event is named after the payment event, so publishing one again is a no-op.
+func (s *Service) Publish(ctx context.Context, paymentEvent *payment.Event) error {
+	p := paymentEvent.Payment
+	endpoints, err := s.Repository.FindEndpoints(ctx, merchant.Scope{MerchantID: p.MerchantID, Livemode: p.Livemode})
+	if err != nil {
+		return err
+	}
+
+	var data any = p
+	if s.Render != nil {
+		data = s.Render(p)
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	event := &Event{
+		ID:        eventPrefix + paymentEvent.ID,
+		Type:      paymentEvent.Type,
+		PaymentID: p.ID,
+		Data:      encoded,
+		CreatedAt: paymentEvent.CreatedAt,
+	}
+
+	deliveries := make([]Delivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribed(event.Type) {
+			continue
+		}
+
+		deliveries = append(deliveries, Delivery{
+			ID:            uuid.New().String(),
+			EventID:       event.ID,
+			EndpointID:    endpoint.ID,
+			Status:        DeliveryPending,
+			NextAttemptAt: event.CreatedAt,
+			CreatedAt:     event.CreatedAt,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return s.Repository.CreateEvent(ctx, event, deliveries)
+}
+
+// Dispatch sends up to limit due deliveries and returns how many the
+// endpoints accepted.
+func (s *Service) Dispatch(ctx context.Context, limit int) (int, error) {
+	jobs, err := s.Repository.ClaimDue(ctx, now(), s.lease(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, job := range jobs {
+		if s.deliver(ctx, job) {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// Deliveries returns the latest deliveries of an endpoint, newest first.
+func (s *Service) Deliveries(ctx context.Context, endpointID string, limit int) ([]Delivery, error) {
+	if _, err := s.endpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+
+	return s.Repository.FindDeliveries(ctx, endpointID, limit)
+}
+
+// Delivery returns a delivery with its log of attempts.
+func (s *Service) Delivery(ctx context.Context, id string) (*Delivery, error) {
+	delivery, err := s.delivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if delivery.Log, err = s.Repository.FindAttempts(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Redeliver queues a delivery to be sent again right away, whatever its
+// status, with a fresh budget of attempts. Its log is kept.
+func (s *Service) Redeliver(ctx context.Context, id string) (*Delivery, error) {
+	delivery, err := s.delivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now().Format(time.RFC3339)
+
+	if err = s.Repository.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	logger.Info(ctx, "Webhook delivery queued for redelivery", attributes.Attributes{"delivery_id": delivery.ID, "event_id": delivery.EventID})
+	return delivery, nil
+}
+
+// deliver sends the job once, logs the attempt and schedules the next one
+// when the endpoint did not accept it.
+func (s *Service) deliver(ctx context.Context, job Job) bool {
+	start := time.Now()
+	status, err := s.send(ctx, job)
+
+	attempt := &Attempt{
+		ID:             uuid.New().String(),
+		DeliveryID:     job.Delivery.ID,
+		ResponseStatus: status,
+		DurationMs:     time.Since(start).Milliseconds(),
+		CreatedAt:      now().Format(time.RFC3339),
+	}
+
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	if recordErr := s.Repository.AddAttempt(ctx, attempt); recordErr != nil {
+		logger.Error(ctx, "Error recording webhook attempt", attributes.Attributes{"delivery_id": job.Delivery.ID}.WithError(recordErr))
+	}
+
+	delivery := job.Delivery
+	delivery.Attempts++
+	delivery.LastError = attempt.Error
+
+	switch {
+	case err == nil:
+		delivery.Status = DeliverySucceeded
+		delivery.NextAttemptAt = ""
+	case delivery.Attempts >= s.MaxAttempts:
+		delivery.Status = DeliveryFailed
+		delivery.NextAttemptAt = ""
+		logger.Warn(ctx, "Webhook delivery failed, attempts exhausted", attributes.Attributes{"delivery_id": delivery.ID, "event_id": delivery.EventID, "url": job.URL}.WithError(err))
+	default:
+		delivery.NextAttemptAt = now().Add(s.backoff(delivery.Attempts)).Format(time.RFC3339)
+	}
+
+	if updateErr := s.Repository.UpdateDelivery(ctx, &delivery); updateErr != nil {
+		logger.Error(ctx, "Error updating webhook delivery", attributes.Attributes{"delivery_id": delivery.ID}.WithError(updateErr))
+	}
+
+	return err == nil
+}
+
+// send posts the event to the endpoint and returns the response status. Any
+// status outside 2xx is an error.
+func (s *Service) send(ctx context.Context, job Job) (int, error) {
+	body, err := json.Marshal(job.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventIDHeader, job.Event.ID)
+	request.Header.Set(EventTypeHeader, job.Event.Type)
+	request.Header.Set(SignatureHeader, Sign(job.Secret, now(), body))
+
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("endpoint answered with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (s *Service) backoff(attempts int) time.Duration {
+	wait := s.Backoff
+	for i := 1; i < attempts && wait < s.MaxBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, s.MaxBackoff)
+}
+
+// lease keeps claimed deliveries from other dispatchers for longer than a
+// send may take.
+func (s *Service) lease() time.Duration {
+	return max(2*s.Client.Timeout, time.Minute)
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
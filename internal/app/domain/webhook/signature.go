@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "Webhook-Signature"
+	EventIDHeader   = "Webhook-Id"
+	EventTypeHeader = "Webhook-Event"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the signature header of body sent at timestamp, in the form
+// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">. Signing
+// the timestamp lets receivers reject replayed deliveries.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + signature(secret, unix, body)
+}
+
+// Verify checks a signature header made by Sign, rejecting signatures older
+// than tolerance. It is what merchants run on their side.
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix string
+	var signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidSignature)
+	}
+
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := signature(secret, unix, body)
+	for _, candidate := range signatures {
+		if hmac.Equal([]byte(candidate), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func signature(secret string, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package paypalProvider
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/paypal"
+)
+
+type chargeResponse struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	OriginalAmount int64  `json:"originalAmount"`
+	CurrentAmount  int64  `json:"currentAmount"`
+	CapturedAmount int64  `json:"capturedAmount"`
+	Currency       string `json:"currency"`
+	Description    string `json:"description"`
+	CreatedAt      string `json:"createdAt"`
+	PaymentMethod  string `json:"paymentMethod"`
+	CardId         string `json:"cardId"`
+	// Reference is the payment ID the charge was created with.
+	Reference string `json:"reference"`
+}
+
+func toPaymentRequest(request payment.ChargeRequest) *paypal.PaymentRequest {
+	return &paypal.PaymentRequest{
+		Amount:      request.Amount,
+		Description: request.Description,
+		PaymentMethod: paypal.PaymentMethod{
+			Type: "card",
+			Card: paypal.Card{
+				Number:       request.Method.Card.Number,
+				HolderName:   request.Method.Card.Holder,
+				CVV:          request.Method.Card.CVV,
+				Expiration:   request.Method.Card.Expiration,
+				Installments: request.Method.Card.InstallmentNumber,
+			},
+		},
+	}
+}
+
+// chargeStatuses maps PayPal charge statuses onto the payment statuses shared
+// by every provider. PayPal reports a completed sale as authorized and a hold
+// as pending capture.
+var chargeStatuses = map[string]payment.Status{
+	"pending_capture":    payment.StatusAuthorized,
+	"authorized":         payment.StatusCaptured,
+	"captured":           payment.StatusCaptured,
+	"partially_refunded": payment.StatusPartiallyRefunded,
+	"refunded":           payment.StatusRefunded,
+	"canceled":           payment.StatusCanceled,
+	"expired":            payment.StatusCanceled,
+	"failed":             payment.StatusFailed,
+	"disputed":           payment.StatusDisputed,
+}
+
+// toStatus maps a PayPal charge status. A status PayPal added after this
+// mapping is left pending until a later refresh settles it.
+func toStatus(status string) payment.Status {
+	if mapped, ok := chargeStatuses[status]; ok {
+		return mapped
+	}
+
+	return payment.StatusPending
+}
+
+func toChargeResult(response *chargeResponse) payment.ChargeResult {
+	return payment.ChargeResult{
+		ProviderPaymentID: response.ID,
+		Status:            toStatus(response.Status),
+		Amount:            money.New(response.CurrentAmount, response.Currency),
+		CapturedAmount:    money.New(response.CapturedAmount, response.Currency),
+		RefundedAmount:    money.New(response.CapturedAmount-response.CurrentAmount, response.Currency),
+		Description:       response.Description,
+		CreatedAt:         response.CreatedAt,
+	}
+}
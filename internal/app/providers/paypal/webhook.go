@@ -0,0 +1,94 @@
+package paypalProvider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	transmissionIDHeader   = "Paypal-Transmission-Id"
+	transmissionTimeHeader = "Paypal-Transmission-Time"
+	transmissionSigHeader  = "Paypal-Transmission-Sig"
+	// signatureTolerance rejects replays of old webhooks.
+	signatureTolerance = 5 * time.Minute
+)
+
+type webhookEvent struct {
+	ID         string         `json:"id"`
+	EventType  string         `json:"event_type"`
+	CreateTime string         `json:"create_time"`
+	Resource   chargeResponse `json:"resource"`
+}
+
+// ParseWebhook verifies the PayPal transmission signature and decodes the
+// charge the event carries. PayPal signs
+// "<transmission id>|<transmission time>|<webhook id>|<crc32 of body>"; the
+// signature is an HMAC-SHA256 with the webhook secret, base64 encoded.
+func (p *Provider) ParseWebhook(header http.Header, body []byte) (payment.ProviderEvent, error) {
+	if p.webhookSecret == "" {
+		return payment.ProviderEvent{}, payment.ErrWebhookNotConfigured
+	}
+
+	if err := p.verifySignature(header, body, time.Now()); err != nil {
+		return payment.ProviderEvent{}, payment.ErrInvalidWebhookSignature.Wrap(err)
+	}
+
+	var event webhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return payment.ProviderEvent{}, payment.ErrMalformedWebhook.Wrap(err)
+	}
+
+	if event.ID == "" || event.Resource.ID == "" || event.Resource.Reference == "" {
+		return payment.ProviderEvent{}, payment.ErrMalformedWebhook
+	}
+
+	return payment.ProviderEvent{
+		ID:        event.ID,
+		Provider:  providerName,
+		Type:      event.EventType,
+		PaymentID: event.Resource.Reference,
+		Result:    toChargeResult(&event.Resource),
+	}, nil
+}
+
+func (p *Provider) verifySignature(header http.Header, body []byte, now time.Time) error {
+	transmissionID := header.Get(transmissionIDHeader)
+	transmissionTime := header.Get(transmissionTimeHeader)
+
+	sentAt, err := time.Parse(time.RFC3339, transmissionTime)
+	if err != nil || transmissionID == "" {
+		return errors.New("missing transmission id or time")
+	}
+
+	if age := now.Sub(sentAt); age > signatureTolerance || age < -signatureTolerance {
+		return errors.New("transmission time outside tolerance")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(header.Get(transmissionSigHeader))
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(signature, Sign(p.webhookSecret, transmissionID, transmissionTime, p.webhookID, body)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
+// Sign returns the transmission signature PayPal sends for body.
+func Sign(secret string, transmissionID string, transmissionTime string, webhookID string, body []byte) []byte {
+	message := transmissionID + "|" + transmissionTime + "|" + webhookID + "|" + strconv.FormatUint(uint64(crc32.ChecksumIEEE(body)), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,79 @@
+package stripeProvider
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/stripe"
+)
+
+type transactionResponse struct {
+	ID                  string      `json:"id"`
+	Status              string      `json:"status"`
+	Amount              int64       `json:"amount"`
+	OriginalAmount      int64       `json:"originalAmount"`
+	CapturedAmount      int64       `json:"capturedAmount"`
+	Currency            string      `json:"currency"`
+	Description         string      `json:"description"`
+	PaymentType         string      `json:"paymentType"`
+	CreatedAt           string      `json:"date"`
+	StatementDescriptor string      `json:"statementDescriptor"`
+	Card                stripe.Card `json:"card"`
+	// Reference is the payment ID the transaction was created with.
+	Reference string `json:"reference"`
+}
+
+func toPaymentRequest(request payment.ChargeRequest) *stripe.PaymentRequest {
+	statementDescriptor := request.StatementDescriptor
+	if statementDescriptor == "" {
+		statementDescriptor = request.Description
+	}
+
+	return &stripe.PaymentRequest{
+		Amount:              request.Amount,
+		StatementDescriptor: statementDescriptor,
+		PaymentType:         "card",
+		Card: stripe.Card{
+			Number:            request.Method.Card.Number,
+			Holder:            request.Method.Card.Holder,
+			CVV:               request.Method.Card.CVV,
+			Expiration:        request.Method.Card.Expiration,
+			InstallmentNumber: request.Method.Card.InstallmentNumber,
+		},
+	}
+}
+
+// transactionStatuses maps Stripe transaction statuses onto the payment
+// statuses shared by every provider. Stripe refunds by voiding.
+var transactionStatuses = map[string]payment.Status{
+	"requires_capture": payment.StatusAuthorized,
+	"paid":             payment.StatusCaptured,
+	"captured":         payment.StatusCaptured,
+	"partially_voided": payment.StatusPartiallyRefunded,
+	"voided":           payment.StatusRefunded,
+	"canceled":         payment.StatusCanceled,
+	"expired":          payment.StatusCanceled,
+	"failed":           payment.StatusFailed,
+	"disputed":         payment.StatusDisputed,
+}
+
+// toStatus maps a Stripe transaction status. A status Stripe added after this
+// mapping is left pending until a later refresh settles it.
+func toStatus(status string) payment.Status {
+	if mapped, ok := transactionStatuses[status]; ok {
+		return mapped
+	}
+
+	return payment.StatusPending
+}
+
+func toChargeResult(response *transactionResponse) payment.ChargeResult {
+	return payment.ChargeResult{
+		ProviderPaymentID: response.ID,
+		Status:            toStatus(response.Status),
+		Amount:            money.New(response.Amount, response.Currency),
+		CapturedAmount:    money.New(response.CapturedAmount, response.Currency),
+		RefundedAmount:    money.New(response.CapturedAmount-response.Amount, response.Currency),
+		Description:       response.Description,
+		CreatedAt:         response.CreatedAt,
+	}
+}
@@ -6,18 +6,49 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"lucassaraiva5/api-pay/internal/app/domain/stripe"
-	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"io"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
 	"lucassaraiva5/api-pay/internal/infra/logger"
 	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/metrics"
+	"lucassaraiva5/api-pay/internal/infra/tracing"
+	"lucassaraiva5/api-pay/internal/infra/variables"
 	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type Provider struct{}
+const providerName = "stripe"
+
+type Provider struct {
+	client *http.Client
+	// webhookSecret signs the webhooks Stripe sends; without it they are refused.
+	webhookSecret string
+}
 
 func New() *Provider {
-	return &Provider{}
+	provider := NewWithClient(&http.Client{Timeout: variables.StripeTimeout()})
+	provider.webhookSecret = variables.StripeWebhookSecret()
+	return provider
+}
+
+// NewWithClient builds the provider on top of the given HTTP client, which
+// bounds every call made to the Stripe API.
+func NewWithClient(client *http.Client) *Provider {
+	return &Provider{client: client}
+}
+
+func (p *Provider) Name() string {
+	return providerName
 }
 
 func getStripeMockURL() string {
@@ -29,154 +60,211 @@ func getStripeMockURL() string {
 	return url
 }
 
-func (p *Provider) CreatePayment(req interface{}) (interface{}, error) {
-	ctx := context.Background()
-	logger.Info(ctx, "[Stripe] CreatePayment called", nil)
-	request, ok := req.(*stripe.PaymentRequest)
-	if !ok {
-		err := errors.New("invalid request type for Stripe")
-		logger.Error(ctx, "[Stripe] Invalid request type", attributes.Attributes{"request": req}.WithError(err))
-		return nil, err
+// baseURL is the API of the merchant account the call is made on, or the
+// default one.
+func baseURL(ctx context.Context) string {
+	if account := payment.AccountFrom(ctx, providerName); account != nil && account.BaseURL != "" {
+		return account.BaseURL
 	}
 
+	return getStripeMockURL()
+}
+
+func (p *Provider) Charge(ctx context.Context, request payment.ChargeRequest) (payment.ChargeResult, error) {
+	logger.Info(ctx, "[Stripe] Charge called", attributes.Attributes{"payment_id": request.PaymentID})
+
+	paymentRequest := toPaymentRequest(request)
 	payload := map[string]interface{}{
-		"amount":              int64(request.Amount * 100),
-		"currency":            request.Currency,
-		"statementDescriptor": request.StatementDescriptor,
-		"paymentType":         request.PaymentType,
-		"description":         request.Description,
-		"card":                request.Card,
+		"amount":              paymentRequest.Amount.Amount,
+		"currency":            paymentRequest.Amount.Currency,
+		"statementDescriptor": paymentRequest.StatementDescriptor,
+		"paymentType":         paymentRequest.PaymentType,
+		"description":         paymentRequest.Description,
+		"card":                paymentRequest.Card,
+		"capture":             !request.AuthorizeOnly,
+		"reference":           request.PaymentID,
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
-		logger.Error(ctx, "[Stripe] Error marshaling payload", attributes.Attributes{"payload": payload}.WithError(err))
-		return nil, err
+		logger.Error(ctx, "[Stripe] Error marshaling payload", attributes.Attributes{"payment_id": request.PaymentID}.WithError(err))
+		return payment.ChargeResult{}, err
 	}
-	url := getStripeMockURL() + "/transactions"
-	logger.Info(ctx, "[Stripe] POST to mock", attributes.Attributes{"url": url})
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+
+	response, err := p.do(ctx, "charge", http.MethodPost, baseURL(ctx)+"/transactions", bytes.NewBuffer(body))
 	if err != nil {
-		logger.Error(ctx, "[Stripe] Error on POST", attributes.Attributes{"url": url}.WithError(err))
-		return nil, err
+		return payment.ChargeResult{}, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("Stripe mock returned status %d", resp.StatusCode)
-		logger.Error(ctx, "[Stripe] Mock returned error status", attributes.Attributes{"status": resp.StatusCode, "url": url}.WithError(err))
-		return nil, err
+
+	logger.Info(ctx, "[Stripe] Payment created successfully", attributes.Attributes{"payment_id": request.PaymentID, "provider_payment_id": response.ID})
+	return toChargeResult(response), nil
+}
+
+func (p *Provider) Refund(ctx context.Context, request payment.RefundRequest) (payment.ChargeResult, error) {
+	logger.Info(ctx, "[Stripe] Refund called", attributes.Attributes{"payment_id": request.PaymentID, "provider_payment_id": request.ProviderPaymentID})
+
+	payload := map[string]interface{}{
+		"amount": request.Amount.Amount,
+		"reason": request.Reason,
 	}
-	var mockResp struct {
-		ID                  string      `json:"id"`
-		Status              string      `json:"status"`
-		Amount              int64       `json:"amount"`
-		OriginalAmount      int64       `json:"originalAmount"`
-		Currency            string      `json:"currency"`
-		Description         string      `json:"description"`
-		PaymentType         string      `json:"paymentType"`
-		CreatedAt           string      `json:"date"`
-		StatementDescriptor string      `json:"statementDescriptor"`
-		Card                stripe.Card `json:"card"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&mockResp); err != nil {
-		logger.Error(ctx, "[Stripe] Error decoding response", attributes.Attributes{}.WithError(err))
-		return nil, err
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error(ctx, "[Stripe] Error marshaling refund payload", attributes.Attributes{"payment_id": request.PaymentID}.WithError(err))
+		return payment.ChargeResult{}, err
 	}
-	payment := outbound.StripePaymentResponse{
-		ID:          mockResp.ID,
-		Status:      mockResp.Status,
-		Amount:      float64(mockResp.Amount) / 100.0,
-		Description: mockResp.Description,
-		PaymentType: mockResp.PaymentType,
-		CreatedAt:   mockResp.CreatedAt,
-		Currency:    mockResp.Currency,
-		Card:        mockResp.Card,
-	}
-	logger.Info(ctx, "[Stripe] Payment created successfully", attributes.Attributes{"payment_id": payment.ID})
-	return &payment, nil
+
+	url := fmt.Sprintf("%s/void/%s", baseURL(ctx), request.ProviderPaymentID)
+	response, err := p.do(ctx, "refund", http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return payment.ChargeResult{}, err
+	}
+
+	logger.Info(ctx, "[Stripe] Refund processed successfully", attributes.Attributes{"payment_id": request.PaymentID, "provider_payment_id": response.ID})
+	return toChargeResult(response), nil
 }
 
-func (p *Provider) Refund(paymentID string) (interface{}, error) {
-	ctx := context.Background()
-	logger.Info(ctx, "[Stripe] Refund called", attributes.Attributes{"payment_id": paymentID})
-	url := fmt.Sprintf("%s/void/%s", getStripeMockURL(), paymentID)
-	logger.Info(ctx, "[Stripe] POST to mock for refund", attributes.Attributes{"url": url})
-	resp, err := http.Post(url, "application/json", nil)
+func (p *Provider) Capture(ctx context.Context, request payment.CaptureRequest) (payment.ChargeResult, error) {
+	logger.Info(ctx, "[Stripe] Capture called", attributes.Attributes{"payment_id": request.PaymentID, "provider_payment_id": request.ProviderPaymentID})
+
+	body, err := json.Marshal(map[string]interface{}{"amount": request.Amount.Amount})
 	if err != nil {
-		logger.Error(ctx, "[Stripe] Error on POST refund", attributes.Attributes{"url": url}.WithError(err))
-		return nil, err
+		logger.Error(ctx, "[Stripe] Error marshaling capture payload", attributes.Attributes{"payment_id": request.PaymentID}.WithError(err))
+		return payment.ChargeResult{}, err
+	}
+
+	url := fmt.Sprintf("%s/capture/%s", baseURL(ctx), request.ProviderPaymentID)
+	response, err := p.do(ctx, "capture", http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return payment.ChargeResult{}, err
+	}
+
+	logger.Info(ctx, "[Stripe] Capture processed successfully", attributes.Attributes{"payment_id": request.PaymentID, "provider_payment_id": response.ID})
+	return toChargeResult(response), nil
+}
+
+func (p *Provider) Cancel(ctx context.Context, request payment.CancelRequest) (payment.ChargeResult, error) {
+	logger.Info(ctx, "[Stripe] Cancel called", attributes.Attributes{"payment_id": request.PaymentID, "provider_payment_id": request.ProviderPaymentID, "reason": request.Reason})
+
+	body, err := json.Marshal(map[string]interface{}{"reason": request.Reason})
+	if err != nil {
+		logger.Error(ctx, "[Stripe] Error marshaling cancel payload", attributes.Attributes{"payment_id": request.PaymentID}.WithError(err))
+		return payment.ChargeResult{}, err
+	}
+
+	url := fmt.Sprintf("%s/cancel/%s", baseURL(ctx), request.ProviderPaymentID)
+	response, err := p.do(ctx, "cancel", http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return payment.ChargeResult{}, err
+	}
+
+	logger.Info(ctx, "[Stripe] Cancel processed successfully", attributes.Attributes{"payment_id": request.PaymentID, "provider_payment_id": response.ID})
+	return toChargeResult(response), nil
+}
+
+func (p *Provider) Get(ctx context.Context, providerPaymentID string) (payment.ChargeResult, error) {
+	url := fmt.Sprintf("%s/transactions/%s", baseURL(ctx), providerPaymentID)
+	response, err := p.do(ctx, "get", http.MethodGet, url, nil)
+	if err != nil {
+		return payment.ChargeResult{}, err
+	}
+
+	return toChargeResult(response), nil
+}
+
+// FindByReference looks up the charge created for the given payment ID, which
+// is sent as the reference of every charge.
+func (p *Provider) FindByReference(ctx context.Context, reference string) (payment.ChargeResult, error) {
+	url := fmt.Sprintf("%s/transactions?reference=%s", baseURL(ctx), neturl.QueryEscape(reference))
+	response, err := p.do(ctx, "find_by_reference", http.MethodGet, url, nil)
+	if err != nil {
+		return payment.ChargeResult{}, err
+	}
+
+	return toChargeResult(response), nil
+}
+
+// Ping checks the Stripe health endpoint.
+func (p *Provider) Ping(ctx context.Context) (err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveProviderCall(providerName, "ping", payment.Outcome(err), time.Since(start)) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL(ctx)+"/health", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return payment.NewProviderError(providerName, payment.ErrorNetwork, 0, err)
 	}
 	defer resp.Body.Close()
+
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("Stripe mock returned status %d", resp.StatusCode)
-		logger.Error(ctx, "[Stripe] Mock returned error status on refund", attributes.Attributes{"status": resp.StatusCode, "url": url}.WithError(err))
-		return nil, err
+		return payment.NewProviderError(providerName, payment.ErrorProvider, resp.StatusCode, errors.New("unhealthy"))
 	}
-	var mockResp struct {
-		ID                  string      `json:"id"`
-		Status              string      `json:"status"`
-		Amount              int64       `json:"amount"`
-		OriginalAmount      int64       `json:"originalAmount"`
-		Currency            string      `json:"currency"`
-		Description         string      `json:"description"`
-		PaymentType         string      `json:"paymentType"`
-		CreatedAt           string      `json:"date"`
-		StatementDescriptor string      `json:"statementDescriptor"`
-		Card                stripe.Card `json:"card"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&mockResp); err != nil {
-		logger.Error(ctx, "[Stripe] Error decoding refund response", attributes.Attributes{}.WithError(err))
-		return nil, err
-	}
-	payment := outbound.StripePaymentResponse{
-		ID:          mockResp.ID,
-		Status:      mockResp.Status,
-		Amount:      float64(mockResp.Amount) / 100.0,
-		Description: mockResp.Description,
-		PaymentType: mockResp.PaymentType,
-		CreatedAt:   mockResp.CreatedAt,
-		Currency:    mockResp.Currency,
-		Card:        mockResp.Card,
-	}
-	logger.Info(ctx, "[Stripe] Refund processed successfully", attributes.Attributes{"payment_id": payment.ID})
-	return &payment, nil
+
+	return nil
 }
 
-func (p *Provider) GetPayment(paymentID string) (interface{}, error) {
-	fmt.Println("[DEBUG] STRIPE_MOCK_URL:", getStripeMockURL())
-	url := fmt.Sprintf("%s/transactions/%s", getStripeMockURL(), paymentID)
-	fmt.Println("[DEBUG] GET URL:", url)
-	resp, err := http.Get(url)
+func (p *Provider) do(ctx context.Context, operation string, method string, url string, body io.Reader) (_ *transactionResponse, err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, providerName+" "+operation, trace.SpanKindClient,
+		attribute.String("provider", providerName),
+		attribute.String("http.request.method", method),
+	)
+	defer func() {
+		metrics.ObserveProviderCall(providerName, operation, payment.Outcome(err), time.Since(start))
+		tracing.End(span, err)
+	}()
+
+	logger.Debug(ctx, fmt.Sprintf("[Stripe] %s to mock", method), attributes.Attributes{"url": url})
+
+	// Once the request is on the wire the provider may act on it, so any
+	// later failure leaves the outcome unknown rather than failed.
+	var written atomic.Bool
+	clientTrace := &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			written.Store(info.Err == nil)
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, clientTrace), method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	if account := payment.AccountFrom(ctx, providerName); account != nil && account.Credentials.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+account.Credentials.APIKey)
+	}
+	tracing.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	correlation.Inject(ctx, req.Header)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		logger.Error(ctx, fmt.Sprintf("[Stripe] Error on %s", method), attributes.Attributes{"url": url}.WithError(err))
+		if written.Load() {
+			return nil, payment.NewProviderError(providerName, payment.ErrorTimeout, 0, err)
+		}
+		return nil, payment.NewProviderError(providerName, payment.ErrorNetwork, 0, err)
+	}
 	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		logger.Warn(ctx, "[Stripe] Mock returned not found", attributes.Attributes{"url": url})
+		return nil, payment.ErrChargeNotFound
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Stripe mock returned status %d", resp.StatusCode)
-	}
-	var mockResp struct {
-		ID                  string      `json:"id"`
-		Status              string      `json:"status"`
-		Amount              int64       `json:"amount"`
-		OriginalAmount      int64       `json:"originalAmount"`
-		Currency            string      `json:"currency"`
-		Description         string      `json:"description"`
-		PaymentType         string      `json:"paymentType"`
-		CreatedAt           string      `json:"date"`
-		StatementDescriptor string      `json:"statementDescriptor"`
-		Card                stripe.Card `json:"card"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&mockResp); err != nil {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := payment.NewProviderError(providerName, payment.ErrorKindForStatus(resp.StatusCode), resp.StatusCode, errors.New(strings.TrimSpace(string(message))))
+		logger.Error(ctx, "[Stripe] Mock returned error status", attributes.Attributes{"status": resp.StatusCode, "url": url}.WithError(err))
 		return nil, err
 	}
-	payment := outbound.StripePaymentResponse{
-		ID:          mockResp.ID,
-		Status:      mockResp.Status,
-		Amount:      float64(mockResp.Amount) / 100.0,
-		Description: mockResp.Description,
-		PaymentType: mockResp.PaymentType,
-		CreatedAt:   mockResp.CreatedAt,
-		Currency:    mockResp.Currency,
-		Card:        mockResp.Card,
-	}
-	return &payment, nil
+
+	var response transactionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		logger.Error(ctx, "[Stripe] Error decoding response", attributes.Attributes{"url": url}.WithError(err))
+		return nil, payment.NewProviderError(providerName, payment.ErrorTimeout, resp.StatusCode, err)
+	}
+
+	return &response, nil
 }
@@ -0,0 +1,54 @@
+package stripeProvider
+
+import (
+	"encoding/json"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/webhook"
+	"net/http"
+	"time"
+)
+
+const (
+	signatureHeader = "Stripe-Signature"
+	// signatureTolerance rejects replays of old webhooks.
+	signatureTolerance = 5 * time.Minute
+)
+
+type webhookEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object transactionResponse `json:"object"`
+	} `json:"data"`
+}
+
+// ParseWebhook verifies the Stripe-Signature header, which signs the
+// timestamp and body the same way as the webhooks sent to merchants, and
+// decodes the transaction the event carries.
+func (p *Provider) ParseWebhook(header http.Header, body []byte) (payment.ProviderEvent, error) {
+	if p.webhookSecret == "" {
+		return payment.ProviderEvent{}, payment.ErrWebhookNotConfigured
+	}
+
+	if err := webhook.Verify(p.webhookSecret, header.Get(signatureHeader), body, signatureTolerance, time.Now()); err != nil {
+		return payment.ProviderEvent{}, payment.ErrInvalidWebhookSignature.Wrap(err)
+	}
+
+	var event webhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return payment.ProviderEvent{}, payment.ErrMalformedWebhook.Wrap(err)
+	}
+
+	if event.ID == "" || event.Data.Object.ID == "" || event.Data.Object.Reference == "" {
+		return payment.ProviderEvent{}, payment.ErrMalformedWebhook
+	}
+
+	return payment.ProviderEvent{
+		ID:        event.ID,
+		Provider:  providerName,
+		Type:      event.Type,
+		PaymentID: event.Data.Object.Reference,
+		Result:    toChargeResult(&event.Data.Object),
+	}, nil
+}
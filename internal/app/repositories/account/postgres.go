@@ -0,0 +1,102 @@
+package accountRepository
+
+import (
+	"context"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/app/domain/account"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/infra/database"
+)
+
+const (
+	upsertAccount = `INSERT INTO provider_accounts (merchant_id, livemode, provider, base_url, enabled, priority, statement_descriptor, key_id, credentials, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (merchant_id, livemode, provider) DO UPDATE SET
+			base_url = EXCLUDED.base_url,
+			enabled = EXCLUDED.enabled,
+			priority = EXCLUDED.priority,
+			statement_descriptor = EXCLUDED.statement_descriptor,
+			key_id = EXCLUDED.key_id,
+			credentials = EXCLUDED.credentials,
+			updated_at = EXCLUDED.updated_at`
+	selectAccounts = `SELECT merchant_id, livemode, provider, base_url, enabled, priority, statement_descriptor, key_id, credentials, created_at, updated_at
+		FROM provider_accounts WHERE merchant_id = $1 AND livemode = $2 ORDER BY priority, provider`
+	deleteAccount = `DELETE FROM provider_accounts WHERE merchant_id = $1 AND livemode = $2 AND provider = $3`
+)
+
+type Repository struct {
+	read  *database.Database
+	write *database.Database
+}
+
+func New(databases *database.Databases) *Repository {
+	return &Repository{
+		read:  databases.Read,
+		write: databases.Write,
+	}
+}
+
+func (r *Repository) Save(ctx context.Context, stored *account.StoredAccount) error {
+	updatedAt, err := parseTime(stored.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.write.Connection().ExecContext(ctx, upsertAccount,
+		stored.MerchantID, stored.Livemode, stored.Provider, stored.BaseURL, stored.Enabled, stored.Priority, stored.StatementDescriptor,
+		stored.KeyID, stored.Ciphertext, updatedAt)
+	return err
+}
+
+// Find reads from the write database: the result is cached, and caching a
+// replica lagging behind a change would keep it stale until the entry
+// expires.
+func (r *Repository) Find(ctx context.Context, scope merchant.Scope) ([]account.StoredAccount, error) {
+	rows, err := r.write.Connection().QueryContext(ctx, selectAccounts, scope.MerchantID, scope.Livemode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]account.StoredAccount, 0)
+	for rows.Next() {
+		var (
+			stored               account.StoredAccount
+			createdAt, updatedAt time.Time
+		)
+
+		err = rows.Scan(&stored.MerchantID, &stored.Livemode, &stored.Provider, &stored.BaseURL, &stored.Enabled, &stored.Priority,
+			&stored.StatementDescriptor, &stored.KeyID, &stored.Ciphertext, &createdAt, &updatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		stored.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		stored.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+		accounts = append(accounts, stored)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (r *Repository) Delete(ctx context.Context, scope merchant.Scope, provider string) error {
+	result, err := r.write.Connection().ExecContext(ctx, deleteAccount, scope.MerchantID, scope.Livemode, provider)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return account.ErrNotFound
+	}
+
+	return nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
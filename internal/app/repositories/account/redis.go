@@ -0,0 +1,72 @@
+package accountRepository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/app/domain/account"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/infra/database"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const cacheKeyPrefix = "provider_accounts:"
+
+// Cache keeps the accounts of a scope in Redis as stored, credentials
+// encrypted.
+type Cache struct {
+	redis *database.Redis
+}
+
+func NewCache(redis *database.Redis) *Cache {
+	return &Cache{redis: redis}
+}
+
+func (c *Cache) Get(ctx context.Context, scope merchant.Scope) ([]account.StoredAccount, bool, error) {
+	rdb, err := c.redis.TryConnection()
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err := rdb.Get(ctx, cacheKeyPrefix+scope.String()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	var accounts []account.StoredAccount
+	if err = json.Unmarshal(value, &accounts); err != nil {
+		return nil, false, err
+	}
+
+	return accounts, true, nil
+}
+
+func (c *Cache) Set(ctx context.Context, scope merchant.Scope, accounts []account.StoredAccount, ttl time.Duration) error {
+	rdb, err := c.redis.TryConnection()
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(accounts)
+	if err != nil {
+		return err
+	}
+
+	return rdb.Set(ctx, cacheKeyPrefix+scope.String(), value, ttl).Err()
+}
+
+func (c *Cache) Invalidate(ctx context.Context, scope merchant.Scope) error {
+	rdb, err := c.redis.TryConnection()
+	if err != nil {
+		return err
+	}
+
+	return rdb.Del(ctx, cacheKeyPrefix+scope.String()).Err()
+}
@@ -0,0 +1,181 @@
+package merchantRepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/infra/database"
+)
+
+const (
+	insertMerchant = `INSERT INTO merchants (id, name, created_at) VALUES ($1, $2, $3)`
+	selectMerchant = `SELECT id, name, created_at FROM merchants WHERE id = $1`
+	insertKey      = `INSERT INTO merchant_api_keys (id, merchant_id, type, livemode, key_hash, last4, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	selectKey = `SELECT id, merchant_id, type, livemode, key_hash, last4, created_at, revoked_at
+		FROM merchant_api_keys`
+	revokeKey = `UPDATE merchant_api_keys SET revoked_at = now()
+		WHERE id = $1 AND merchant_id = $2 AND revoked_at IS NULL`
+)
+
+// execer is a connection or a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type Repository struct {
+	read  *database.Database
+	write *database.Database
+}
+
+func New(databases *database.Databases) *Repository {
+	return &Repository{
+		read:  databases.Read,
+		write: databases.Write,
+	}
+}
+
+// Create stores the merchant together with its keys.
+func (r *Repository) Create(ctx context.Context, m *merchant.Merchant) error {
+	createdAt, err := parseTime(m.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, insertMerchant, m.ID, m.Name, createdAt); err != nil {
+			return err
+		}
+
+		for i := range m.Keys {
+			if err := createKey(ctx, tx, &m.Keys[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) FindByID(ctx context.Context, id string) (*merchant.Merchant, error) {
+	var (
+		m         merchant.Merchant
+		createdAt time.Time
+	)
+
+	err := r.read.Connection().QueryRowContext(ctx, selectMerchant, id).Scan(&m.ID, &m.Name, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, merchant.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	m.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return &m, nil
+}
+
+func (r *Repository) CreateKey(ctx context.Context, key *merchant.APIKey) error {
+	return createKey(ctx, r.write.Connection(), key)
+}
+
+// FindKeyByHash reads from the write database: a key issued a moment ago
+// must authenticate right away, whatever the replication lag.
+func (r *Repository) FindKeyByHash(ctx context.Context, hash string) (*merchant.APIKey, error) {
+	row := r.write.Connection().QueryRowContext(ctx, selectKey+" WHERE key_hash = $1", hash)
+
+	key, err := scanKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, merchant.ErrKeyNotFound
+	}
+
+	return key, err
+}
+
+func (r *Repository) FindKeys(ctx context.Context, merchantID string) ([]merchant.APIKey, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectKey+" WHERE merchant_id = $1 ORDER BY created_at, id", merchantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]merchant.APIKey, 0)
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *Repository) RevokeKey(ctx context.Context, merchantID string, keyID string) error {
+	result, err := r.write.Connection().ExecContext(ctx, revokeKey, keyID, merchantID)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return merchant.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.write.Connection().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func createKey(ctx context.Context, db execer, key *merchant.APIKey) error {
+	createdAt, err := parseTime(key.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, insertKey, key.ID, key.MerchantID, key.Type, key.Livemode, key.Hash, key.Last4, createdAt)
+	return err
+}
+
+func scanKey(row interface{ Scan(dest ...any) error }) (*merchant.APIKey, error) {
+	var (
+		key       merchant.APIKey
+		createdAt time.Time
+		revokedAt sql.NullTime
+	)
+
+	if err := row.Scan(&key.ID, &key.MerchantID, &key.Type, &key.Livemode, &key.Hash, &key.Last4, &createdAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	key.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	if revokedAt.Valid {
+		key.RevokedAt = revokedAt.Time.UTC().Format(time.RFC3339)
+	}
+
+	return &key, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,459 @@
+package paymentRepository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/infra/database"
+)
+
+const (
+	insertPayment = `INSERT INTO payments (id, amount, currency, description, status, payment_type, card_token, provider, provider_payment_id, merchant_id, livemode, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)`
+	lockPayment   = `SELECT status FROM payments WHERE id = $1 FOR UPDATE`
+	updatePayment = `UPDATE payments
+		SET status = $2, provider = $3, provider_payment_id = $4, captured_amount = $5, refunded_amount = GREATEST(refunded_amount, $6), updated_at = now()
+		WHERE id = $1`
+	selectPayment = `SELECT id, amount, captured_amount, refunded_amount, currency, description, status, payment_type, card_token, provider, provider_payment_id, merchant_id, livemode, created_at
+		FROM payments`
+	insertStatusHistory = `INSERT INTO payment_status_history (payment_id, previous_status, status) VALUES ($1, $2, $3)`
+	selectStatusHistory = `SELECT payment_id, previous_status, status, created_at
+		FROM payment_status_history WHERE payment_id = $1 ORDER BY created_at, id`
+	insertAttempt = `INSERT INTO payment_attempts (id, payment_id, provider, provider_payment_id, status, error, error_kind, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	selectAttempts = `SELECT id, payment_id, provider, provider_payment_id, status, error, error_kind, created_at
+		FROM payment_attempts WHERE payment_id = $1 ORDER BY created_at, id`
+	insertRefund = `INSERT INTO payment_refunds (id, payment_id, amount, reason, status, error, error_kind, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	lockRefundBalance    = `SELECT captured_amount, refunded_amount, currency FROM payments WHERE id = $1 FOR UPDATE`
+	selectPendingRefunds = `SELECT COALESCE(SUM(amount), 0) FROM payment_refunds WHERE payment_id = $1 AND status = $2`
+	updateRefund         = `UPDATE payment_refunds SET status = $2, error = $3, error_kind = $4 WHERE id = $1`
+	addRefundedAmount    = `UPDATE payments SET refunded_amount = refunded_amount + $2, updated_at = now()
+		WHERE id = $1 RETURNING refunded_amount, currency`
+	selectRefunds = `SELECT r.id, r.payment_id, r.amount, p.currency, r.reason, r.status, r.error, r.error_kind, r.created_at
+		FROM payment_refunds r JOIN payments p ON p.id = r.payment_id
+		WHERE r.payment_id = $1 ORDER BY r.created_at, r.id`
+	insertEvent = `INSERT INTO payment_events (id, payment_id, type, merchant_id, payload, card_number, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	claimEvents = `UPDATE payment_events SET claimed_until = $2
+		WHERE id IN (
+			SELECT id FROM payment_events
+			WHERE published_at IS NULL AND claimed_until <= $1
+			ORDER BY created_at LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, type, merchant_id, payload, card_number, created_at`
+	markEventPublished  = `UPDATE payment_events SET published_at = now() WHERE id = $1`
+	selectProviderEvent = `SELECT EXISTS (SELECT 1 FROM provider_events WHERE provider = $1 AND event_id = $2)`
+	insertProviderEvent = `INSERT INTO provider_events (provider, event_id, type, payment_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, event_id) DO NOTHING`
+)
+
+type Repository struct {
+	read  *database.Database
+	write *database.Database
+}
+
+func New(databases *database.Databases) *Repository {
+	return &Repository{
+		read:  databases.Read,
+		write: databases.Write,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, p *payment.Payment) error {
+	createdAt, err := parseTime(p.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, insertPayment, p.ID, p.Amount.String(), p.Amount.Currency, p.Description, p.Status, p.Method.Type, p.Method.Token, p.Provider, p.ProviderPaymentID, p.MerchantID, p.Livemode, createdAt); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, insertStatusHistory, p.ID, "", p.Status)
+		return err
+	})
+}
+
+// Update stores the mutable fields of the payment and appends a status
+// history entry whenever the status differs from the stored one. The stored
+// status is locked first, so concurrent updates cannot move the payment
+// backwards.
+func (r *Repository) Update(ctx context.Context, p *payment.Payment) error {
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		var previous payment.Status
+		err := tx.QueryRowContext(ctx, lockPayment, p.ID).Scan(&previous)
+		if errors.Is(err, sql.ErrNoRows) {
+			return payment.ErrNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !previous.CanTransitionTo(p.Status) {
+			return fmt.Errorf("%w: %s to %s", payment.ErrInvalidTransition, previous, p.Status)
+		}
+
+		if _, err = tx.ExecContext(ctx, updatePayment, p.ID, p.Status, p.Provider, p.ProviderPaymentID, p.CapturedAmount.String(), p.RefundedAmount.String()); err != nil {
+			return err
+		}
+
+		if previous != p.Status {
+			if _, err = tx.ExecContext(ctx, insertStatusHistory, p.ID, previous, p.Status); err != nil {
+				return err
+			}
+		}
+
+		event, ok := payment.NewEvent(p)
+		if !ok {
+			return nil
+		}
+
+		return insertPaymentEvent(ctx, tx, event)
+	})
+}
+
+// insertPaymentEvent stores the event with the payment encoded as its
+// payload. Card numbers are never encoded, so the masked one is kept aside.
+func insertPaymentEvent(ctx context.Context, tx *sql.Tx, event *payment.Event) error {
+	createdAt, err := parseTime(event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event.Payment)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertEvent, event.ID, event.Payment.ID, event.Type, event.Payment.MerchantID, payload, event.Payment.Method.Card.Number, createdAt)
+	return err
+}
+
+func (r *Repository) ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]payment.Event, error) {
+	rows, err := r.write.Connection().QueryContext(ctx, claimEvents, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]payment.Event, 0)
+	for rows.Next() {
+		var (
+			event                  payment.Event
+			p                      payment.Payment
+			merchantID, cardNumber string
+			payload                []byte
+			createdAt              time.Time
+		)
+
+		if err = rows.Scan(&event.ID, &event.Type, &merchantID, &payload, &cardNumber, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(payload, &p); err != nil {
+			return nil, err
+		}
+
+		p.MerchantID = merchantID
+		p.Method.Card.Number = cardNumber
+		event.Payment = &p
+		event.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt < events[j].CreatedAt })
+	return events, nil
+}
+
+func (r *Repository) MarkEventPublished(ctx context.Context, id string) error {
+	_, err := r.write.Connection().ExecContext(ctx, markEventPublished, id)
+	return err
+}
+
+func (r *Repository) FindByID(ctx context.Context, id string) (*payment.Payment, error) {
+	row := r.read.Connection().QueryRowContext(ctx, selectPayment+" WHERE id = $1", id)
+	return scanPayment(row)
+}
+
+func (r *Repository) FindByStatus(ctx context.Context, status payment.Status, createdBefore time.Time, limit int) ([]*payment.Payment, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectPayment+" WHERE status = $1 AND created_at <= $2 ORDER BY created_at LIMIT $3", status, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]*payment.Payment, 0)
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+func (r *Repository) FindStatusHistory(ctx context.Context, paymentID string) ([]payment.StatusChange, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectStatusHistory, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]payment.StatusChange, 0)
+	for rows.Next() {
+		var (
+			change    payment.StatusChange
+			createdAt time.Time
+		)
+
+		if err = rows.Scan(&change.PaymentID, &change.From, &change.To, &createdAt); err != nil {
+			return nil, err
+		}
+
+		change.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+func (r *Repository) AddAttempt(ctx context.Context, attempt *payment.Attempt) error {
+	createdAt, err := parseTime(attempt.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.write.Connection().ExecContext(ctx, insertAttempt, attempt.ID, attempt.PaymentID, attempt.Provider, attempt.ProviderPaymentID, attempt.Status, attempt.Error, attempt.ErrorKind, createdAt)
+	return err
+}
+
+func (r *Repository) FindAttempts(ctx context.Context, paymentID string) ([]payment.Attempt, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectAttempts, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]payment.Attempt, 0)
+	for rows.Next() {
+		var (
+			attempt   payment.Attempt
+			createdAt time.Time
+		)
+
+		if err = rows.Scan(&attempt.ID, &attempt.PaymentID, &attempt.Provider, &attempt.ProviderPaymentID, &attempt.Status, &attempt.Error, &attempt.ErrorKind, &createdAt); err != nil {
+			return nil, err
+		}
+
+		attempt.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// ReserveRefund locks the payment while it checks the balance left by the
+// succeeded and pending refunds and records the refund, so a concurrent
+// reservation waits for this one and sees its amount.
+func (r *Repository) ReserveRefund(ctx context.Context, refund *payment.Refund) error {
+	createdAt, err := parseTime(refund.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		var captured, refunded, pending, currency string
+		err := tx.QueryRowContext(ctx, lockRefundBalance, refund.PaymentID).Scan(&captured, &refunded, &currency)
+		if errors.Is(err, sql.ErrNoRows) {
+			return payment.ErrNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = tx.QueryRowContext(ctx, selectPendingRefunds, refund.PaymentID, payment.RefundPending).Scan(&pending); err != nil {
+			return err
+		}
+
+		balance, err := refundBalance(currency, captured, refunded, pending)
+		if err != nil {
+			return err
+		}
+
+		if refund.Amount.Amount > balance {
+			return payment.ErrRefundExceedsBalance
+		}
+
+		_, err = tx.ExecContext(ctx, insertRefund, refund.ID, refund.PaymentID, refund.Amount.String(), refund.Reason, refund.Status, refund.Error, refund.ErrorKind, createdAt)
+		return err
+	})
+}
+
+func (r *Repository) SettleRefund(ctx context.Context, refund *payment.Refund) (money.Money, error) {
+	var refunded money.Money
+	err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, updateRefund, refund.ID, refund.Status, refund.Error, refund.ErrorKind); err != nil {
+			return err
+		}
+
+		var amount, currency string
+		err := tx.QueryRowContext(ctx, addRefundedAmount, refund.PaymentID, settledAmount(refund).String()).Scan(&amount, &currency)
+		if errors.Is(err, sql.ErrNoRows) {
+			return payment.ErrNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		refunded, err = money.Parse(amount, currency)
+		return err
+	})
+
+	return refunded, err
+}
+
+func (r *Repository) FindRefunds(ctx context.Context, paymentID string) ([]payment.Refund, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectRefunds, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := make([]payment.Refund, 0)
+	for rows.Next() {
+		var (
+			refund           payment.Refund
+			amount, currency string
+			createdAt        time.Time
+		)
+
+		if err = rows.Scan(&refund.ID, &refund.PaymentID, &amount, &currency, &refund.Reason, &refund.Status, &refund.Error, &refund.ErrorKind, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if refund.Amount, err = money.Parse(amount, currency); err != nil {
+			return nil, err
+		}
+
+		refund.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+func (r *Repository) ProviderEventSeen(ctx context.Context, provider string, eventID string) (bool, error) {
+	var seen bool
+	err := r.write.Connection().QueryRowContext(ctx, selectProviderEvent, provider, eventID).Scan(&seen)
+	return seen, err
+}
+
+func (r *Repository) AddProviderEvent(ctx context.Context, event *payment.ProviderEvent) error {
+	_, err := r.write.Connection().ExecContext(ctx, insertProviderEvent, event.Provider, event.ID, event.Type, event.PaymentID)
+	return err
+}
+
+func (r *Repository) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.write.Connection().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanPayment(row interface{ Scan(dest ...any) error }) (*payment.Payment, error) {
+	var (
+		p                        payment.Payment
+		amount, captured, refund string
+		currency                 string
+		createdAt                time.Time
+	)
+
+	err := row.Scan(&p.ID, &amount, &captured, &refund, &currency, &p.Description, &p.Status, &p.Method.Type, &p.Method.Token, &p.Provider, &p.ProviderPaymentID, &p.MerchantID, &p.Livemode, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, payment.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Amount, err = money.Parse(amount, currency); err != nil {
+		return nil, err
+	}
+
+	if p.CapturedAmount, err = money.Parse(captured, currency); err != nil {
+		return nil, err
+	}
+
+	if p.RefundedAmount, err = money.Parse(refund, currency); err != nil {
+		return nil, err
+	}
+
+	p.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return &p, nil
+}
+
+// refundBalance is the captured amount minus the refunded and pending ones,
+// in minor units.
+func refundBalance(currency string, captured string, amounts ...string) (int64, error) {
+	balance, err := money.Parse(captured, currency)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, amount := range amounts {
+		parsed, err := money.Parse(amount, currency)
+		if err != nil {
+			return 0, err
+		}
+
+		balance.Amount -= parsed.Amount
+	}
+
+	return balance.Amount, nil
+}
+
+// settledAmount is what a settled refund adds to the refunded amount: its
+// amount when it succeeded, nothing otherwise.
+func settledAmount(refund *payment.Refund) money.Money {
+	if refund.Status != payment.RefundSucceeded {
+		return money.New(0, refund.Amount.Currency)
+	}
+
+	return refund.Amount
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,74 @@
+package vaultRepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	"lucassaraiva5/api-pay/internal/infra/database"
+)
+
+const (
+	insertCard = `INSERT INTO card_tokens (id, fingerprint, last4, brand, holder, expiration, key_id, ciphertext, merchant_id, livemode, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)`
+	selectCard = `SELECT id, fingerprint, last4, brand, holder, expiration, key_id, ciphertext, merchant_id, livemode, created_at
+		FROM card_tokens WHERE id = $1`
+	updateCardEncryption = `UPDATE card_tokens SET key_id = $2, ciphertext = $3, updated_at = now() WHERE id = $1`
+)
+
+type Repository struct {
+	read  *database.Database
+	write *database.Database
+}
+
+func New(databases *database.Databases) *Repository {
+	return &Repository{
+		read:  databases.Read,
+		write: databases.Write,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, card *vault.StoredCard) error {
+	createdAt, err := time.Parse(time.RFC3339, card.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.write.Connection().ExecContext(ctx, insertCard, card.ID, card.Fingerprint, card.Last4, card.Brand, card.Holder, card.Expiration, card.KeyID, card.Ciphertext, card.MerchantID, card.Livemode, createdAt)
+	return err
+}
+
+func (r *Repository) FindByID(ctx context.Context, id string) (*vault.StoredCard, error) {
+	var (
+		card      vault.StoredCard
+		createdAt time.Time
+	)
+
+	err := r.read.Connection().QueryRowContext(ctx, selectCard, id).
+		Scan(&card.ID, &card.Fingerprint, &card.Last4, &card.Brand, &card.Holder, &card.Expiration, &card.KeyID, &card.Ciphertext, &card.MerchantID, &card.Livemode, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, vault.ErrTokenNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	card.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return &card, nil
+}
+
+func (r *Repository) UpdateEncryption(ctx context.Context, card *vault.StoredCard) error {
+	result, err := r.write.Connection().ExecContext(ctx, updateCardEncryption, card.ID, card.KeyID, card.Ciphertext)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return vault.ErrTokenNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package vaultRepository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/infra/database"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const cvvKeyPrefix = "vault:cvv:"
+
+// CVVStore keeps security codes in Redis with an expiry, never on disk.
+type CVVStore struct {
+	redis *database.Redis
+}
+
+func NewCVVStore(redis *database.Redis) *CVVStore {
+	return &CVVStore{redis: redis}
+}
+
+func (s *CVVStore) Save(ctx context.Context, tokenID string, cvv string, ttl time.Duration) error {
+	rdb, err := s.redis.TryConnection()
+	if err != nil {
+		return err
+	}
+
+	return rdb.Set(ctx, cvvKeyPrefix+tokenID, cvv, ttl).Err()
+}
+
+// Take reads and deletes the code in one step, so it is used at most once.
+func (s *CVVStore) Take(ctx context.Context, tokenID string) (string, error) {
+	rdb, err := s.redis.TryConnection()
+	if err != nil {
+		return "", err
+	}
+
+	cvv, err := rdb.GetDel(ctx, cvvKeyPrefix+tokenID).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+
+	return cvv, err
+}
@@ -0,0 +1,328 @@
+package webhookRepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/webhook"
+	"lucassaraiva5/api-pay/internal/infra/database"
+
+	"github.com/lib/pq"
+)
+
+const (
+	insertEndpoint = `INSERT INTO webhook_endpoints (id, url, secret, events, merchant_id, livemode, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	selectEndpoint = `SELECT id, url, secret, events, merchant_id, livemode, created_at
+		FROM webhook_endpoints`
+	deleteEndpoint = `UPDATE webhook_endpoints SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
+	// Deliveries left for a deleted endpoint would be retried for nothing.
+	failEndpointDeliveries = `UPDATE webhook_deliveries
+		SET status = 'failed', next_attempt_at = NULL, last_error = 'endpoint deleted', updated_at = now()
+		WHERE endpoint_id = $1 AND status = 'pending'`
+	insertEvent = `INSERT INTO webhook_events (id, type, payment_id, payload, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO NOTHING`
+	insertDelivery = `INSERT INTO webhook_deliveries (id, event_id, endpoint_id, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)`
+	claimDeliveries = `UPDATE webhook_deliveries d SET next_attempt_at = $2, updated_at = now()
+		FROM webhook_events e, webhook_endpoints p
+		WHERE d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) AND e.id = d.event_id AND p.id = d.endpoint_id
+		RETURNING d.id, d.event_id, d.endpoint_id, d.status, d.attempts, d.last_error, d.created_at,
+			e.type, e.payment_id, e.payload, e.created_at, p.url, p.secret`
+	updateDelivery = `UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, updated_at = now()
+		WHERE id = $1`
+	selectDelivery = `SELECT id, event_id, endpoint_id, status, attempts, next_attempt_at, last_error, created_at
+		FROM webhook_deliveries`
+	insertAttempt = `INSERT INTO webhook_delivery_attempts (id, delivery_id, response_status, error, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	selectAttempts = `SELECT id, delivery_id, response_status, error, duration_ms, created_at
+		FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY created_at, id`
+)
+
+type Repository struct {
+	read  *database.Database
+	write *database.Database
+}
+
+func New(databases *database.Databases) *Repository {
+	return &Repository{
+		read:  databases.Read,
+		write: databases.Write,
+	}
+}
+
+func (r *Repository) CreateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	createdAt, err := parseTime(endpoint.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.write.Connection().ExecContext(ctx, insertEndpoint, endpoint.ID, endpoint.URL, endpoint.Secret, pq.Array(endpoint.Events), endpoint.MerchantID, endpoint.Livemode, createdAt)
+	return err
+}
+
+func (r *Repository) FindEndpoint(ctx context.Context, id string) (*webhook.Endpoint, error) {
+	row := r.read.Connection().QueryRowContext(ctx, selectEndpoint+" WHERE id = $1", id)
+
+	endpoint, err := scanEndpoint(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, webhook.ErrEndpointNotFound
+	}
+
+	return endpoint, err
+}
+
+func (r *Repository) FindEndpoints(ctx context.Context, scope merchant.Scope) ([]webhook.Endpoint, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectEndpoint+" WHERE deleted_at IS NULL AND merchant_id = $1 AND livemode = $2 ORDER BY created_at, id", scope.MerchantID, scope.Livemode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := make([]webhook.Endpoint, 0)
+	for rows.Next() {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoints = append(endpoints, *endpoint)
+	}
+
+	return endpoints, rows.Err()
+}
+
+// DeleteEndpoint stops deliveries to the endpoint. The endpoint row is kept
+// for the delivery log.
+func (r *Repository) DeleteEndpoint(ctx context.Context, id string) error {
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, deleteEndpoint, id)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return webhook.ErrEndpointNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, failEndpointDeliveries, id)
+		return err
+	})
+}
+
+func (r *Repository) CreateEvent(ctx context.Context, event *webhook.Event, deliveries []webhook.Delivery) error {
+	createdAt, err := parseTime(event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return r.inTransaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, insertEvent, event.ID, event.Type, event.PaymentID, []byte(event.Data), createdAt)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return nil
+		}
+
+		for _, delivery := range deliveries {
+			nextAttemptAt, err := parseTime(delivery.NextAttemptAt)
+			if err != nil {
+				return err
+			}
+
+			if _, err = tx.ExecContext(ctx, insertDelivery, delivery.ID, delivery.EventID, delivery.EndpointID, delivery.Status, delivery.Attempts, nextAttemptAt, createdAt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Job, error) {
+	leasedUntil := now.Add(lease)
+
+	rows, err := r.write.Connection().QueryContext(ctx, claimDeliveries, now, leasedUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]webhook.Job, 0)
+	for rows.Next() {
+		var (
+			job                       webhook.Job
+			payload                   []byte
+			createdAt, eventCreatedAt time.Time
+		)
+
+		err = rows.Scan(&job.Delivery.ID, &job.Delivery.EventID, &job.Delivery.EndpointID, &job.Delivery.Status, &job.Delivery.Attempts, &job.Delivery.LastError, &createdAt,
+			&job.Event.Type, &job.Event.PaymentID, &payload, &eventCreatedAt, &job.URL, &job.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		job.Delivery.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		job.Delivery.NextAttemptAt = leasedUntil.UTC().Format(time.RFC3339)
+		job.Event.ID = job.Delivery.EventID
+		job.Event.Data = payload
+		job.Event.CreatedAt = eventCreatedAt.UTC().Format(time.RFC3339)
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (r *Repository) UpdateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	var nextAttemptAt *time.Time
+	if delivery.NextAttemptAt != "" {
+		next, err := parseTime(delivery.NextAttemptAt)
+		if err != nil {
+			return err
+		}
+
+		nextAttemptAt = &next
+	}
+
+	result, err := r.write.Connection().ExecContext(ctx, updateDelivery, delivery.ID, delivery.Status, delivery.Attempts, nextAttemptAt, delivery.LastError)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return webhook.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) FindDelivery(ctx context.Context, id string) (*webhook.Delivery, error) {
+	row := r.read.Connection().QueryRowContext(ctx, selectDelivery+" WHERE id = $1", id)
+	return scanDelivery(row)
+}
+
+// FindDeliveries returns the latest deliveries of an endpoint, newest first.
+func (r *Repository) FindDeliveries(ctx context.Context, endpointID string, limit int) ([]webhook.Delivery, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectDelivery+" WHERE endpoint_id = $1 ORDER BY created_at DESC, id LIMIT $2", endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]webhook.Delivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, *delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *Repository) AddAttempt(ctx context.Context, attempt *webhook.Attempt) error {
+	createdAt, err := parseTime(attempt.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.write.Connection().ExecContext(ctx, insertAttempt, attempt.ID, attempt.DeliveryID, attempt.ResponseStatus, attempt.Error, attempt.DurationMs, createdAt)
+	return err
+}
+
+func (r *Repository) FindAttempts(ctx context.Context, deliveryID string) ([]webhook.Attempt, error) {
+	rows, err := r.read.Connection().QueryContext(ctx, selectAttempts, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]webhook.Attempt, 0)
+	for rows.Next() {
+		var (
+			attempt   webhook.Attempt
+			createdAt time.Time
+		)
+
+		if err = rows.Scan(&attempt.ID, &attempt.DeliveryID, &attempt.ResponseStatus, &attempt.Error, &attempt.DurationMs, &createdAt); err != nil {
+			return nil, err
+		}
+
+		attempt.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+func (r *Repository) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.write.Connection().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanEndpoint(row interface{ Scan(dest ...any) error }) (*webhook.Endpoint, error) {
+	var (
+		endpoint  webhook.Endpoint
+		createdAt time.Time
+	)
+
+	if err := row.Scan(&endpoint.ID, &endpoint.URL, &endpoint.Secret, pq.Array(&endpoint.Events), &endpoint.MerchantID, &endpoint.Livemode, &createdAt); err != nil {
+		return nil, err
+	}
+
+	endpoint.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return &endpoint, nil
+}
+
+func scanDelivery(row interface{ Scan(dest ...any) error }) (*webhook.Delivery, error) {
+	var (
+		delivery      webhook.Delivery
+		nextAttemptAt sql.NullTime
+		createdAt     time.Time
+	)
+
+	err := row.Scan(&delivery.ID, &delivery.EventID, &delivery.EndpointID, &delivery.Status, &delivery.Attempts, &nextAttemptAt, &delivery.LastError, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, webhook.ErrDeliveryNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = nextAttemptAt.Time.UTC().Format(time.RFC3339)
+	}
+
+	delivery.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return &delivery, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
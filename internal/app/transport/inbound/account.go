@@ -0,0 +1,19 @@
+package inbound
+
+type (
+	// ConfigureProviderAccountRequest leaves the account enabled when
+	// Enabled is not sent.
+	ConfigureProviderAccountRequest struct {
+		BaseURL             string                     `json:"baseUrl"`
+		Credentials         ProviderCredentialsRequest `json:"credentials"`
+		Enabled             *bool                      `json:"enabled"`
+		Priority            int                        `json:"priority"`
+		StatementDescriptor string                     `json:"statementDescriptor"`
+	}
+
+	ProviderCredentialsRequest struct {
+		APIKey       string `json:"apiKey"`
+		ClientID     string `json:"clientId"`
+		ClientSecret string `json:"clientSecret"`
+	}
+)
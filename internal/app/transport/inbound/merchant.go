@@ -0,0 +1,11 @@
+package inbound
+
+type (
+	CreateMerchantRequest struct {
+		Name string `json:"name"`
+	}
+
+	IssueKeyRequest struct {
+		Type string `json:"type"`
+	}
+)
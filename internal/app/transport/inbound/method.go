@@ -1,6 +1,7 @@
 package inbound
 
 type Method struct {
-	Type string `json:"type"`
-	Card Card   `json:"card"`
+	Type  string `json:"type"`
+	Token string `json:"token"` // replaces the card data, which then only carries installments
+	Card  Card   `json:"card"`
 }
@@ -1,12 +1,15 @@
 package inbound
 
+import "lucassaraiva5/api-pay/internal/app/domain/money"
+
 type (
 	CreatePaymentRequest struct {
-		ID          string  `json:"id"`
-		Amount      float64 `json:"amount"`
-		Currency    string  `json:"currency"`
-		Description string  `json:"description"`
-		Method      Method  `json:"method"`
-		Status      string  `json:"status"` 
+		ID          string        `json:"id"`
+		Amount      money.Decimal `json:"amount"`
+		Currency    string        `json:"currency"`
+		Description string        `json:"description"`
+		Method      Method        `json:"method"`
+		Status      string        `json:"status"`
+		Capture     *bool         `json:"capture"` // false only authorizes, defaults to true
 	}
 )
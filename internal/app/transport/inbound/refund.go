@@ -0,0 +1,17 @@
+package inbound
+
+import "lucassaraiva5/api-pay/internal/app/domain/money"
+
+type (
+	RefundRequest struct {
+		ID     string        `json:"id"`
+		Amount money.Decimal `json:"amount"`
+		Reason string        `json:"reason"`
+	}
+)
+
+type (
+	CaptureRequest struct {
+		Amount money.Decimal `json:"amount"`
+	}
+)
@@ -0,0 +1,10 @@
+package inbound
+
+type (
+	CreateTokenRequest struct {
+		Number     string `json:"number"`
+		Holder     string `json:"holder"`
+		CVV        string `json:"cvv"`
+		Expiration string `json:"expiration"`
+	}
+)
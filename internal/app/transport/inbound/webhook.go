@@ -0,0 +1,8 @@
+package inbound
+
+type (
+	CreateWebhookEndpointRequest struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+)
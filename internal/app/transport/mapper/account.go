@@ -0,0 +1,26 @@
+package mapper
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/account"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+)
+
+func SettingsFromConfigureProviderAccountRequest(request *inbound.ConfigureProviderAccountRequest) account.Settings {
+	enabled := true
+	if request.Enabled != nil {
+		enabled = *request.Enabled
+	}
+
+	return account.Settings{
+		BaseURL: request.BaseURL,
+		Credentials: payment.Credentials{
+			APIKey:       request.Credentials.APIKey,
+			ClientID:     request.Credentials.ClientID,
+			ClientSecret: request.Credentials.ClientSecret,
+		},
+		Enabled:             enabled,
+		Priority:            request.Priority,
+		StatementDescriptor: request.StatementDescriptor,
+	}
+}
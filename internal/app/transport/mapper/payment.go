@@ -5,13 +5,21 @@ import (
 	"lucassaraiva5/api-pay/internal/app/transport/inbound"
 )
 
-func PaymentFromCreatePaymentRequest(request *inbound.CreatePaymentRequest) *payment.Payment {
+// PaymentFromCreatePaymentRequest maps the request to a payment, converting
+// the amount to minor units of its currency.
+func PaymentFromCreatePaymentRequest(request *inbound.CreatePaymentRequest) (*payment.Payment, error) {
+	amount, err := request.Amount.In(request.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	return &payment.Payment{
-		Amount:      request.Amount,
-		Currency:    request.Currency,
-		Description: request.Description,
+		Amount:        amount,
+		Description:   request.Description,
+		AuthorizeOnly: request.Capture != nil && !*request.Capture,
 		Method: payment.Method{
-			Type: request.Method.Type,
+			Type:  request.Method.Type,
+			Token: request.Method.Token,
 			Card: payment.Card{
 				Number:            request.Method.Card.Number,
 				Holder:            request.Method.Card.Holder,
@@ -20,5 +28,5 @@ func PaymentFromCreatePaymentRequest(request *inbound.CreatePaymentRequest) *pay
 				InstallmentNumber: request.Method.Card.InstallmentNumber,
 			},
 		},
-	}
+	}, nil
 }
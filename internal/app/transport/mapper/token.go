@@ -0,0 +1,15 @@
+package mapper
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+)
+
+func CardFromCreateTokenRequest(request *inbound.CreateTokenRequest) payment.Card {
+	return payment.Card{
+		Number:     request.Number,
+		Holder:     request.Holder,
+		CVV:        request.CVV,
+		Expiration: request.Expiration,
+	}
+}
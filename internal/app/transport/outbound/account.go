@@ -0,0 +1,20 @@
+package outbound
+
+type (
+	// ProviderAccountResponse never carries the credentials, only their
+	// last characters.
+	ProviderAccountResponse struct {
+		Provider            string                      `json:"provider"`
+		BaseURL             string                      `json:"baseUrl,omitempty"`
+		Credentials         ProviderCredentialsResponse `json:"credentials"`
+		Enabled             bool                        `json:"enabled"`
+		Priority            int                         `json:"priority"`
+		StatementDescriptor string                      `json:"statementDescriptor,omitempty"`
+	}
+
+	ProviderCredentialsResponse struct {
+		APIKey       string `json:"apiKey,omitempty"`
+		ClientID     string `json:"clientId,omitempty"`
+		ClientSecret string `json:"clientSecret,omitempty"`
+	}
+)
@@ -1,8 +1,15 @@
 package outbound
 
-type HealthResponse struct {
-	Healthy bool   `json:"healthy"`
-	ReadDB  string `json:"read_db"`
-	WriteDB string `json:"write_db"`
-	RedisDB string `json:"redis_db"`
-}
+type (
+	HealthResponse struct {
+		Healthy      bool               `json:"healthy"`
+		Dependencies []DependencyHealth `json:"dependencies,omitempty"`
+	}
+
+	DependencyHealth struct {
+		Name      string `json:"name"`
+		Status    string `json:"status"`
+		Required  bool   `json:"required"`
+		LatencyMs int64  `json:"latencyMs"`
+	}
+)
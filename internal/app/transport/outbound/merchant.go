@@ -0,0 +1,21 @@
+package outbound
+
+type (
+	MerchantResponse struct {
+		ID        string            `json:"id"`
+		Name      string            `json:"name"`
+		Keys      []*APIKeyResponse `json:"keys"`
+		CreatedAt string            `json:"createdAt"`
+	}
+
+	// APIKeyResponse only carries the key itself when it is issued.
+	APIKeyResponse struct {
+		ID        string `json:"id"`
+		Type      string `json:"type"`
+		Livemode  bool   `json:"livemode"`
+		Key       string `json:"key,omitempty"`
+		Last4     string `json:"last4"`
+		CreatedAt string `json:"createdAt"`
+		RevokedAt string `json:"revokedAt,omitempty"`
+	}
+)
@@ -1,18 +1,66 @@
 package outbound
 
+import "lucassaraiva5/api-pay/internal/app/domain/money"
+
 type (
-	CreatePaymentResponse struct {
-		Id     string `json:"id"`
-		Status string `json:"status"`
+	PaymentResponse struct {
+		ID                     string        `json:"id"`
+		Amount                 money.Decimal `json:"amount"`
+		CapturedAmount         money.Decimal `json:"capturedAmount"`
+		RefundedAmount         money.Decimal `json:"refundedAmount"`
+		Currency               string        `json:"currency"`
+		Description            string        `json:"description"`
+		Status                 string        `json:"status"`
+		Livemode               bool          `json:"livemode"`
+		CreatedAt              string        `json:"createdAt"`
+		Provider               string        `json:"provider,omitempty"`
+		AuthorizationExpiresAt string        `json:"authorizationExpiresAt,omitempty"`
+		Method                 Method        `json:"method"`
+		Attempts               []Attempt     `json:"attempts,omitempty"`
+		Refunds                []Refund      `json:"refunds,omitempty"`
+	}
+
+	Method struct {
+		Type  string `json:"type"`
+		Token string `json:"token,omitempty"`
+		Card  Card   `json:"card"`
+	}
+
+	// Card only shows what identifies the card to its holder; the number
+	// and CVV never leave the service.
+	Card struct {
+		Brand             string `json:"brand,omitempty"`
+		Last4             string `json:"last4,omitempty"`
+		Holder            string `json:"holder,omitempty"`
+		Expiration        string `json:"expiration,omitempty"`
+		InstallmentNumber int    `json:"installmentNumber"`
+	}
+
+	Attempt struct {
+		ID                string `json:"id"`
+		Provider          string `json:"provider"`
+		ProviderPaymentID string `json:"providerPaymentId,omitempty"`
+		Status            string `json:"status"`
+		Error             string `json:"error,omitempty"`
+		ErrorKind         string `json:"errorKind,omitempty"`
+		CreatedAt         string `json:"createdAt"`
 	}
 
-	ReadPaymentByIdResponse struct {
-		Id     string `json:"id"`
-		Status string `json:"status"` 
+	Refund struct {
+		ID        string        `json:"id"`
+		Amount    money.Decimal `json:"amount"`
+		Reason    string        `json:"reason,omitempty"`
+		Status    string        `json:"status"`
+		Error     string        `json:"error,omitempty"`
+		ErrorKind string        `json:"errorKind,omitempty"`
+		CreatedAt string        `json:"createdAt"`
 	}
 
-	RefundResponse struct {
-		Id     string `json:"id"`
-		Status string `json:"status"` 
+	// StatusChange is an entry of the status history of a payment; From is
+	// omitted for the status it was created with.
+	StatusChange struct {
+		From      string `json:"from,omitempty"`
+		To        string `json:"to"`
+		CreatedAt string `json:"createdAt"`
 	}
 )
@@ -0,0 +1,14 @@
+package outbound
+
+type (
+	TokenResponse struct {
+		ID          string `json:"id"`
+		Fingerprint string `json:"fingerprint"`
+		Last4       string `json:"last4"`
+		Brand       string `json:"brand"`
+		Holder      string `json:"holder"`
+		Expiration  string `json:"expiration"`
+		Livemode    bool   `json:"livemode"`
+		CreatedAt   string `json:"createdAt"`
+	}
+)
@@ -0,0 +1,39 @@
+package outbound
+
+type (
+	// WebhookEndpointResponse only carries the signing secret when the
+	// endpoint is created.
+	WebhookEndpointResponse struct {
+		ID        string   `json:"id"`
+		URL       string   `json:"url"`
+		Events    []string `json:"events"`
+		Secret    string   `json:"secret,omitempty"`
+		Livemode  bool     `json:"livemode"`
+		CreatedAt string   `json:"createdAt"`
+	}
+
+	WebhookDeliveryResponse struct {
+		ID            string           `json:"id"`
+		EventID       string           `json:"eventId"`
+		EndpointID    string           `json:"endpointId"`
+		Status        string           `json:"status"`
+		Attempts      int              `json:"attempts"`
+		NextAttemptAt string           `json:"nextAttemptAt,omitempty"`
+		LastError     string           `json:"lastError,omitempty"`
+		CreatedAt     string           `json:"createdAt"`
+		Log           []WebhookAttempt `json:"log,omitempty"`
+	}
+
+	WebhookAttempt struct {
+		ResponseStatus int    `json:"responseStatus,omitempty"`
+		Error          string `json:"error,omitempty"`
+		DurationMs     int64  `json:"durationMs"`
+		CreatedAt      string `json:"createdAt"`
+	}
+
+	// ProviderWebhookResponse tells the provider whether its event was
+	// processed, a duplicate, or ignored.
+	ProviderWebhookResponse struct {
+		Outcome string `json:"outcome"`
+	}
+)
@@ -0,0 +1,50 @@
+package presenter
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+)
+
+// visibleCredentialSize is how many trailing characters of a credential are
+// shown to tell credentials apart.
+const visibleCredentialSize = 4
+
+func ProviderAccount(account *payment.Account) *outbound.ProviderAccountResponse {
+	return &outbound.ProviderAccountResponse{
+		Provider: account.Provider,
+		BaseURL:  account.BaseURL,
+		Credentials: outbound.ProviderCredentialsResponse{
+			APIKey:       maskCredential(account.Credentials.APIKey),
+			ClientID:     maskCredential(account.Credentials.ClientID),
+			ClientSecret: maskCredential(account.Credentials.ClientSecret),
+		},
+		Enabled:             account.Enabled,
+		Priority:            account.Priority,
+		StatementDescriptor: account.StatementDescriptor,
+	}
+}
+
+func ProviderAccounts(accounts []payment.Account) []*outbound.ProviderAccountResponse {
+	response := make([]*outbound.ProviderAccountResponse, 0, len(accounts))
+	for i := range accounts {
+		response = append(response, ProviderAccount(&accounts[i]))
+	}
+
+	return response
+}
+
+// maskCredential keeps the last characters of long credentials and hides
+// short ones entirely, without telling their length.
+func maskCredential(value string) string {
+	const mask = "****"
+
+	if value == "" {
+		return ""
+	}
+
+	if len(value) <= 2*visibleCredentialSize {
+		return mask
+	}
+
+	return mask + value[len(value)-visibleCredentialSize:]
+}
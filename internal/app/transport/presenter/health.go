@@ -0,0 +1,20 @@
+package presenter
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/health"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+)
+
+func Health(report health.Report) outbound.HealthResponse {
+	response := outbound.HealthResponse{Healthy: report.Ready}
+	for _, result := range report.Results {
+		response.Dependencies = append(response.Dependencies, outbound.DependencyHealth{
+			Name:      result.Name,
+			Status:    result.Status,
+			Required:  result.Required,
+			LatencyMs: result.Latency.Milliseconds(),
+		})
+	}
+
+	return response
+}
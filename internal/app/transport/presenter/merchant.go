@@ -0,0 +1,32 @@
+package presenter
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+)
+
+func Merchant(m *merchant.Merchant) *outbound.MerchantResponse {
+	keys := make([]*outbound.APIKeyResponse, 0, len(m.Keys))
+	for i := range m.Keys {
+		keys = append(keys, APIKey(&m.Keys[i]))
+	}
+
+	return &outbound.MerchantResponse{
+		ID:        m.ID,
+		Name:      m.Name,
+		Keys:      keys,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func APIKey(key *merchant.APIKey) *outbound.APIKeyResponse {
+	return &outbound.APIKeyResponse{
+		ID:        key.ID,
+		Type:      string(key.Type),
+		Livemode:  key.Livemode,
+		Key:       key.Value,
+		Last4:     key.Last4,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
@@ -5,23 +5,78 @@ import (
 	"lucassaraiva5/api-pay/internal/app/transport/outbound"
 )
 
-func CreatePayment(payment *payment.Payment) *outbound.CreatePaymentResponse {
-	return &outbound.CreatePaymentResponse{
-		Id:     payment.ID,
-		Status: payment.Status,
+// Card masks the card down to its brand and last four digits.
+func Card(card payment.Card) outbound.Card {
+	masked := outbound.Card{
+		Holder:            card.Holder,
+		Expiration:        card.Expiration,
+		InstallmentNumber: card.InstallmentNumber,
 	}
+
+	if len(card.Number) >= 4 {
+		masked.Brand = payment.CardBrand(card.Number)
+		masked.Last4 = card.Number[len(card.Number)-4:]
+	}
+
+	return masked
 }
 
-func ReadPaymentById(payment *payment.Payment) *outbound.ReadPaymentByIdResponse {
-	return &outbound.ReadPaymentByIdResponse{
-		Id:     payment.ID,
-		Status: payment.Status,
+func Payment(payment *payment.Payment) *outbound.PaymentResponse {
+	response := &outbound.PaymentResponse{
+		ID:                     payment.ID,
+		Amount:                 payment.Amount.Decimal(),
+		CapturedAmount:         payment.CapturedAmount.Decimal(),
+		RefundedAmount:         payment.RefundedAmount.Decimal(),
+		Currency:               payment.Amount.Currency,
+		Description:            payment.Description,
+		Status:                 string(payment.Status),
+		Livemode:               payment.Livemode,
+		CreatedAt:              payment.CreatedAt,
+		Provider:               payment.Provider,
+		AuthorizationExpiresAt: payment.AuthorizationExpiresAt,
+		Method: outbound.Method{
+			Type:  payment.Method.Type,
+			Token: payment.Method.Token,
+			Card:  Card(payment.Method.Card),
+		},
+	}
+
+	for _, attempt := range payment.Attempts {
+		response.Attempts = append(response.Attempts, outbound.Attempt{
+			ID:                attempt.ID,
+			Provider:          attempt.Provider,
+			ProviderPaymentID: attempt.ProviderPaymentID,
+			Status:            attempt.Status,
+			Error:             attempt.Error,
+			ErrorKind:         string(attempt.ErrorKind),
+			CreatedAt:         attempt.CreatedAt,
+		})
 	}
+
+	for _, refund := range payment.Refunds {
+		response.Refunds = append(response.Refunds, outbound.Refund{
+			ID:        refund.ID,
+			Amount:    refund.Amount.Decimal(),
+			Reason:    refund.Reason,
+			Status:    refund.Status,
+			Error:     refund.Error,
+			ErrorKind: string(refund.ErrorKind),
+			CreatedAt: refund.CreatedAt,
+		})
+	}
+
+	return response
 }
 
-func Refund(payment *payment.Payment) *outbound.RefundResponse {
-	return &outbound.RefundResponse{
-		Id:     payment.ID,
-		Status: payment.Status,
+func StatusHistory(changes []payment.StatusChange) []outbound.StatusChange {
+	response := make([]outbound.StatusChange, 0, len(changes))
+	for _, change := range changes {
+		response = append(response, outbound.StatusChange{
+			From:      string(change.From),
+			To:        string(change.To),
+			CreatedAt: change.CreatedAt,
+		})
 	}
+
+	return response
 }
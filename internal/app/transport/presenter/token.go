@@ -0,0 +1,19 @@
+package presenter
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+)
+
+func Token(token *vault.Token) *outbound.TokenResponse {
+	return &outbound.TokenResponse{
+		ID:          token.ID,
+		Fingerprint: token.Fingerprint,
+		Last4:       token.Last4,
+		Brand:       token.Brand,
+		Holder:      token.Holder,
+		Expiration:  token.Expiration,
+		Livemode:    token.Livemode,
+		CreatedAt:   token.CreatedAt,
+	}
+}
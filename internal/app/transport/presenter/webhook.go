@@ -0,0 +1,63 @@
+package presenter
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/webhook"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+)
+
+func WebhookEndpoint(endpoint *webhook.Endpoint) *outbound.WebhookEndpointResponse {
+	events := endpoint.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	return &outbound.WebhookEndpointResponse{
+		ID:        endpoint.ID,
+		URL:       endpoint.URL,
+		Events:    events,
+		Livemode:  endpoint.Livemode,
+		CreatedAt: endpoint.CreatedAt,
+	}
+}
+
+func WebhookEndpoints(endpoints []webhook.Endpoint) []*outbound.WebhookEndpointResponse {
+	response := make([]*outbound.WebhookEndpointResponse, 0, len(endpoints))
+	for i := range endpoints {
+		response = append(response, WebhookEndpoint(&endpoints[i]))
+	}
+
+	return response
+}
+
+func WebhookDelivery(delivery *webhook.Delivery) *outbound.WebhookDeliveryResponse {
+	response := &outbound.WebhookDeliveryResponse{
+		ID:            delivery.ID,
+		EventID:       delivery.EventID,
+		EndpointID:    delivery.EndpointID,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastError:     delivery.LastError,
+		CreatedAt:     delivery.CreatedAt,
+	}
+
+	for _, attempt := range delivery.Log {
+		response.Log = append(response.Log, outbound.WebhookAttempt{
+			ResponseStatus: attempt.ResponseStatus,
+			Error:          attempt.Error,
+			DurationMs:     attempt.DurationMs,
+			CreatedAt:      attempt.CreatedAt,
+		})
+	}
+
+	return response
+}
+
+func WebhookDeliveries(deliveries []webhook.Delivery) []*outbound.WebhookDeliveryResponse {
+	response := make([]*outbound.WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		response = append(response, WebhookDelivery(&deliveries[i]))
+	}
+
+	return response
+}
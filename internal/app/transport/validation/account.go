@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"net/url"
+)
+
+// maxStatementDescriptorSize is the longest descriptor card networks print.
+const maxStatementDescriptorSize = 22
+
+// ConfigureProviderAccount checks the account settings and returns Errors
+// listing every invalid field, or nil.
+func ConfigureProviderAccount(request *inbound.ConfigureProviderAccountRequest) error {
+	var errs Errors
+
+	if request.BaseURL != "" {
+		if parsed, err := url.Parse(request.BaseURL); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			errs.add("baseUrl", CodeInvalid, "baseUrl must be an absolute https URL")
+		}
+	}
+
+	credentials := request.Credentials
+	if credentials.APIKey == "" && credentials.ClientID == "" {
+		errs.add("credentials", CodeRequired, "credentials need an apiKey or a clientId")
+	}
+
+	if credentials.ClientID != "" && credentials.ClientSecret == "" {
+		errs.add("credentials.clientSecret", CodeRequired, "clientSecret is required with clientId")
+	}
+
+	if request.Priority < 0 {
+		errs.add("priority", CodeInvalid, "priority must not be negative")
+	}
+
+	if len(request.StatementDescriptor) > maxStatementDescriptorSize {
+		errs.add("statementDescriptor", CodeInvalid, "statementDescriptor must be at most 22 characters")
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"net/http"
+	"strings"
+)
+
+var ErrInvalidRequest = apperror.New(http.StatusUnprocessableEntity, "invalid_request", "request has invalid fields")
+
+const (
+	CodeRequired    = "required"
+	CodeInvalid     = "invalid"
+	CodeUnsupported = "unsupported"
+	CodeTooPrecise  = "too_precise"
+	CodeTooSmall    = "too_small"
+	CodeTooLarge    = "too_large"
+	CodeExpired     = "expired"
+)
+
+// FieldError describes one invalid field, named by its JSON path such as
+// "method.card.number".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors holds every invalid field of a request, so clients can fix them all
+// in one round trip.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, field := range e {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// APIError lists the invalid fields so clients can fix them all at once.
+func (e Errors) APIError() *apperror.Error {
+	apiErr := ErrInvalidRequest.Wrap(e)
+	apiErr.Fields = e
+	return apiErr
+}
+
+func (e *Errors) add(field string, code string, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
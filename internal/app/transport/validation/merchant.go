@@ -0,0 +1,39 @@
+package validation
+
+import (
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"strings"
+)
+
+const maxMerchantNameSize = 255
+
+// CreateMerchant checks the merchant name and returns Errors listing every
+// invalid field, or nil.
+func CreateMerchant(request *inbound.CreateMerchantRequest) error {
+	var errs Errors
+
+	if strings.TrimSpace(request.Name) == "" {
+		errs.add("name", CodeRequired, "name is required")
+	} else if len(request.Name) > maxMerchantNameSize {
+		errs.add("name", CodeInvalid, "name must be at most 255 characters")
+	}
+
+	return errs.err()
+}
+
+// IssueKey checks the key type and returns Errors listing every invalid
+// field, or nil.
+func IssueKey(request *inbound.IssueKeyRequest) error {
+	var errs Errors
+
+	switch merchant.KeyType(request.Type) {
+	case merchant.KeySecret, merchant.KeyPublishable:
+	case "":
+		errs.add("type", CodeRequired, "type is required")
+	default:
+		errs.add("type", CodeUnsupported, "type must be secret or publishable")
+	}
+
+	return errs.err()
+}
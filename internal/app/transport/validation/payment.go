@@ -0,0 +1,191 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MinInstallments = 1
+	MaxInstallments = 12
+)
+
+// Limit bounds the amount of a single payment in major units of a currency.
+type Limit struct {
+	Min money.Decimal
+	Max money.Decimal
+}
+
+// defaultLimit applies to currencies without their own limit. Any positive
+// amount is at least one minor unit once its precision has been checked.
+var defaultLimit = newLimit("0", "1000000")
+
+var amountLimits = map[string]Limit{
+	"USD": newLimit("0.50", "999999.99"),
+	"EUR": newLimit("0.50", "999999.99"),
+	"GBP": newLimit("0.30", "999999.99"),
+	"BRL": newLimit("0.50", "5000000"),
+	"JPY": newLimit("50", "99999999"),
+	"KRW": newLimit("100", "999999999"),
+}
+
+// CreatePayment checks a payment request before it reaches the providers and
+// returns Errors listing every invalid field, or nil.
+func CreatePayment(request *inbound.CreatePaymentRequest) error {
+	var errs Errors
+
+	validateAmount(&errs, request.Amount, request.Currency)
+
+	switch request.Method.Type {
+	case "card":
+		validateMethod(&errs, request.Method)
+	case "":
+		errs.add("method.type", CodeRequired, "payment method is required")
+	default:
+		errs.add("method.type", CodeUnsupported, fmt.Sprintf("payment method %q is not supported", request.Method.Type))
+	}
+
+	return errs.err()
+}
+
+func validateAmount(errs *Errors, amount money.Decimal, currency string) {
+	if currency == "" {
+		errs.add("currency", CodeRequired, "currency is required")
+		return
+	}
+
+	minor, err := amount.In(currency)
+	switch {
+	case errors.Is(err, money.ErrUnknownCurrency):
+		errs.add("currency", CodeUnsupported, fmt.Sprintf("%q is not an ISO-4217 currency", currency))
+		return
+	case errors.Is(err, money.ErrTooPrecise):
+		errs.add("amount", CodeTooPrecise, fmt.Sprintf("%s allows at most %d decimals", strings.ToUpper(currency), exponent(currency)))
+		return
+	case err != nil:
+		errs.add("amount", CodeInvalid, err.Error())
+		return
+	}
+
+	limit := limitFor(minor.Currency)
+	switch {
+	case amount.Sign() <= 0:
+		errs.add("amount", CodeTooSmall, "amount must be positive")
+	case amount.Cmp(limit.Min) < 0:
+		errs.add("amount", CodeTooSmall, fmt.Sprintf("amount must be at least %s %s", limit.Min, minor.Currency))
+	case amount.Cmp(limit.Max) > 0:
+		errs.add("amount", CodeTooLarge, fmt.Sprintf("amount must be at most %s %s", limit.Max, minor.Currency))
+	}
+}
+
+// validateMethod checks either a raw card or a token. Card data sent next to
+// a token is rejected rather than silently ignored.
+func validateMethod(errs *Errors, method inbound.Method) {
+	if method.Token == "" {
+		card := method.Card
+		validateCard(errs, "method.card.", card.Number, card.Holder, card.CVV, card.Expiration)
+	} else if card := method.Card; card.Number != "" || card.Holder != "" || card.CVV != "" || card.Expiration != "" {
+		errs.add("method.card", CodeUnsupported, "card data must not be sent with a token")
+	}
+
+	if installments := method.Card.InstallmentNumber; installments < MinInstallments || installments > MaxInstallments {
+		errs.add("method.card.installmentNumber", CodeInvalid, fmt.Sprintf("installments must be between %d and %d", MinInstallments, MaxInstallments))
+	}
+}
+
+// validateCard checks the card fields, named with prefix in errors.
+func validateCard(errs *Errors, prefix string, number string, holder string, cvv string, expiration string) {
+	switch {
+	case number == "":
+		errs.add(prefix+"number", CodeRequired, "card number is required")
+	case len(number) < 12 || len(number) > 19 || !payment.LuhnValid(number):
+		errs.add(prefix+"number", CodeInvalid, "card number is not valid")
+	}
+
+	if strings.TrimSpace(holder) == "" {
+		errs.add(prefix+"holder", CodeRequired, "card holder is required")
+	}
+
+	cvvLength := 3
+	if payment.CardBrand(number) == payment.BrandAmex {
+		cvvLength = 4
+	}
+
+	switch {
+	case cvv == "":
+		errs.add(prefix+"cvv", CodeRequired, "cvv is required")
+	case len(cvv) != cvvLength || !digits(cvv):
+		errs.add(prefix+"cvv", CodeInvalid, fmt.Sprintf("cvv must have %d digits", cvvLength))
+	}
+
+	validateExpiration(errs, prefix+"expiration", expiration)
+}
+
+// validateExpiration accepts MM/YYYY and MM/YY. A card is valid through the
+// last day of its expiry month.
+func validateExpiration(errs *Errors, field string, expiration string) {
+	if expiration == "" {
+		errs.add(field, CodeRequired, "expiration is required")
+		return
+	}
+
+	monthText, yearText, ok := strings.Cut(expiration, "/")
+	month, monthErr := strconv.Atoi(monthText)
+	year, yearErr := strconv.Atoi(yearText)
+	if !ok || monthErr != nil || yearErr != nil || len(monthText) != 2 || month < 1 || month > 12 || len(yearText) != 2 && len(yearText) != 4 {
+		errs.add(field, CodeInvalid, "expiration must be MM/YYYY")
+		return
+	}
+
+	if len(yearText) == 2 {
+		year += 2000
+	}
+
+	current := time.Now()
+	if year < current.Year() || year == current.Year() && time.Month(month) < current.Month() {
+		errs.add(field, CodeExpired, "card has expired")
+	}
+}
+
+func limitFor(currency string) Limit {
+	if limit, ok := amountLimits[currency]; ok {
+		return limit
+	}
+
+	return defaultLimit
+}
+
+func exponent(currency string) int {
+	exponent, _ := money.Exponent(currency)
+	return exponent
+}
+
+func digits(value string) bool {
+	for _, digit := range value {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newLimit(min string, max string) Limit {
+	minimum, err := money.ParseDecimal(min)
+	if err != nil {
+		panic(err)
+	}
+
+	maximum, err := money.ParseDecimal(max)
+	if err != nil {
+		panic(err)
+	}
+
+	return Limit{Min: minimum, Max: maximum}
+}
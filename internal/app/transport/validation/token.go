@@ -0,0 +1,13 @@
+package validation
+
+import "lucassaraiva5/api-pay/internal/app/transport/inbound"
+
+// CreateToken checks the card sent to the vault and returns Errors listing
+// every invalid field, or nil.
+func CreateToken(request *inbound.CreateTokenRequest) error {
+	var errs Errors
+
+	validateCard(&errs, "", request.Number, request.Holder, request.CVV, request.Expiration)
+
+	return errs.err()
+}
@@ -0,0 +1,29 @@
+package validation
+
+import (
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"net/url"
+	"slices"
+)
+
+// CreateWebhookEndpoint checks the endpoint URL and its event types and
+// returns Errors listing every invalid field, or nil.
+func CreateWebhookEndpoint(request *inbound.CreateWebhookEndpointRequest) error {
+	var errs Errors
+
+	if request.URL == "" {
+		errs.add("url", CodeRequired, "url is required")
+	} else if parsed, err := url.Parse(request.URL); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		errs.add("url", CodeInvalid, "url must be an absolute https URL")
+	}
+
+	for i, event := range request.Events {
+		if !slices.Contains(payment.EventTypes, event) {
+			errs.add(fmt.Sprintf("events[%d]", i), CodeUnsupported, fmt.Sprintf("event %q is not supported", event))
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,76 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Error is a failure the API can show to clients: a stable code they can
+// branch on, the HTTP status, whether retrying the same request may succeed
+// and a message that never exposes internal details. The cause is kept for
+// logs only.
+type Error struct {
+	Code      string
+	Status    int
+	Retryable bool
+	Message   string
+	Fields    any
+	Err       error
+}
+
+var (
+	ErrInternal         = New(http.StatusInternalServerError, "internal_error", "an unexpected error occurred")
+	ErrMalformedRequest = New(http.StatusBadRequest, "malformed_request", "request body is not valid JSON for this endpoint")
+	ErrRouteNotFound    = New(http.StatusNotFound, "route_not_found", "no route matches the request")
+	ErrMethodNotAllowed = New(http.StatusMethodNotAllowed, "method_not_allowed", "method is not allowed on this route")
+)
+
+// Converter is implemented by errors that map themselves to an API error,
+// such as provider errors classified by kind.
+type Converter interface {
+	APIError() *Error
+}
+
+func New(status int, code string, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func NewRetryable(status int, code string, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message, Retryable: true}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e caused by err, so the cause is logged while the
+// client still sees the safe message.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// From returns the API error carried by err. Errors that carry none become
+// ErrInternal, hiding their text from clients.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var converter Converter
+	if errors.As(err, &converter) {
+		return converter.APIError()
+	}
+
+	return ErrInternal.Wrap(err)
+}
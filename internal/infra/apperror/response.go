@@ -0,0 +1,32 @@
+package apperror
+
+type (
+	// Response is the envelope of every error the API returns:
+	//
+	//	{"error": {"code": "payment_not_found", "message": "payment not found", "retryable": false, "cid": "..."}}
+	//
+	// Fields is only set for invalid requests and lists each invalid field.
+	Response struct {
+		Error Body `json:"error"`
+	}
+
+	Body struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		Retryable bool   `json:"retryable"`
+		CID       string `json:"cid,omitempty"`
+		Fields    any    `json:"fields,omitempty"`
+	}
+)
+
+func NewResponse(err *Error, cid string) *Response {
+	return &Response{
+		Error: Body{
+			Code:      err.Code,
+			Message:   err.Message,
+			Retryable: err.Retryable,
+			CID:       cid,
+			Fields:    err.Fields,
+		},
+	}
+}
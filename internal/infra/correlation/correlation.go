@@ -0,0 +1,90 @@
+package correlation
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// HeaderCID carries the correlation id in requests and responses, both the
+// ones the service receives and the ones it sends to providers.
+const HeaderCID = "x-cid"
+
+type contextKey string
+
+const (
+	cidKey        contextKey = "cid"
+	merchantIDKey contextKey = "merchant_id"
+	paymentIDKey  contextKey = "payment_id"
+)
+
+// NewCID returns a fresh correlation id, for work no request started.
+func NewCID() string {
+	return uuid.New().String()
+}
+
+// WithCID returns ctx carrying the correlation id cid.
+func WithCID(ctx context.Context, cid string) context.Context {
+	return context.WithValue(ctx, cidKey, cid)
+}
+
+// CID returns the correlation id of ctx, empty when it carries none.
+func CID(ctx context.Context) string {
+	return value(ctx, cidKey)
+}
+
+// WithMerchantID returns ctx carrying the merchant the work is done for.
+func WithMerchantID(ctx context.Context, merchantID string) context.Context {
+	return context.WithValue(ctx, merchantIDKey, merchantID)
+}
+
+func MerchantID(ctx context.Context) string {
+	return value(ctx, merchantIDKey)
+}
+
+// WithPaymentID returns ctx carrying the payment the work is about.
+func WithPaymentID(ctx context.Context, paymentID string) context.Context {
+	return context.WithValue(ctx, paymentIDKey, paymentID)
+}
+
+func PaymentID(ctx context.Context) string {
+	return value(ctx, paymentIDKey)
+}
+
+// Fields returns the ids ctx carries keyed as log attributes, leaving out
+// the ones it does not carry.
+func Fields(ctx context.Context) map[string]string {
+	fields := make(map[string]string, 3)
+	for _, key := range []contextKey{cidKey, merchantIDKey, paymentIDKey} {
+		if id := value(ctx, key); id != "" {
+			fields[string(key)] = id
+		}
+	}
+
+	return fields
+}
+
+// Inject writes the correlation id of ctx into the headers of an outgoing
+// request, so the provider can trace it back.
+func Inject(ctx context.Context, header http.Header) {
+	if cid := CID(ctx); cid != "" {
+		header.Set(HeaderCID, cid)
+	}
+}
+
+// Extract returns the correlation id sent in header, empty when there is
+// none.
+func Extract(header http.Header) string {
+	return strings.TrimSpace(header.Get(HeaderCID))
+}
+
+func value(ctx context.Context, key contextKey) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(key).(string)
+	return id
+}
@@ -49,6 +49,45 @@ func (d *Database) Connection() *sql.DB {
 	return d.initializeAndGetDB()
 }
 
+// Ping checks the database answers within ctx. A lazy connection is opened
+// on the way but, unlike Connection, never retried nor fatal, so health
+// checks can report the database down instead.
+func (d *Database) Ping(ctx context.Context) error {
+	db, err := d.tryConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.PingContext(ctx)
+}
+
+func (d *Database) tryConnection(ctx context.Context) (*sql.DB, error) {
+	if db := d.db; db != nil {
+		return db, nil
+	}
+
+	d.locker.Lock()
+	defer d.locker.Unlock()
+
+	if d.db != nil {
+		return d.db, nil
+	}
+
+	db, err := sql.Open(d.config.Driver, d.connectionStringBuilder(d.config))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	d.configurePool(db)
+	d.db = db
+	return db, nil
+}
+
 func (d *Database) Close() {
 	d.locker.Lock()
 	defer d.locker.Unlock()
@@ -106,11 +145,7 @@ func (d *Database) initializeAndGetDB() *sql.DB {
 		}
 	}
 
-	db.SetMaxIdleConns(d.config.MinConnections)
-	db.SetMaxOpenConns(d.config.MaxConnections)
-	db.SetConnMaxLifetime(d.config.ConnectionMaxLifetime)
-	db.SetConnMaxIdleTime(d.config.ConnectionMaxIdleTime)
-
+	d.configurePool(db)
 	d.db = db
 
 	elapsed := time.Since(start)
@@ -119,6 +154,13 @@ func (d *Database) initializeAndGetDB() *sql.DB {
 	return db
 }
 
+func (d *Database) configurePool(db *sql.DB) {
+	db.SetMaxIdleConns(d.config.MinConnections)
+	db.SetMaxOpenConns(d.config.MaxConnections)
+	db.SetConnMaxLifetime(d.config.ConnectionMaxLifetime)
+	db.SetConnMaxIdleTime(d.config.ConnectionMaxIdleTime)
+}
+
 func (d *Database) checkConnection(db *sql.DB) error {
 	timeout, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
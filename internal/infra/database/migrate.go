@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+)
+
+const (
+	migrationsDir      = "migrations"
+	migrationsLockID   = 7311001
+	createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate applies every embedded migration that was not applied yet, in
+// lexical order, each one inside its own transaction. An advisory lock keeps
+// concurrent instances from migrating the same database at the same time.
+func Migrate(ctx context.Context, database *Database) error {
+	start := time.Now()
+	db := database.Connection()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockID)
+	}()
+
+	if _, err = conn.ExecContext(ctx, createVersionTable); err != nil {
+		return err
+	}
+
+	files, err := migrationFiles()
+	if err != nil {
+		return err
+	}
+
+	applied := 0
+	for _, file := range files {
+		version := strings.TrimSuffix(file, ".sql")
+
+		var exists bool
+		if err = conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&exists); err != nil {
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		content, err := migrations.ReadFile(migrationsDir + "/" + file)
+		if err != nil {
+			return err
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, string(content)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+
+		applied++
+		logger.Info(ctx, fmt.Sprintf("Migration [%s] applied", version), attributes.Attributes{"migration.version": version})
+	}
+
+	logger.Info(ctx, fmt.Sprintf("Database migrations finished in [%v]", time.Since(start)), attributes.Attributes{
+		"migration.applied": applied,
+		"migration.total":   len(files),
+	})
+
+	return nil
+}
+
+func migrationFiles() ([]string, error) {
+	entries, err := fs.ReadDir(migrations, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			files = append(files, entry.Name())
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id                  UUID PRIMARY KEY,
+    amount              NUMERIC(19, 4) NOT NULL,
+    currency            VARCHAR(3)     NOT NULL,
+    description         TEXT           NOT NULL DEFAULT '',
+    status              VARCHAR(32)    NOT NULL,
+    payment_type        VARCHAR(32)    NOT NULL DEFAULT '',
+    provider            VARCHAR(32)    NOT NULL DEFAULT '',
+    provider_payment_id VARCHAR(128)   NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payments_created_at_idx ON payments (created_at);
+CREATE INDEX IF NOT EXISTS payments_provider_payment_id_idx ON payments (provider, provider_payment_id);
+
+CREATE TABLE IF NOT EXISTS payment_attempts (
+    id                  UUID PRIMARY KEY,
+    payment_id          UUID         NOT NULL REFERENCES payments (id),
+    provider            VARCHAR(32)  NOT NULL,
+    provider_payment_id VARCHAR(128) NOT NULL DEFAULT '',
+    status              VARCHAR(32)  NOT NULL,
+    error               TEXT         NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payment_attempts_payment_id_idx ON payment_attempts (payment_id);
+
+CREATE TABLE IF NOT EXISTS payment_status_history (
+    id         BIGSERIAL PRIMARY KEY,
+    payment_id UUID        NOT NULL REFERENCES payments (id),
+    status     VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payment_status_history_payment_id_idx ON payment_status_history (payment_id);
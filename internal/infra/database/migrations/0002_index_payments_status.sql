@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS payments_status_created_at_idx ON payments (status, created_at);
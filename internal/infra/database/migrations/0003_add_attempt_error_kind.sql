@@ -0,0 +1 @@
+ALTER TABLE payment_attempts ADD COLUMN IF NOT EXISTS error_kind VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded_amount NUMERIC(19, 4) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id         UUID PRIMARY KEY,
+    payment_id UUID           NOT NULL REFERENCES payments (id),
+    amount     NUMERIC(19, 4) NOT NULL,
+    reason     TEXT           NOT NULL DEFAULT '',
+    status     VARCHAR(32)    NOT NULL,
+    error      TEXT           NOT NULL DEFAULT '',
+    error_kind VARCHAR(32)    NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payment_refunds_payment_id_idx ON payment_refunds (payment_id);
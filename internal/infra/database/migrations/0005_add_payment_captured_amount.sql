@@ -0,0 +1,4 @@
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS captured_amount NUMERIC(19, 4) NOT NULL DEFAULT 0;
+
+-- Every charge made so far was captured right away.
+UPDATE payments SET captured_amount = amount WHERE provider_payment_id <> '';
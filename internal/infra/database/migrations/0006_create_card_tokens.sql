@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS card_tokens (
+    id          VARCHAR(64) PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL,
+    last4       VARCHAR(4)  NOT NULL,
+    brand       VARCHAR(32) NOT NULL,
+    holder      TEXT        NOT NULL DEFAULT '',
+    expiration  VARCHAR(7)  NOT NULL,
+    key_id      VARCHAR(64) NOT NULL,
+    ciphertext  BYTEA       NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS card_tokens_fingerprint_idx ON card_tokens (fingerprint);
+CREATE INDEX IF NOT EXISTS card_tokens_key_id_idx ON card_tokens (key_id);
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS card_token VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id         UUID PRIMARY KEY,
+    url        TEXT        NOT NULL,
+    secret     TEXT        NOT NULL,
+    events     TEXT[]      NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id         VARCHAR(64) PRIMARY KEY,
+    type       VARCHAR(64) NOT NULL,
+    payment_id UUID        NOT NULL,
+    payload    JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_events_payment_id_idx ON webhook_events (payment_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              UUID PRIMARY KEY,
+    event_id        VARCHAR(64) NOT NULL REFERENCES webhook_events (id),
+    endpoint_id     UUID        NOT NULL REFERENCES webhook_endpoints (id),
+    status          VARCHAR(32) NOT NULL,
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id              UUID PRIMARY KEY,
+    delivery_id     UUID        NOT NULL REFERENCES webhook_deliveries (id),
+    response_status INTEGER     NOT NULL DEFAULT 0,
+    error           TEXT        NOT NULL DEFAULT '',
+    duration_ms     BIGINT      NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery_id_idx ON webhook_delivery_attempts (delivery_id);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS provider_events (
+    provider    VARCHAR(32) NOT NULL,
+    event_id    VARCHAR(128) NOT NULL,
+    type        VARCHAR(64) NOT NULL DEFAULT '',
+    payment_id  VARCHAR(64) NOT NULL DEFAULT '',
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, event_id)
+);
@@ -0,0 +1,35 @@
+-- Statuses stored so far mixed provider vocabularies with our own. PayPal
+-- reported completed sales as authorized, so those become captured before
+-- holds awaiting capture take the authorized name.
+UPDATE payments SET status = CASE status
+    WHEN 'paid' THEN 'captured'
+    WHEN 'authorized' THEN 'captured'
+    WHEN 'requires_capture' THEN 'authorized'
+    WHEN 'unknown' THEN 'pending'
+    WHEN 'declined' THEN 'failed'
+    WHEN 'expired' THEN 'canceled'
+    ELSE status
+END;
+
+UPDATE payment_status_history SET status = CASE status
+    WHEN 'paid' THEN 'captured'
+    WHEN 'authorized' THEN 'captured'
+    WHEN 'requires_capture' THEN 'authorized'
+    WHEN 'unknown' THEN 'pending'
+    WHEN 'declined' THEN 'failed'
+    WHEN 'expired' THEN 'canceled'
+    ELSE status
+END;
+
+ALTER TABLE payment_status_history ADD COLUMN IF NOT EXISTS previous_status VARCHAR(32) NOT NULL DEFAULT '';
+
+UPDATE payment_status_history h SET previous_status = p.previous_status
+FROM (
+    SELECT id, LAG(status) OVER (PARTITION BY payment_id ORDER BY created_at, id) AS previous_status
+    FROM payment_status_history
+) p
+WHERE h.id = p.id AND p.previous_status IS NOT NULL;
+
+-- Expired authorizations are now canceled ones.
+UPDATE webhook_endpoints SET events = array_replace(events, 'payment.expired', 'payment.canceled')
+WHERE 'payment.expired' = ANY (events);
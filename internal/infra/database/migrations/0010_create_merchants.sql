@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS merchants (
+    id         UUID PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Only the SHA-256 of each key is stored, looked up on every request.
+CREATE TABLE IF NOT EXISTS merchant_api_keys (
+    id          UUID PRIMARY KEY,
+    merchant_id UUID        NOT NULL REFERENCES merchants (id),
+    type        VARCHAR(16) NOT NULL,
+    livemode    BOOLEAN     NOT NULL,
+    key_hash    VARCHAR(64) NOT NULL UNIQUE,
+    last4       VARCHAR(4)  NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS merchant_api_keys_merchant_id_idx ON merchant_api_keys (merchant_id);
+
+-- Rows stored before merchants existed keep an empty merchant and test mode:
+-- no API key can reach them.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS merchant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS livemode BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS payments_merchant_id_idx ON payments (merchant_id, created_at);
+
+ALTER TABLE card_tokens ADD COLUMN IF NOT EXISTS merchant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE card_tokens ADD COLUMN IF NOT EXISTS livemode BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS merchant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS livemode BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS webhook_endpoints_merchant_id_idx ON webhook_endpoints (merchant_id, livemode) WHERE deleted_at IS NULL;
@@ -0,0 +1,16 @@
+-- A merchant's own account with a provider, per mode. Credentials hold the
+-- JSON credentials encrypted by the vault key key_id.
+CREATE TABLE IF NOT EXISTS provider_accounts (
+    merchant_id          VARCHAR(64) NOT NULL,
+    livemode             BOOLEAN     NOT NULL,
+    provider             VARCHAR(32) NOT NULL,
+    base_url             TEXT        NOT NULL DEFAULT '',
+    enabled              BOOLEAN     NOT NULL DEFAULT true,
+    priority             INTEGER     NOT NULL DEFAULT 0,
+    statement_descriptor VARCHAR(22) NOT NULL DEFAULT '',
+    key_id               VARCHAR(64) NOT NULL,
+    credentials          BYTEA       NOT NULL,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (merchant_id, livemode, provider)
+);
@@ -0,0 +1,16 @@
+-- Events of payment changes, written in the transaction of the change and
+-- published to webhooks from here. Payload is the payment as stored, with
+-- only the masked card_number kept of its card number.
+CREATE TABLE IF NOT EXISTS payment_events (
+    id            UUID PRIMARY KEY,
+    payment_id    UUID        NOT NULL REFERENCES payments (id),
+    type          VARCHAR(64) NOT NULL,
+    merchant_id   VARCHAR(64) NOT NULL DEFAULT '',
+    payload       JSONB       NOT NULL,
+    card_number   VARCHAR(32) NOT NULL DEFAULT '',
+    claimed_until TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at  TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payment_events_unpublished_idx ON payment_events (created_at) WHERE published_at IS NULL;
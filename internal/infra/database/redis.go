@@ -42,6 +42,43 @@ func (r *Redis) Connection() *redis.Client {
 	return r.initializeAndGetRedis()
 }
 
+// TryConnection returns the client when Redis is reachable and an error
+// otherwise. Unlike Connection it never retries nor exits the process, so
+// request paths can degrade gracefully while Redis is down.
+func (r *Redis) TryConnection() (*redis.Client, error) {
+	if rdb := r.rdb; rdb != nil {
+		return rdb, nil
+	}
+
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	if r.rdb != nil {
+		return r.rdb, nil
+	}
+
+	rdb := redis.NewClient(r.opt)
+	if err := r.checkConnection(rdb); err != nil {
+		_ = rdb.Close()
+		return nil, err
+	}
+
+	logger.Info(context.Background(), "Redis initialized", r.configToAttribute())
+
+	r.rdb = rdb
+	return rdb, nil
+}
+
+// Ping checks Redis answers within ctx, connecting through TryConnection.
+func (r *Redis) Ping(ctx context.Context) error {
+	rdb, err := r.TryConnection()
+	if err != nil {
+		return err
+	}
+
+	return rdb.Ping(ctx).Err()
+}
+
 func (r *Redis) Close() {
 	r.locker.Lock()
 	defer r.locker.Unlock()
@@ -21,7 +21,13 @@ func (attr Attributes) WithError(err error) Attributes {
 		return attr
 	}
 
-	attr["exception.type"] = reflect.TypeOf(err).Elem().String()
+	// Errors such as context.DeadlineExceeded are values, not pointers.
+	errType := reflect.TypeOf(err)
+	if errType.Kind() == reflect.Pointer {
+		errType = errType.Elem()
+	}
+
+	attr["exception.type"] = errType.String()
 	attr["exception.message"] = err.Error()
 
 	if cause := errors.Cause(err); cause != nil {
@@ -2,10 +2,11 @@ package logger
 
 import (
 	"context"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
 	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/tracing"
 	"sync"
 
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -15,6 +16,8 @@ type Option struct {
 	ServiceVersion string
 	Environment    string
 	LogLevel       string
+	// RedactKeys are extra attribute keys whose values are never logged.
+	RedactKeys []string
 }
 
 var (
@@ -26,6 +29,7 @@ var (
 func Init(opt *Option) {
 	once.Do(func() {
 		option = opt
+		redaction = newRedactor(opt.RedactKeys)
 		zapLogger, err := newZap()
 
 		if err != nil {
@@ -108,22 +112,27 @@ func makeAttributes(ctx context.Context, attr attributes.Attributes) []zapcore.F
 		attr = attributes.New()
 	}
 
-	if attr["cid"] == nil {
-		cid := ctx.Value("cid")
-
-		if cid == nil || cid == "" {
-			cid = uuid.New().String()
+	// Ids set explicitly win over the ones the context carries.
+	for key, id := range correlation.Fields(ctx) {
+		if attr[key] == nil {
+			attr[key] = id
 		}
-
-		attr["cid"] = cid
 	}
 
-	return []zapcore.Field{
-		zap.Any("Attributes", attr),
+	fields := []zapcore.Field{
+		zap.Any("Attributes", Redact(attr)),
 		zap.Any("Resource", map[string]interface{}{
 			"service.name":        option.ServiceName,
 			"service.version":     option.ServiceVersion,
 			"service.environment": option.Environment,
 		}),
 	}
+
+	// Logs written inside a span are correlated with it, as in the OTel log
+	// data model.
+	if traceID, spanID := tracing.IDs(ctx); traceID != "" {
+		fields = append(fields, zap.String("TraceId", traceID), zap.String("SpanId", spanID))
+	}
+
+	return fields
 }
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// defaultRedactKeys are attribute and JSON keys whose values are never
+// logged, compared lowercase without separators.
+var defaultRedactKeys = []string{
+	"number", "pan", "cardnumber", "cvv", "cvv2", "cvc", "securitycode",
+	"authorization", "password", "secret", "apikey",
+}
+
+var (
+	// panPattern finds runs of 13 to 19 digits, optionally grouped by spaces
+	// or dashes. Only runs passing the Luhn check are masked.
+	panPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	// cvvPattern finds security codes written next to their name, as in
+	// `"cvv":"123"` or `cvc=1234`.
+	cvvPattern = regexp.MustCompile(`(?i)("?(?:cvv2?|cvc|security_?code)"?\s*[:=]\s*"?)\d{3,4}`)
+)
+
+// redactor scrubs card data and secrets from attributes before they are
+// encoded, whatever shape the values have.
+type redactor struct {
+	keys map[string]bool
+}
+
+var redaction = newRedactor(nil)
+
+func newRedactor(extraKeys []string) *redactor {
+	r := &redactor{keys: make(map[string]bool)}
+	for _, key := range append(defaultRedactKeys, extraKeys...) {
+		r.keys[normalizeKey(key)] = true
+	}
+
+	return r
+}
+
+// Redact returns a copy of attr without card numbers, security codes and the
+// values of sensitive keys. Every log call goes through it.
+func Redact(attr attributes.Attributes) attributes.Attributes {
+	return redaction.attributes(attr)
+}
+
+func (r *redactor) attributes(attr attributes.Attributes) attributes.Attributes {
+	clean := make(attributes.Attributes, len(attr))
+	for key, value := range attr {
+		clean[key] = r.value(key, value)
+	}
+
+	return clean
+}
+
+func (r *redactor) value(key string, value any) any {
+	if value == nil {
+		return nil
+	}
+
+	if r.keys[normalizeKey(key)] {
+		return redacted
+	}
+
+	switch v := value.(type) {
+	case string:
+		return redactString(v)
+	case error:
+		return redactString(v.Error())
+	case map[string]any:
+		return r.object(v)
+	case attributes.Attributes:
+		return r.object(v)
+	case []any:
+		clean := make([]any, len(v))
+		for i, item := range v {
+			clean[i] = r.value("", item)
+		}
+		return clean
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return value
+	}
+
+	// Structs, slices and pointers are walked through their JSON form, so
+	// their field names can be matched against the redacted keys.
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return redactString(fmt.Sprintf("%+v", value))
+	}
+
+	var decoded any
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		return redactString(string(encoded))
+	}
+
+	return r.value("", decoded)
+}
+
+func (r *redactor) object(object map[string]any) map[string]any {
+	clean := make(map[string]any, len(object))
+	for key, value := range object {
+		clean[key] = r.value(key, value)
+	}
+
+	return clean
+}
+
+// redactString masks card numbers down to their last four digits and drops
+// security codes from free text such as error messages.
+func redactString(value string) string {
+	value = panPattern.ReplaceAllStringFunc(value, func(match string) string {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, match)
+
+		if !luhn(digits) {
+			return match
+		}
+
+		return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+	})
+
+	return cvvPattern.ReplaceAllString(value, "${1}***")
+}
+
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(strings.ToLower(key))
+}
+
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
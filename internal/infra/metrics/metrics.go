@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "api_pay"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	providerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Payment provider call latency, by operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "operation", "outcome"})
+
+	paymentAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_attempts_total",
+		Help:      "Charge attempts, by provider and outcome: succeeded, declined or the error kind.",
+	}, []string{"provider", "outcome"})
+
+	failovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_failovers_total",
+		Help:      "Payments moved on to the next provider after one failed.",
+	}, []string{"provider"})
+
+	refunds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_refunds_total",
+		Help:      "Refunds requested, by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	refundedAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_refunded_amount_total",
+		Help:      "Amount refunded successfully, in major units of the currency.",
+	}, []string{"provider", "currency"})
+
+	breakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "provider_circuit_breaker_state",
+		Help:      "Circuit breaker state of each provider; the current state is 1, the others 0.",
+	}, []string{"provider", "state"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests refused for exceeding a rate limit, by limit.",
+	}, []string{"limit"})
+
+	databasesLock sync.Mutex
+	databases     = make(map[string]prometheus.Collector)
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		providerDuration,
+		paymentAttempts,
+		failovers,
+		refunds,
+		refundedAmount,
+		breakerState,
+		rateLimited,
+	)
+}
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records an HTTP request. Route is the route template, never
+// the raw path, so IDs do not become labels.
+func ObserveRequest(method string, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+func ObserveProviderCall(provider string, operation string, outcome string, duration time.Duration) {
+	providerDuration.WithLabelValues(provider, operation, outcome).Observe(duration.Seconds())
+}
+
+func CountPaymentAttempt(provider string, outcome string) {
+	paymentAttempts.WithLabelValues(provider, outcome).Inc()
+}
+
+// CountFailover records a payment leaving provider for the next one.
+func CountFailover(provider string) {
+	failovers.WithLabelValues(provider).Inc()
+}
+
+func CountRefund(provider string, outcome string) {
+	refunds.WithLabelValues(provider, outcome).Inc()
+}
+
+// AddRefundedAmount adds a successful refund to the refunded volume.
+func AddRefundedAmount(provider string, currency string, amount float64) {
+	refundedAmount.WithLabelValues(provider, currency).Add(amount)
+}
+
+// SetBreakerState records the current state of a provider's circuit breaker
+// among every possible state.
+func SetBreakerState(provider string, state string, states ...string) {
+	for _, candidate := range states {
+		value := 0.0
+		if candidate == state {
+			value = 1
+		}
+
+		breakerState.WithLabelValues(provider, candidate).Set(value)
+	}
+}
+
+// CountRateLimited records a request refused by the named rate limit.
+func CountRateLimited(limit string) {
+	rateLimited.WithLabelValues(limit).Inc()
+}
+
+// RegisterDatabase exposes the connection pool stats of db under name,
+// replacing the pool registered before under the same name.
+func RegisterDatabase(name string, db *sql.DB) {
+	databasesLock.Lock()
+	defer databasesLock.Unlock()
+
+	if previous, ok := databases[name]; ok {
+		registry.Unregister(previous)
+	}
+
+	collector := collectors.NewDBStatsCollector(db, name)
+	registry.MustRegister(collector)
+	databases[name] = collector
+}
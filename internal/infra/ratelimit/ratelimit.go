@@ -0,0 +1,232 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultRetryBackoff    = time.Second
+	DefaultMaxRetryBackoff = 30 * time.Second
+
+	keyPrefix = "ratelimit:"
+	// sweepEvery is how many requests the in-memory limiter counts between
+	// two sweeps of the keys whose window went empty.
+	sweepEvery = 1024
+)
+
+// slidingWindowScript keeps the timestamps of the requests of the window in
+// a sorted set and admits the request when fewer than the limit remain. It
+// returns whether it was admitted, the requests in the window and the
+// timestamp of the oldest one.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+
+redis.call("PEXPIRE", KEYS[1], window)
+
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {allowed, count, tonumber(oldest[2] or now)}
+`)
+
+// Result is the state of a limit after a request was counted against it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until a request is admitted again when it was not,
+	// or until the window is empty otherwise.
+	Reset time.Duration
+}
+
+// Limiter counts requests per key in a sliding window shared through Redis.
+// While Redis is unavailable each instance limits on its own in memory, so
+// limits hold per instance rather than not at all.
+type Limiter struct {
+	redis    *database.Redis
+	fallback *memoryWindows
+	health   redisHealth
+	// RetryBackoff is how long requests are limited in memory after Redis
+	// failed, before one of them tries it again. It doubles on each failure
+	// up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+}
+
+func New(redis *database.Redis) *Limiter {
+	return &Limiter{
+		redis:           redis,
+		fallback:        &memoryWindows{windows: make(map[string]*memoryWindow)},
+		RetryBackoff:    DefaultRetryBackoff,
+		MaxRetryBackoff: DefaultMaxRetryBackoff,
+	}
+}
+
+// Allow counts a request against key, at most limit in every window.
+func (l *Limiter) Allow(ctx context.Context, key string, limit int, window time.Duration) Result {
+	now := time.Now()
+
+	if !l.health.allow(now) {
+		return l.fallback.allow(key, limit, window, now)
+	}
+
+	result, err := l.allowInRedis(ctx, key, limit, window, now)
+	// The backoff runs from the failure, which may have taken a while.
+	if l.health.record(err, time.Now(), l.RetryBackoff, l.MaxRetryBackoff) {
+		logger.Info(ctx, "Redis is back, rate limiting in Redis", nil)
+	}
+
+	if err != nil {
+		logger.Warn(ctx, "Error rate limiting in Redis, limiting in memory", attributes.Attributes{"key": key}.WithError(err))
+		return l.fallback.allow(key, limit, window, now)
+	}
+
+	return result
+}
+
+func (l *Limiter) allowInRedis(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (Result, error) {
+	rdb, err := l.redis.TryConnection()
+	if err != nil {
+		return Result{}, err
+	}
+
+	reply, err := slidingWindowScript.Run(ctx, rdb, []string{keyPrefix + key},
+		now.UnixMicro(), window.Microseconds(), limit, strconv.FormatInt(now.UnixMicro(), 10)+":"+uuid.New().String()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	oldest := time.UnixMicro(reply[2])
+	return newResult(reply[0] == 1, limit, int(reply[1]), oldest.Add(window).Sub(now)), nil
+}
+
+// redisHealth keeps requests off Redis once it failed, so they are limited in
+// memory right away instead of each waiting on a reconnection. When the
+// backoff elapsed a single request tries Redis again.
+type redisHealth struct {
+	mu       sync.Mutex
+	failures int
+	retryAt  time.Time
+	trial    bool
+}
+
+// allow reports whether the request may use Redis now.
+func (h *redisHealth) allow(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failures == 0 {
+		return true
+	}
+
+	if h.trial || now.Before(h.retryAt) {
+		return false
+	}
+
+	h.trial = true
+	return true
+}
+
+// record feeds the outcome of a request allowed by allow back, and reports
+// whether it was the first success after failures.
+func (h *redisHealth) record(err error, now time.Time, backoff time.Duration, maxBackoff time.Duration) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.trial = false
+	if err == nil {
+		recovered := h.failures > 0
+		h.failures = 0
+		return recovered
+	}
+
+	h.failures++
+	for i := 1; i < h.failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	h.retryAt = now.Add(min(backoff, maxBackoff))
+	return false
+}
+
+// memoryWindows are the in-process sliding windows used while Redis is down.
+type memoryWindows struct {
+	mu      sync.Mutex
+	windows map[string]*memoryWindow
+	calls   int
+}
+
+type memoryWindow struct {
+	requests []time.Time
+	window   time.Duration
+}
+
+func (m *memoryWindows) allow(key string, limit int, window time.Duration, now time.Time) Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.calls++; m.calls%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	entry, ok := m.windows[key]
+	if !ok {
+		entry = &memoryWindow{window: window}
+		m.windows[key] = entry
+	}
+
+	entry.expire(now)
+
+	allowed := len(entry.requests) < limit
+	if allowed {
+		entry.requests = append(entry.requests, now)
+	}
+
+	return newResult(allowed, limit, len(entry.requests), entry.requests[0].Add(window).Sub(now))
+}
+
+// sweep drops the windows without requests left, so keys seen once do not
+// pile up.
+func (m *memoryWindows) sweep(now time.Time) {
+	for key, entry := range m.windows {
+		if entry.expire(now); len(entry.requests) == 0 {
+			delete(m.windows, key)
+		}
+	}
+}
+
+func (w *memoryWindow) expire(now time.Time) {
+	start := now.Add(-w.window)
+	for len(w.requests) > 0 && !w.requests[0].After(start) {
+		w.requests = w.requests[1:]
+	}
+}
+
+func newResult(allowed bool, limit int, count int, reset time.Duration) Result {
+	return Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: max(limit-count, 0),
+		Reset:     max(reset, 0),
+	}
+}
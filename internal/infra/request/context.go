@@ -3,13 +3,13 @@ package request
 import (
 	"context"
 
-	"github.com/google/uuid"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+
 	"github.com/labstack/echo/v4"
 )
 
 const (
 	contextKey          ContextKey = "request-context"
-	headerCid           string     = "x-cid"
 	headerAuthorization string     = "authorization"
 )
 
@@ -30,9 +30,29 @@ func GetContext(c echo.Context) *Context {
 	return c.Request().Context().Value(contextKey).(*Context)
 }
 
+// CID returns the correlation id of the request, generating one when the
+// client sent none.
+func CID(c echo.Context) string {
+	if cid := correlation.CID(c.Request().Context()); cid != "" {
+		return cid
+	}
+
+	return extractCid(c)
+}
+
+// Authorization returns the Authorization header captured for the request.
+func Authorization(c echo.Context) string {
+	if rctx, ok := c.Request().Context().Value(contextKey).(*Context); ok {
+		return rctx.Authorization
+	}
+
+	return extractAuthorization(c)
+}
+
 func buildContext(c echo.Context) *Context {
 	rctx := extractContext(c)
 	ctx := context.WithValue(c.Request().Context(), contextKey, rctx)
+	ctx = correlation.WithCID(ctx, rctx.CID)
 	c.SetRequest(c.Request().WithContext(ctx))
 
 	return rctx
@@ -48,11 +68,11 @@ func extractContext(c echo.Context) *Context {
 }
 
 func extractCid(c echo.Context) string {
-	cid := c.Request().Header.Get(headerCid)
+	cid := correlation.Extract(c.Request().Header)
 
 	if len(cid) == 0 {
-		cid = uuid.New().String()
-		c.Request().Header.Set(headerCid, cid)
+		cid = correlation.NewCID()
+		c.Request().Header.Set(correlation.HeaderCID, cid)
 	}
 
 	return cid
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/request"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPErrorHandler renders every error returned by handlers and middlewares
+// as an apperror.Response carrying the request's correlation id. Errors
+// without an API error are logged and shown as internal errors.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	apiErr := toAPIError(err)
+	cid := request.CID(c)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		logger.Error(c.Request().Context(), "Request failed", attributes.Attributes{
+			"cid":        cid,
+			"error.code": apiErr.Code,
+			"uri":        c.Request().RequestURI,
+		}.WithError(err))
+	}
+
+	c.Response().Header().Set(correlation.HeaderCID, cid)
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(apiErr.Status)
+	} else {
+		err = c.JSON(apiErr.Status, apperror.NewResponse(apiErr, cid))
+	}
+
+	if err != nil {
+		logger.Error(c.Request().Context(), "Error writing error response", attributes.New().WithError(err))
+	}
+}
+
+func toAPIError(err error) *apperror.Error {
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) {
+		return apperror.From(err)
+	}
+
+	switch httpErr.Code {
+	case http.StatusNotFound:
+		return apperror.ErrRouteNotFound.Wrap(err)
+	case http.StatusMethodNotAllowed:
+		return apperror.ErrMethodNotAllowed.Wrap(err)
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType:
+		return apperror.ErrMalformedRequest.Wrap(err)
+	default:
+		return apperror.New(httpErr.Code, "http_error", http.StatusText(httpErr.Code)).Wrap(err)
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"lucassaraiva5/api-pay/internal/infra/request"
+
+	"github.com/labstack/echo/v4"
+)
+
+const bearerScheme = "bearer "
+
+// Authenticator resolves the key of a request into the context it is handled
+// in, or refuses it.
+type Authenticator func(ctx context.Context, key string) (context.Context, error)
+
+// ConfigAuthentication middleware authenticates the `Authorization: Bearer`
+// key of the request with authenticate and handles the request in the
+// context it returns.
+func ConfigAuthentication(authenticate Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, err := authenticate(c.Request().Context(), bearerKey(c))
+			if err != nil {
+				return err
+			}
+
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+func bearerKey(c echo.Context) string {
+	authorization := request.Authorization(c)
+
+	if len(authorization) < len(bearerScheme) || !strings.EqualFold(authorization[:len(bearerScheme)], bearerScheme) {
+		return ""
+	}
+
+	return strings.TrimSpace(authorization[len(bearerScheme):])
+}
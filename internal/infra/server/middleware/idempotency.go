@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/variables"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+
+	idempotencyKeyPrefix  = "idempotency:"
+	idempotencyLockSuffix = ":lock"
+	maxIdempotencyKeySize = 255
+)
+
+var (
+	errIdempotencyKeyTooLong       = apperror.New(http.StatusBadRequest, "idempotency_key_too_long", "idempotency key is too long")
+	errIdempotencyKeyReused        = apperror.New(http.StatusUnprocessableEntity, "idempotency_key_reused", "idempotency key reused with a different request")
+	errIdempotencyInProgress       = apperror.NewRetryable(http.StatusConflict, "idempotency_in_progress", "a request with this idempotency key is already in progress")
+	errIdempotencyStoreUnavailable = apperror.NewRetryable(http.StatusServiceUnavailable, "idempotency_unavailable", "idempotency store unavailable")
+)
+
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type (
+	IdempotencyConfig struct {
+		Redis   *database.Redis
+		TTL     time.Duration
+		LockTTL time.Duration
+		// Namespace keeps the keys of different callers apart, so two
+		// merchants sending the same key never see each other's response.
+		Namespace func(ctx context.Context) string
+	}
+
+	idempotencyRecord struct {
+		Fingerprint string `json:"fingerprint"`
+		Status      int    `json:"status"`
+		ContentType string `json:"contentType"`
+		Body        []byte `json:"body"`
+	}
+
+	responseRecorder struct {
+		http.ResponseWriter
+		body bytes.Buffer
+	}
+)
+
+// ConfigIdempotency middleware makes unsafe requests carrying an
+// `Idempotency-Key` header safe to retry: the first response is stored in
+// Redis and replayed for later requests with the same key and body within
+// the same namespace.
+func ConfigIdempotency(redis *database.Redis, namespace func(ctx context.Context) string) echo.MiddlewareFunc {
+	return IdempotencyWithConfig(IdempotencyConfig{
+		Redis:     redis,
+		TTL:       variables.IdempotencyTTL(),
+		LockTTL:   variables.IdempotencyLockTTL(),
+		Namespace: namespace,
+	})
+}
+
+func IdempotencyWithConfig(config IdempotencyConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if len(key) == 0 {
+				return next(c)
+			}
+
+			if len(key) > maxIdempotencyKeySize {
+				return errIdempotencyKeyTooLong
+			}
+
+			fingerprint, err := requestFingerprint(c.Request())
+			if err != nil {
+				return apperror.ErrMalformedRequest.Wrap(err)
+			}
+
+			ctx := c.Request().Context()
+			recordKey := idempotencyKeyPrefix + key
+			if config.Namespace != nil {
+				recordKey = idempotencyKeyPrefix + config.Namespace(ctx) + ":" + key
+			}
+			lockKey := recordKey + idempotencyLockSuffix
+
+			rdb, err := config.Redis.TryConnection()
+			if err != nil {
+				logger.Error(ctx, "Error connecting to idempotency store", attributes.Attributes{"idempotency_key": key}.WithError(err))
+				return errIdempotencyStoreUnavailable.Wrap(err)
+			}
+
+			if record, err := loadIdempotencyRecord(ctx, rdb, recordKey); err != nil {
+				logger.Error(ctx, "Error loading idempotency record", attributes.Attributes{"idempotency_key": key}.WithError(err))
+				return errIdempotencyStoreUnavailable.Wrap(err)
+			} else if record != nil {
+				return replayIdempotencyRecord(c, record, fingerprint)
+			}
+
+			// The lock value carries a per-request token next to the
+			// fingerprint so only its owner can release it.
+			lockValue := fingerprint + ":" + uuid.New().String()
+			acquired, err := rdb.SetNX(ctx, lockKey, lockValue, config.LockTTL).Result()
+			if err != nil {
+				logger.Error(ctx, "Error acquiring idempotency lock", attributes.Attributes{"idempotency_key": key}.WithError(err))
+				return errIdempotencyStoreUnavailable.Wrap(err)
+			}
+
+			if !acquired {
+				if owner, _ := rdb.Get(ctx, lockKey).Result(); owner != "" && !strings.HasPrefix(owner, fingerprint+":") {
+					return errIdempotencyKeyReused
+				}
+
+				return errIdempotencyInProgress
+			}
+
+			// The request context may already be cancelled by the timeout
+			// middleware, but the outcome still has to be stored.
+			defer releaseIdempotencyLock(rdb, lockKey, lockValue)
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				return nil
+			}
+
+			record := &idempotencyRecord{
+				Fingerprint: fingerprint,
+				Status:      status,
+				ContentType: c.Response().Header().Get(echo.HeaderContentType),
+				Body:        recorder.body.Bytes(),
+			}
+
+			if err = storeIdempotencyRecord(context.Background(), rdb, recordKey, record, config.TTL); err != nil {
+				logger.Error(ctx, "Error storing idempotency record", attributes.Attributes{"idempotency_key": key}.WithError(err))
+			}
+
+			return nil
+		}
+	}
+}
+
+// releaseIdempotencyLock deletes the lock only while it still holds this
+// request's value, so a request that outlived LockTTL cannot release the lock
+// taken by a retry.
+func releaseIdempotencyLock(rdb *redis.Client, lockKey string, lockValue string) {
+	if err := releaseLockScript.Run(context.Background(), rdb, []string{lockKey}, lockValue).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		logger.Error(context.Background(), "Error releasing idempotency lock", attributes.Attributes{"lock_key": lockKey}.WithError(err))
+	}
+}
+
+func requestFingerprint(req *http.Request) (string, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func loadIdempotencyRecord(ctx context.Context, rdb *redis.Client, key string) (*idempotencyRecord, error) {
+	value, err := rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := &idempotencyRecord{}
+	if err = json.Unmarshal(value, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func storeIdempotencyRecord(ctx context.Context, rdb *redis.Client, key string, record *idempotencyRecord, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return rdb.Set(ctx, key, value, ttl).Err()
+}
+
+func replayIdempotencyRecord(c echo.Context, record *idempotencyRecord, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return errIdempotencyKeyReused
+	}
+
+	c.Response().Header().Set(HeaderIdempotencyReplayed, "true")
+
+	if len(record.ContentType) == 0 {
+		return c.NoContent(record.Status)
+	}
+
+	return c.Blob(record.Status, record.ContentType, record.Body)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
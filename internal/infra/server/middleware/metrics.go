@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"time"
+
+	"lucassaraiva5/api-pay/internal/infra/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests no route matched, keeping unknown paths
+// out of the metric labels.
+const unmatchedRoute = "unmatched"
+
+// ConfigMetrics middleware records the count and latency of every request
+// by route template and status.
+func ConfigMetrics() echo.MiddlewareFunc {
+	return newMetrics
+}
+
+func newMetrics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		err := next(c)
+		if err != nil {
+			// Render the error now so the status it maps to is the one recorded.
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		metrics.ObserveRequest(c.Request().Method, route, c.Response().Status, time.Since(start))
+
+		return nil
+	}
+}
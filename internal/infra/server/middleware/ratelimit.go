@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/metrics"
+	"lucassaraiva5/api-pay/internal/infra/ratelimit"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+var errRateLimited = apperror.NewRetryable(http.StatusTooManyRequests, "rate_limited", "too many requests, retry later")
+
+type (
+	// RateLimit admits at most Limit requests with the same key in every
+	// Window; a Limit of 0 disables it.
+	RateLimit struct {
+		Name   string
+		Limit  int
+		Window time.Duration
+		// Key returns what the request is counted against, or "" to leave
+		// the request out of this limit.
+		Key func(c echo.Context) (string, error)
+	}
+
+	RateLimitConfig struct {
+		Limiter *ratelimit.Limiter
+		Limits  []RateLimit
+	}
+)
+
+// ConfigRateLimit middleware refuses requests over any of the limits with a
+// 429 and a `Retry-After` header. Every response carries the
+// `X-RateLimit-*` headers of the limit closest to being exceeded.
+func ConfigRateLimit(redis *database.Redis, limits ...RateLimit) echo.MiddlewareFunc {
+	return RateLimitWithConfig(RateLimitConfig{
+		Limiter: ratelimit.New(redis),
+		Limits:  limits,
+	})
+}
+
+func RateLimitWithConfig(config RateLimitConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+
+			var closest *ratelimit.Result
+			for _, limit := range config.Limits {
+				if limit.Limit <= 0 {
+					continue
+				}
+
+				key, err := limit.Key(c)
+				if err != nil {
+					return err
+				}
+
+				if key == "" {
+					continue
+				}
+
+				result := config.Limiter.Allow(ctx, limit.Name+":"+key, limit.Limit, limit.Window)
+				if !result.Allowed {
+					setRateLimitHeaders(c, result)
+					c.Response().Header().Set(HeaderRetryAfter, strconv.Itoa(seconds(result.Reset)))
+
+					metrics.CountRateLimited(limit.Name)
+					logger.Info(ctx, "Request rate limited", attributes.Attributes{"limit": limit.Name, "uri": c.Request().RequestURI})
+					return errRateLimited
+				}
+
+				if closest == nil || result.Remaining < closest.Remaining {
+					closest = &result
+				}
+			}
+
+			if closest != nil {
+				setRateLimitHeaders(c, *closest)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func setRateLimitHeaders(c echo.Context, result ratelimit.Result) {
+	header := c.Response().Header()
+	header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+	header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+	header.Set(HeaderRateLimitReset, strconv.Itoa(seconds(result.Reset)))
+}
+
+// seconds rounds d up, so clients waiting for it are never early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
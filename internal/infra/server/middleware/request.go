@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"lucassaraiva5/api-pay/internal/infra/correlation"
 	"lucassaraiva5/api-pay/internal/infra/logger"
 	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
 	"lucassaraiva5/api-pay/internal/infra/request"
@@ -21,7 +22,8 @@ func newRequest(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		start := time.Now()
 
-		_ = request.BuildContext(c)
+		rctx := request.BuildContext(c)
+		c.Response().Header().Set(correlation.HeaderCID, rctx.CID)
 
 		response := next(c)
 		elapsed := time.Since(start)
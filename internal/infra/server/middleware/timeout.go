@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"time"
 
+	"lucassaraiva5/api-pay/internal/infra/apperror"
 	"lucassaraiva5/api-pay/internal/infra/logger"
 	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
 	"lucassaraiva5/api-pay/internal/infra/variables"
@@ -11,20 +15,28 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 )
 
+var errRequestTimeout = apperror.NewRetryable(http.StatusServiceUnavailable, "request_timeout", "request timed out")
+
 var (
-	DefaultTimeoutConfig = middleware.TimeoutConfig{
-		Skipper:      middleware.DefaultSkipper,
-		ErrorMessage: "{\"error\":\"Request Timeout\"}",
-		OnTimeoutRouteErrorHandler: func(err error, c echo.Context) {
+	DefaultTimeoutConfig = middleware.ContextTimeoutConfig{
+		Skipper: middleware.DefaultSkipper,
+		ErrorHandler: func(err error, c echo.Context) error {
+			if !errors.Is(c.Request().Context().Err(), context.DeadlineExceeded) || !errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+
 			logger.Warn(c.Request().Context(), "Request Timeout", attributes.Attributes{
 				"uri": c.Request().RequestURI,
 			}.WithError(err))
+			return errRequestTimeout.Wrap(err)
 		},
 		Timeout: time.Second * time.Duration(variables.ServerTimeout()),
 	}
 )
 
-// ConfigTimeout middleware adds a `timeout`
+// ConfigTimeout middleware bounds every request with a deadline on its
+// context. Handlers stopped by it fail with a `request_timeout` error,
+// rendered by the error handler like any other.
 func ConfigTimeout() echo.MiddlewareFunc {
-	return middleware.TimeoutWithConfig(DefaultTimeoutConfig)
+	return middleware.ContextTimeoutWithConfig(DefaultTimeoutConfig)
 }
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"lucassaraiva5/api-pay/internal/infra/tracing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ConfigTracing middleware starts a server span per request, continuing the
+// trace of the caller when it sends a traceparent header.
+func ConfigTracing() echo.MiddlewareFunc {
+	return newTracing
+}
+
+func newTracing(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+
+		route := c.Path()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx := tracing.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracing.Start(ctx, req.Method+" "+route, trace.SpanKindServer,
+			attribute.String("http.request.method", req.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", req.URL.Path),
+		)
+		defer span.End()
+
+		c.SetRequest(req.WithContext(ctx))
+
+		if err := next(c); err != nil {
+			// Render the error now so the span carries the status it maps to.
+			c.Error(err)
+		}
+
+		status := c.Response().Status
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		return nil
+	}
+}
@@ -1,9 +1,13 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
 	"lucassaraiva5/api-pay/internal/infra/server/middleware"
 	"lucassaraiva5/api-pay/internal/infra/variables"
+	"net"
 
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
@@ -11,10 +15,18 @@ import (
 
 func New() (e *echo.Echo) {
 	e = echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.IPExtractor = IPExtractor(variables.TrustedProxies())
+
+	// Configure tracing
+	e.Use(middleware.ConfigTracing())
 
 	// Configure request
 	e.Use(middleware.ConfigRequest())
 
+	// Configure metrics
+	e.Use(middleware.ConfigMetrics())
+
 	// Configure cors
 	e.Use(middleware.ConfigCors())
 
@@ -28,3 +40,24 @@ func New() (e *echo.Echo) {
 
 	return e
 }
+
+// IPExtractor reads the client IP from X-Forwarded-For when the request came
+// through one of the trusted proxy ranges, and from the connection otherwise,
+// so clients cannot choose the IP they are rate limited by.
+func IPExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			logger.Fatal(context.Background(), "Invalid trusted proxy range", attributes.Attributes{"proxy": proxy}.WithError(err))
+		}
+
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const instrumentation = "lucassaraiva5/api-pay"
+
+type Option struct {
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	// Exporter is where spans are sent: none, stdout or otlp.
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector URL, used by the otlp exporter.
+	Endpoint string
+	// SampleRatio is the fraction of new traces recorded; a sampled parent
+	// is always followed.
+	SampleRatio float64
+}
+
+var provider *sdktrace.TracerProvider
+
+// Init installs the global tracer provider and the W3C trace context
+// propagator. With the none exporter spans are still created, so trace IDs
+// reach the logs and provider calls, but never exported.
+func Init(opt *Option) error {
+	exporter, err := newExporter(opt)
+	if err != nil {
+		return err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opt.ServiceName),
+		semconv.ServiceVersion(opt.ServiceVersion),
+		semconv.DeploymentEnvironment(opt.Environment),
+	))
+	if err != nil {
+		return err
+	}
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opt.SampleRatio))),
+	}
+
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	provider = sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return nil
+}
+
+// Shutdown flushes the spans still buffered and stops exporting.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+
+	return provider.Shutdown(ctx)
+}
+
+func newExporter(opt *Option) (sdktrace.SpanExporter, error) {
+	switch opt.Exporter {
+	case ExporterNone, "":
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		return otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(opt.Endpoint))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opt.Exporter)
+	}
+}
+
+// Start starts a span named name as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed with err when there is one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Inject writes the trace context of ctx into the headers of an outgoing
+// request.
+func Inject(ctx context.Context, header propagation.HeaderCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, header)
+}
+
+// Extract returns ctx with the trace context of an incoming request.
+func Extract(ctx context.Context, header propagation.HeaderCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, header)
+}
+
+// IDs returns the trace and span IDs of the span in ctx, empty when ctx
+// carries none.
+func IDs(ctx context.Context) (traceID string, spanID string) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return "", ""
+	}
+
+	return spanContext.TraceID().String(), spanContext.SpanID().String()
+}
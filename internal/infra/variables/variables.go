@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"lucassaraiva5/api-pay/internal/infra/logger"
@@ -22,9 +23,11 @@ var (
 	environment                  = &variable{key: "ENVIRONMENT", defaultValue: "local"}
 	isLambda                     = &variable{key: "LAMBDA", defaultValue: "false"}
 	logLevel                     = &variable{key: "LOG_LEVEL", defaultValue: "debug"}
+	logRedactKeys                = &variable{key: "LOG_REDACT_KEYS", defaultValue: ""}
 	serverHost                   = &variable{key: "SERVER_HOST", defaultValue: "0.0.0.0"}
 	serverPort                   = &variable{key: "SERVER_PORT", defaultValue: "8088"}
 	serverTimeout                = &variable{key: "SERVER_TIMEOUT", defaultValue: "30"}
+	trustedProxies               = &variable{key: "TRUSTED_PROXIES", defaultValue: ""}
 	dbReadHost                   = &variable{key: "DB_READ_HOST", defaultValue: "localhost"}
 	dbReadPort                   = &variable{key: "DB_READ_PORT", defaultValue: "5432"}
 	dbReadName                   = &variable{key: "DB_READ_NAME", defaultValue: "go-project-template"}
@@ -50,6 +53,51 @@ var (
 	redisPassword                = &variable{key: "REDIS_PASSWORD", defaultValue: ""}
 	redisDB                      = &variable{key: "REDIS_DB", defaultValue: "1"}
 	redisLazyConnection          = &variable{key: "REDIS_LAZY_CONNECTION", defaultValue: "true"}
+	paymentProviders             = &variable{key: "PAYMENT_PROVIDERS", defaultValue: "paypal,stripe"}
+	idempotencyTTL               = &variable{key: "IDEMPOTENCY_TTL", defaultValue: "86400"}
+	idempotencyLockTTL           = &variable{key: "IDEMPOTENCY_LOCK_TTL", defaultValue: "60"}
+	failoverOn                   = &variable{key: "FAILOVER_ON", defaultValue: "network"}
+	routingConfig                = &variable{key: "ROUTING_CONFIG", defaultValue: ""}
+	authorizationTTL             = &variable{key: "AUTHORIZATION_TTL", defaultValue: "604800"}
+	vaultKeys                    = &variable{key: "VAULT_KEYS", defaultValue: ""}
+	vaultFingerprintKey          = &variable{key: "VAULT_FINGERPRINT_KEY", defaultValue: ""}
+	vaultCVVTTL                  = &variable{key: "VAULT_CVV_TTL", defaultValue: "900"}
+	paypalTimeout                = &variable{key: "PAYPAL_TIMEOUT", defaultValue: "10"}
+	stripeTimeout                = &variable{key: "STRIPE_TIMEOUT", defaultValue: "10"}
+	paypalWebhookSecret          = &variable{key: "PAYPAL_WEBHOOK_SECRET", defaultValue: ""}
+	paypalWebhookID              = &variable{key: "PAYPAL_WEBHOOK_ID", defaultValue: ""}
+	stripeWebhookSecret          = &variable{key: "STRIPE_WEBHOOK_SECRET", defaultValue: ""}
+	breakerWindowSize            = &variable{key: "BREAKER_WINDOW_SIZE", defaultValue: "20"}
+	breakerMinimumCalls          = &variable{key: "BREAKER_MINIMUM_CALLS", defaultValue: "5"}
+	breakerFailureRate           = &variable{key: "BREAKER_FAILURE_RATE", defaultValue: "50"}
+	breakerSlowCallRate          = &variable{key: "BREAKER_SLOW_CALL_RATE", defaultValue: "80"}
+	breakerSlowCall              = &variable{key: "BREAKER_SLOW_CALL_MS", defaultValue: "5000"}
+	breakerOpenTimeout           = &variable{key: "BREAKER_OPEN_TIMEOUT", defaultValue: "30"}
+	healthProbeInterval          = &variable{key: "HEALTH_PROBE_INTERVAL", defaultValue: "5"}
+	healthProbeTimeout           = &variable{key: "HEALTH_PROBE_TIMEOUT", defaultValue: "2"}
+	healthCheckTimeout           = &variable{key: "HEALTH_CHECK_TIMEOUT", defaultValue: "2"}
+	healthRequired               = &variable{key: "HEALTH_REQUIRED", defaultValue: "read_db,write_db"}
+	reconciliationInterval       = &variable{key: "RECONCILIATION_INTERVAL", defaultValue: "60"}
+	reconciliationDelay          = &variable{key: "RECONCILIATION_DELAY", defaultValue: "60"}
+	reconciliationBatchSize      = &variable{key: "RECONCILIATION_BATCH_SIZE", defaultValue: "100"}
+	webhookInterval              = &variable{key: "WEBHOOK_INTERVAL", defaultValue: "5"}
+	webhookBatchSize             = &variable{key: "WEBHOOK_BATCH_SIZE", defaultValue: "50"}
+	webhookTimeout               = &variable{key: "WEBHOOK_TIMEOUT", defaultValue: "10"}
+	webhookMaxAttempts           = &variable{key: "WEBHOOK_MAX_ATTEMPTS", defaultValue: "8"}
+	webhookBackoff               = &variable{key: "WEBHOOK_BACKOFF", defaultValue: "30"}
+	webhookMaxBackoff            = &variable{key: "WEBHOOK_MAX_BACKOFF", defaultValue: "21600"}
+	tracingExporter              = &variable{key: "TRACING_EXPORTER", defaultValue: "none"}
+	tracingEndpoint              = &variable{key: "TRACING_ENDPOINT", defaultValue: "http://localhost:4318"}
+	tracingSampleRate            = &variable{key: "TRACING_SAMPLE_RATE", defaultValue: "100"}
+	adminAPIKey                  = &variable{key: "ADMIN_API_KEY", defaultValue: ""}
+	providerAccountCacheTTL      = &variable{key: "PROVIDER_ACCOUNT_CACHE_TTL", defaultValue: "300"}
+	stripeAccountHosts           = &variable{key: "STRIPE_ACCOUNT_HOSTS", defaultValue: ""}
+	paypalAccountHosts           = &variable{key: "PAYPAL_ACCOUNT_HOSTS", defaultValue: ""}
+	rateLimitWindow              = &variable{key: "RATE_LIMIT_WINDOW", defaultValue: "60"}
+	rateLimitMerchant            = &variable{key: "RATE_LIMIT_MERCHANT", defaultValue: "600"}
+	rateLimitIP                  = &variable{key: "RATE_LIMIT_IP", defaultValue: "300"}
+	rateLimitCard                = &variable{key: "RATE_LIMIT_CARD", defaultValue: "10"}
+	rateLimitCardWindow          = &variable{key: "RATE_LIMIT_CARD_WINDOW", defaultValue: "3600"}
 )
 
 func ServiceName() string {
@@ -72,6 +120,12 @@ func LogLevel() string {
 	return get(logLevel)
 }
 
+// LogRedactKeys lists attribute keys, besides card data and credentials,
+// whose values are never logged.
+func LogRedactKeys() []string {
+	return getList(logRedactKeys)
+}
+
 func ServerHost() string {
 	return get(serverHost)
 }
@@ -84,6 +138,12 @@ func ServerTimeout() int {
 	return getInt(serverTimeout)
 }
 
+// TrustedProxies are the CIDR ranges of the proxies whose X-Forwarded-For
+// header is believed. Empty uses the address of the connection.
+func TrustedProxies() []string {
+	return getList(trustedProxies)
+}
+
 func DBReadHost() string {
 	return get(dbReadHost)
 }
@@ -184,6 +244,216 @@ func RedisLazyConnection() bool {
 	return getBool(redisLazyConnection)
 }
 
+func PaymentProviders() []string {
+	return getList(paymentProviders)
+}
+
+func IdempotencyTTL() time.Duration {
+	return time.Second * time.Duration(getInt(idempotencyTTL))
+}
+
+func IdempotencyLockTTL() time.Duration {
+	return time.Second * time.Duration(getInt(idempotencyLockTTL))
+}
+
+func FailoverOn() []string {
+	return getList(failoverOn)
+}
+
+// RoutingConfig is the path of the JSON routing rules file. Empty keeps the
+// PAYMENT_PROVIDERS order for every payment.
+func RoutingConfig() string {
+	return get(routingConfig)
+}
+
+// AuthorizationTTL is how long an authorization may wait for capture before
+// it is expired.
+func AuthorizationTTL() time.Duration {
+	return time.Second * time.Duration(getInt(authorizationTTL))
+}
+
+// VaultKeys lists the card encryption keys as id:base64 pairs, the first one
+// being the key new cards are encrypted with.
+func VaultKeys() []string {
+	return getList(vaultKeys)
+}
+
+func VaultFingerprintKey() string {
+	return get(vaultFingerprintKey)
+}
+
+func VaultCVVTTL() time.Duration {
+	return time.Second * time.Duration(getInt(vaultCVVTTL))
+}
+
+func PaypalTimeout() time.Duration {
+	return time.Second * time.Duration(getInt(paypalTimeout))
+}
+
+func StripeTimeout() time.Duration {
+	return time.Second * time.Duration(getInt(stripeTimeout))
+}
+
+func PaypalWebhookSecret() string {
+	return get(paypalWebhookSecret)
+}
+
+func PaypalWebhookID() string {
+	return get(paypalWebhookID)
+}
+
+func StripeWebhookSecret() string {
+	return get(stripeWebhookSecret)
+}
+
+func BreakerWindowSize() int {
+	return getInt(breakerWindowSize)
+}
+
+func BreakerMinimumCalls() int {
+	return getInt(breakerMinimumCalls)
+}
+
+// BreakerFailureRate is the failure percentage that opens a breaker.
+func BreakerFailureRate() float64 {
+	return float64(getInt(breakerFailureRate)) / 100
+}
+
+// BreakerSlowCallRate is the slow call percentage that opens a breaker.
+func BreakerSlowCallRate() float64 {
+	return float64(getInt(breakerSlowCallRate)) / 100
+}
+
+func BreakerSlowCall() time.Duration {
+	return time.Millisecond * time.Duration(getInt(breakerSlowCall))
+}
+
+func BreakerOpenTimeout() time.Duration {
+	return time.Second * time.Duration(getInt(breakerOpenTimeout))
+}
+
+func HealthProbeInterval() time.Duration {
+	return time.Second * time.Duration(getInt(healthProbeInterval))
+}
+
+func HealthProbeTimeout() time.Duration {
+	return time.Second * time.Duration(getInt(healthProbeTimeout))
+}
+
+// HealthCheckTimeout bounds each dependency ping of the readiness check.
+func HealthCheckTimeout() time.Duration {
+	return time.Second * time.Duration(getInt(healthCheckTimeout))
+}
+
+// HealthRequired names the dependencies that must be up for the service to
+// be ready: read_db, write_db, redis or a provider name.
+func HealthRequired() []string {
+	return getList(healthRequired)
+}
+
+func ReconciliationInterval() time.Duration {
+	return time.Second * time.Duration(getInt(reconciliationInterval))
+}
+
+func ReconciliationDelay() time.Duration {
+	return time.Second * time.Duration(getInt(reconciliationDelay))
+}
+
+func ReconciliationBatchSize() int {
+	return getInt(reconciliationBatchSize)
+}
+
+func WebhookInterval() time.Duration {
+	return time.Second * time.Duration(getInt(webhookInterval))
+}
+
+func WebhookBatchSize() int {
+	return getInt(webhookBatchSize)
+}
+
+func WebhookTimeout() time.Duration {
+	return time.Second * time.Duration(getInt(webhookTimeout))
+}
+
+func WebhookMaxAttempts() int {
+	return getInt(webhookMaxAttempts)
+}
+
+// WebhookBackoff is the wait before retrying a failed delivery, doubled after
+// every further failure up to WebhookMaxBackoff.
+func WebhookBackoff() time.Duration {
+	return time.Second * time.Duration(getInt(webhookBackoff))
+}
+
+func WebhookMaxBackoff() time.Duration {
+	return time.Second * time.Duration(getInt(webhookMaxBackoff))
+}
+
+// TracingExporter is where spans are sent: none, stdout or otlp.
+func TracingExporter() string {
+	return get(tracingExporter)
+}
+
+// TracingEndpoint is the OTLP/HTTP collector URL spans are exported to.
+func TracingEndpoint() string {
+	return get(tracingEndpoint)
+}
+
+// TracingSampleRate is the percentage of new traces recorded.
+func TracingSampleRate() float64 {
+	return float64(getInt(tracingSampleRate)) / 100
+}
+
+// AdminAPIKey authenticates the creation of merchants; empty disables it.
+func AdminAPIKey() string {
+	return get(adminAPIKey)
+}
+
+// ProviderAccountCacheTTL is how long merchants' provider accounts are
+// cached in Redis.
+func ProviderAccountCacheTTL() time.Duration {
+	return time.Second * time.Duration(getInt(providerAccountCacheTTL))
+}
+
+// StripeAccountHosts and PaypalAccountHosts are the hosts merchants' accounts
+// with the provider may set their base URL to; empty keeps every account on
+// the provider default.
+func StripeAccountHosts() []string {
+	return getList(stripeAccountHosts)
+}
+
+func PaypalAccountHosts() []string {
+	return getList(paypalAccountHosts)
+}
+
+// RateLimitWindow is the sliding window the merchant and IP limits count
+// requests in.
+func RateLimitWindow() time.Duration {
+	return time.Second * time.Duration(getInt(rateLimitWindow))
+}
+
+// RateLimitMerchant is how many requests a merchant key may make per
+// window; 0 disables the limit.
+func RateLimitMerchant() int {
+	return getInt(rateLimitMerchant)
+}
+
+// RateLimitIP is how many requests an IP may make per window; 0 disables
+// the limit.
+func RateLimitIP() int {
+	return getInt(rateLimitIP)
+}
+
+// RateLimitCard is how many times a card may be tokenized or charged per
+// card window, against card testing; 0 disables the limit.
+func RateLimitCard() int {
+	return getInt(rateLimitCard)
+}
+
+func RateLimitCardWindow() time.Duration {
+	return time.Second * time.Duration(getInt(rateLimitCardWindow))
+}
+
 func get(env *variable) string {
 	value := os.Getenv(env.key)
 
@@ -194,6 +464,18 @@ func get(env *variable) string {
 	return value
 }
 
+func getList(env *variable) []string {
+	values := make([]string, 0)
+
+	for _, value := range strings.Split(get(env), ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
 func getInt(env *variable) int {
 	value := get(env)
 	intValue, err := strconv.Atoi(value)
@@ -0,0 +1,401 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"hash/crc32"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type Card struct {
+	Number         string `json:"number"`
+	HolderName     string `json:"holderName"`
+	CVV            string `json:"cvv"`
+	ExpirationDate string `json:"expirationDate"`
+	Installments   int    `json:"installments"`
+}
+
+type PaymentMethod struct {
+	Type string `json:"type"`
+	Card Card   `json:"card"`
+}
+
+type CreateChargeRequest struct {
+	Amount        int64         `json:"amount"`
+	Currency      string        `json:"currency"`
+	Description   string        `json:"description"`
+	PaymentMethod PaymentMethod `json:"paymentMethod"`
+	Reference     string        `json:"reference"`
+	// Capture false only authorizes the charge; it defaults to true.
+	Capture *bool `json:"capture"`
+}
+
+type ChargeResponse struct {
+	ID             string `json:"id"`
+	CreatedAt      string `json:"createdAt"`
+	Status         string `json:"status"`
+	OriginalAmount int64  `json:"originalAmount"`
+	CurrentAmount  int64  `json:"currentAmount"`
+	CapturedAmount int64  `json:"capturedAmount"`
+	Currency       string `json:"currency"`
+	Description    string `json:"description"`
+	PaymentMethod  string `json:"paymentMethod"`
+	CardId         string `json:"cardId"`
+	Reference      string `json:"reference,omitempty"`
+}
+
+// Event is the webhook sent to WEBHOOK_URL whenever a charge changes.
+type Event struct {
+	ID         string         `json:"id"`
+	EventType  string         `json:"event_type"`
+	CreateTime string         `json:"create_time"`
+	Resource   ChargeResponse `json:"resource"`
+}
+
+// RefundRequest refunds Amount (minor units) of the charge; zero refunds the
+// remaining balance.
+type RefundRequest struct {
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// CaptureRequest captures Amount (minor units) of an authorization; zero
+// captures all of it.
+type CaptureRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+// VoidRequest releases an authorization. Reason "expired" marks it expired
+// instead of canceled.
+type VoidRequest struct {
+	Reason string `json:"reason"`
+}
+
+type chargeInternal struct {
+	ID             string
+	CreatedAt      string
+	AuthorizedAt   time.Time
+	Status         string
+	OriginalAmount *money.Money
+	CurrentAmount  *money.Money
+	CapturedAmount *money.Money
+	Currency       string
+	Description    string
+	PaymentMethod  string
+	CardId         string
+	Reference      string
+}
+
+const (
+	// declinedCardNumber is always refused, so clients can exercise declines.
+	declinedCardNumber = "4000000000000002"
+	// authorizationTTL is how long an authorization can be captured.
+	authorizationTTL = 7 * 24 * time.Hour
+	// webhookAttempts bounds the sends of a webhook the callback refuses.
+	webhookAttempts = 3
+)
+
+var (
+	charges            = make(map[string]*chargeInternal)
+	chargesByReference = make(map[string]*chargeInternal)
+	chargesMu          sync.Mutex
+)
+
+func generateID() string {
+	return uuid.New().String()
+}
+
+func createChargeHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateChargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.PaymentMethod.Card.Number == declinedCardNumber {
+		http.Error(w, "card declined", http.StatusPaymentRequired)
+		return
+	}
+	chargesMu.Lock()
+	existing, ok := chargesByReference[req.Reference]
+	chargesMu.Unlock()
+	if req.Reference != "" && ok {
+		// Same reference means the client is retrying: answer with the original charge.
+		writeCharge(w, existing)
+		return
+	}
+	id := generateID()
+	cardId := generateID()
+	amount := money.New(req.Amount, req.Currency)
+	charge := &chargeInternal{
+		ID:             id,
+		CreatedAt:      time.Now().Format("2006-01-02"),
+		AuthorizedAt:   time.Now(),
+		Status:         "authorized",
+		OriginalAmount: amount,
+		CurrentAmount:  amount,
+		CapturedAmount: amount,
+		Currency:       req.Currency,
+		Description:    req.Description,
+		PaymentMethod:  req.PaymentMethod.Type,
+		CardId:         cardId,
+		Reference:      req.Reference,
+	}
+	if req.Capture != nil && !*req.Capture {
+		charge.Status = "pending_capture"
+		charge.CurrentAmount = money.New(0, req.Currency)
+		charge.CapturedAmount = money.New(0, req.Currency)
+	}
+	chargesMu.Lock()
+	charges[id] = charge
+	if req.Reference != "" {
+		chargesByReference[req.Reference] = charge
+	}
+	emitEvent("CHARGE.CREATED", charge)
+	chargesMu.Unlock()
+	writeCharge(w, charge)
+}
+
+func findChargeByReferenceHandler(w http.ResponseWriter, r *http.Request) {
+	reference := r.URL.Query().Get("reference")
+	chargesMu.Lock()
+	defer chargesMu.Unlock()
+	charge, ok := chargesByReference[reference]
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeCharge(w, charge)
+}
+
+func writeCharge(w http.ResponseWriter, charge *chargeInternal) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toResponse(charge))
+}
+
+func toResponse(charge *chargeInternal) ChargeResponse {
+	return ChargeResponse{
+		ID:             charge.ID,
+		CreatedAt:      charge.CreatedAt,
+		Status:         charge.Status,
+		OriginalAmount: charge.OriginalAmount.Amount(),
+		CurrentAmount:  charge.CurrentAmount.Amount(),
+		CapturedAmount: charge.CapturedAmount.Amount(),
+		Currency:       charge.Currency,
+		Description:    charge.Description,
+		PaymentMethod:  charge.PaymentMethod,
+		CardId:         charge.CardId,
+		Reference:      charge.Reference,
+	}
+}
+
+// emitEvent posts the charge state to WEBHOOK_URL, signed the way PayPal
+// does, with an HMAC-SHA256 under WEBHOOK_SECRET standing in for PayPal's
+// certificate: the signed message is
+// "<transmission id>|<transmission time>|<WEBHOOK_ID>|<crc32 of body>".
+// Without WEBHOOK_URL no webhook is sent.
+func emitEvent(eventType string, charge *chargeInternal) {
+	url := os.Getenv("WEBHOOK_URL")
+	if url == "" {
+		return
+	}
+	event := Event{
+		ID:         "WH-" + generateID(),
+		EventType:  eventType,
+		CreateTime: time.Now().UTC().Format(time.RFC3339),
+		Resource:   toResponse(charge),
+	}
+	go func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("[ERROR] Webhook %s not encoded: %v", event.ID, err)
+			return
+		}
+		for attempt := 1; attempt <= webhookAttempts; attempt++ {
+			transmissionID := generateID()
+			transmissionTime := time.Now().UTC().Format(time.RFC3339)
+			message := transmissionID + "|" + transmissionTime + "|" + os.Getenv("WEBHOOK_ID") + "|" + strconv.FormatUint(uint64(crc32.ChecksumIEEE(body)), 10)
+			mac := hmac.New(sha256.New, []byte(os.Getenv("WEBHOOK_SECRET")))
+			mac.Write([]byte(message))
+			req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Paypal-Transmission-Id", transmissionID)
+			req.Header.Set("Paypal-Transmission-Time", transmissionTime)
+			req.Header.Set("Paypal-Transmission-Sig", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode < 300 {
+					log.Printf("[INFO] Webhook %s (%s) delivered", event.ID, event.EventType)
+					return
+				}
+				log.Printf("[WARN] Webhook %s refused with status %d", event.ID, resp.StatusCode)
+			} else {
+				log.Printf("[WARN] Webhook %s not delivered: %v", event.ID, err)
+			}
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}()
+}
+
+func refundChargeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+		log.Printf("[ERROR] Invalid refund request for charge %s: %v", id, err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	chargesMu.Lock()
+	defer chargesMu.Unlock()
+	charge, ok := charges[id]
+	if !ok {
+		log.Printf("[ERROR] Refund failed: charge %s not found", id)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if charge.Status == "refunded" {
+		log.Printf("[ERROR] Refund failed: charge %s already refunded", id)
+		http.Error(w, "charge already refunded", http.StatusBadRequest)
+		return
+	}
+	if charge.CapturedAmount.IsZero() {
+		log.Printf("[ERROR] Refund failed: charge %s was not captured", id)
+		http.Error(w, "charge not captured", http.StatusBadRequest)
+		return
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = charge.CurrentAmount.Amount()
+	}
+	if amount < 0 || amount > charge.CurrentAmount.Amount() {
+		log.Printf("[ERROR] Refund failed: amount %d exceeds balance %d of charge %s", amount, charge.CurrentAmount.Amount(), id)
+		http.Error(w, "refund amount exceeds balance", http.StatusBadRequest)
+		return
+	}
+	charge.CurrentAmount = money.New(charge.CurrentAmount.Amount()-amount, charge.Currency)
+	charge.Status = "partially_refunded"
+	if charge.CurrentAmount.IsZero() {
+		charge.Status = "refunded"
+	}
+	log.Printf("[INFO] Refund successful: %d refunded from charge %s (reason: %q)", amount, id, req.Reason)
+	emitEvent("CHARGE.REFUNDED", charge)
+	writeCharge(w, charge)
+}
+
+func getChargeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	chargesMu.Lock()
+	defer chargesMu.Unlock()
+	charge, ok := charges[id]
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeCharge(w, charge)
+}
+
+func captureChargeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var req CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+		log.Printf("[ERROR] Invalid capture request for charge %s: %v", id, err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	chargesMu.Lock()
+	defer chargesMu.Unlock()
+	charge, ok := charges[id]
+	if !ok {
+		log.Printf("[ERROR] Capture failed: charge %s not found", id)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if charge.Status == "pending_capture" && time.Since(charge.AuthorizedAt) > authorizationTTL {
+		charge.Status = "expired"
+	}
+	if charge.Status != "pending_capture" {
+		log.Printf("[ERROR] Capture failed: charge %s is %s", id, charge.Status)
+		http.Error(w, "charge is "+charge.Status, http.StatusBadRequest)
+		return
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = charge.OriginalAmount.Amount()
+	}
+	if amount < 0 || amount > charge.OriginalAmount.Amount() {
+		log.Printf("[ERROR] Capture failed: amount %d exceeds authorization %d of charge %s", amount, charge.OriginalAmount.Amount(), id)
+		http.Error(w, "capture amount exceeds authorization", http.StatusBadRequest)
+		return
+	}
+	charge.Status = "captured"
+	charge.CapturedAmount = money.New(amount, charge.Currency)
+	charge.CurrentAmount = money.New(amount, charge.Currency)
+	log.Printf("[INFO] Capture successful: %d captured from charge %s", amount, id)
+	emitEvent("CHARGE.CAPTURED", charge)
+	writeCharge(w, charge)
+}
+
+func voidChargeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var req VoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+		log.Printf("[ERROR] Invalid void request for charge %s: %v", id, err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	chargesMu.Lock()
+	defer chargesMu.Unlock()
+	charge, ok := charges[id]
+	if !ok {
+		log.Printf("[ERROR] Void failed: charge %s not found", id)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if charge.Status != "pending_capture" {
+		log.Printf("[ERROR] Void failed: charge %s is %s", id, charge.Status)
+		http.Error(w, "charge is "+charge.Status, http.StatusBadRequest)
+		return
+	}
+	charge.Status = "canceled"
+	if req.Reason == "expired" {
+		charge.Status = "expired"
+	}
+	log.Printf("[INFO] Void successful: authorization %s %s", id, charge.Status)
+	emitEvent("CHARGE.VOIDED", charge)
+	writeCharge(w, charge)
+}
+
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// NewRouter serves the PayPal API the provider calls.
+func NewRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/charges", createChargeHandler).Methods("POST")
+	r.HandleFunc("/refund/{id}", refundChargeHandler).Methods("POST")
+	r.HandleFunc("/capture/{id}", captureChargeHandler).Methods("POST")
+	r.HandleFunc("/void/{id}", voidChargeHandler).Methods("POST")
+	r.HandleFunc("/charges", findChargeByReferenceHandler).Methods("GET").Queries("reference", "{reference}")
+	r.HandleFunc("/charges/{id}", getChargeHandler).Methods("GET")
+	r.HandleFunc("/health", healthHandler).Methods("GET")
+	return r
+}
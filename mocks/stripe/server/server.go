@@ -0,0 +1,395 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type Card struct {
+	Number            string `json:"number"`
+	Holder            string `json:"holder"`
+	CVV               string `json:"cvv"`
+	Expiration        string `json:"expiration"`
+	InstallmentNumber int    `json:"installmentNumber"`
+}
+
+type CreateTransactionRequest struct {
+	Amount              int64  `json:"amount"`
+	Currency            string `json:"currency"`
+	StatementDescriptor string `json:"statementDescriptor"`
+	PaymentType         string `json:"paymentType"`
+	Card                Card   `json:"card"`
+	Reference           string `json:"reference"`
+	// Capture false only authorizes the transaction; it defaults to true.
+	Capture *bool `json:"capture"`
+}
+
+type TransactionResponse struct {
+	ID                  string `json:"id"`
+	Date                string `json:"date"`
+	Status              string `json:"status"`
+	Amount              int64  `json:"amount"`
+	OriginalAmount      int64  `json:"originalAmount"`
+	CapturedAmount      int64  `json:"capturedAmount"`
+	Currency            string `json:"currency"`
+	StatementDescriptor string `json:"statementDescriptor"`
+	PaymentType         string `json:"paymentType"`
+	CardId              string `json:"cardId"`
+	Reference           string `json:"reference,omitempty"`
+}
+
+// Event is the webhook sent to WEBHOOK_URL whenever a transaction changes.
+type Event struct {
+	ID      string    `json:"id"`
+	Type    string    `json:"type"`
+	Created int64     `json:"created"`
+	Data    EventData `json:"data"`
+}
+
+type EventData struct {
+	Object TransactionResponse `json:"object"`
+}
+
+// VoidRequest voids Amount (minor units) of the transaction; zero voids the
+// remaining balance.
+type VoidRequest struct {
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// CaptureRequest captures Amount (minor units) of an authorization; zero
+// captures all of it.
+type CaptureRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+// CancelRequest releases an authorization. Reason "expired" marks it expired
+// instead of canceled.
+type CancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+type transactionInternal struct {
+	ID                  string
+	Date                string
+	AuthorizedAt        time.Time
+	Status              string
+	Amount              *money.Money
+	OriginalAmount      *money.Money
+	CapturedAmount      *money.Money
+	Currency            string
+	StatementDescriptor string
+	PaymentType         string
+	CardId              string
+	Reference           string
+}
+
+const (
+	// declinedCardNumber is always refused, so clients can exercise declines.
+	declinedCardNumber = "4000000000000002"
+	// authorizationTTL is how long an authorization can be captured.
+	authorizationTTL = 7 * 24 * time.Hour
+	// webhookAttempts bounds the sends of a webhook the callback refuses.
+	webhookAttempts = 3
+)
+
+var (
+	transactions            = make(map[string]*transactionInternal)
+	transactionsByReference = make(map[string]*transactionInternal)
+	transactionsMu          sync.Mutex
+)
+
+func generateID() string {
+	return uuid.New().String()
+}
+
+func createTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Card.Number == declinedCardNumber {
+		http.Error(w, "card declined", http.StatusPaymentRequired)
+		return
+	}
+	transactionsMu.Lock()
+	existing, ok := transactionsByReference[req.Reference]
+	transactionsMu.Unlock()
+	if req.Reference != "" && ok {
+		// Same reference means the client is retrying: answer with the original transaction.
+		writeTransaction(w, existing)
+		return
+	}
+	id := generateID()
+	cardId := generateID()
+	amount := money.New(req.Amount, req.Currency)
+	transaction := &transactionInternal{
+		ID:                  id,
+		Date:                time.Now().Format("2006-01-02"),
+		AuthorizedAt:        time.Now(),
+		Status:              "paid",
+		Amount:              amount,
+		OriginalAmount:      amount,
+		CapturedAmount:      amount,
+		Currency:            req.Currency,
+		StatementDescriptor: req.StatementDescriptor,
+		PaymentType:         req.PaymentType,
+		CardId:              cardId,
+		Reference:           req.Reference,
+	}
+	if req.Capture != nil && !*req.Capture {
+		transaction.Status = "requires_capture"
+		transaction.Amount = money.New(0, req.Currency)
+		transaction.CapturedAmount = money.New(0, req.Currency)
+	}
+	transactionsMu.Lock()
+	transactions[id] = transaction
+	if req.Reference != "" {
+		transactionsByReference[req.Reference] = transaction
+	}
+	emitEvent("transaction.created", transaction)
+	transactionsMu.Unlock()
+	writeTransaction(w, transaction)
+}
+
+func findTransactionByReferenceHandler(w http.ResponseWriter, r *http.Request) {
+	reference := r.URL.Query().Get("reference")
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	transaction, ok := transactionsByReference[reference]
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeTransaction(w, transaction)
+}
+
+func writeTransaction(w http.ResponseWriter, transaction *transactionInternal) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toResponse(transaction))
+}
+
+func toResponse(transaction *transactionInternal) TransactionResponse {
+	return TransactionResponse{
+		ID:                  transaction.ID,
+		Date:                transaction.Date,
+		Status:              transaction.Status,
+		Amount:              transaction.Amount.Amount(),
+		OriginalAmount:      transaction.OriginalAmount.Amount(),
+		CapturedAmount:      transaction.CapturedAmount.Amount(),
+		Currency:            transaction.Currency,
+		StatementDescriptor: transaction.StatementDescriptor,
+		PaymentType:         transaction.PaymentType,
+		CardId:              transaction.CardId,
+		Reference:           transaction.Reference,
+	}
+}
+
+// emitEvent posts the transaction state to WEBHOOK_URL, signed with
+// WEBHOOK_SECRET the way Stripe does: t=<unix>,v1=<hex HMAC-SHA256 of
+// "<unix>.<body>">. Without WEBHOOK_URL no webhook is sent.
+func emitEvent(eventType string, transaction *transactionInternal) {
+	url := os.Getenv("WEBHOOK_URL")
+	if url == "" {
+		return
+	}
+	event := Event{
+		ID:      "evt_" + generateID(),
+		Type:    eventType,
+		Created: time.Now().Unix(),
+		Data:    EventData{Object: toResponse(transaction)},
+	}
+	go func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("[ERROR] Webhook %s not encoded: %v", event.ID, err)
+			return
+		}
+		for attempt := 1; attempt <= webhookAttempts; attempt++ {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			mac := hmac.New(sha256.New, []byte(os.Getenv("WEBHOOK_SECRET")))
+			mac.Write([]byte(timestamp + "."))
+			mac.Write(body)
+			req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil)))
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode < 300 {
+					log.Printf("[INFO] Webhook %s (%s) delivered", event.ID, event.Type)
+					return
+				}
+				log.Printf("[WARN] Webhook %s refused with status %d", event.ID, resp.StatusCode)
+			} else {
+				log.Printf("[WARN] Webhook %s not delivered: %v", event.ID, err)
+			}
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}()
+}
+
+func voidTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var req VoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+		log.Printf("[ERROR] Invalid void request for transaction %s: %v", id, err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	transaction, ok := transactions[id]
+	if !ok {
+		log.Printf("[ERROR] Void failed: transaction %s not found", id)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if transaction.Status == "voided" {
+		log.Printf("[ERROR] Void failed: transaction %s already voided", id)
+		http.Error(w, "transaction already voided", http.StatusBadRequest)
+		return
+	}
+	if transaction.CapturedAmount.IsZero() {
+		log.Printf("[ERROR] Void failed: transaction %s was not captured", id)
+		http.Error(w, "transaction not captured", http.StatusBadRequest)
+		return
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = transaction.Amount.Amount()
+	}
+	if amount < 0 || amount > transaction.Amount.Amount() {
+		log.Printf("[ERROR] Void failed: amount %d exceeds balance %d of transaction %s", amount, transaction.Amount.Amount(), id)
+		http.Error(w, "void amount exceeds balance", http.StatusBadRequest)
+		return
+	}
+	transaction.Amount = money.New(transaction.Amount.Amount()-amount, transaction.Currency)
+	transaction.Status = "partially_voided"
+	if transaction.Amount.IsZero() {
+		transaction.Status = "voided"
+	}
+	log.Printf("[INFO] Void successful: %d voided from transaction %s (reason: %q)", amount, id, req.Reason)
+	emitEvent("transaction.voided", transaction)
+	writeTransaction(w, transaction)
+}
+
+func getTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	transaction, ok := transactions[id]
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeTransaction(w, transaction)
+}
+
+func captureTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var req CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+		log.Printf("[ERROR] Invalid capture request for transaction %s: %v", id, err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	transaction, ok := transactions[id]
+	if !ok {
+		log.Printf("[ERROR] Capture failed: transaction %s not found", id)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if transaction.Status == "requires_capture" && time.Since(transaction.AuthorizedAt) > authorizationTTL {
+		transaction.Status = "expired"
+	}
+	if transaction.Status != "requires_capture" {
+		log.Printf("[ERROR] Capture failed: transaction %s is %s", id, transaction.Status)
+		http.Error(w, "transaction is "+transaction.Status, http.StatusBadRequest)
+		return
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = transaction.OriginalAmount.Amount()
+	}
+	if amount < 0 || amount > transaction.OriginalAmount.Amount() {
+		log.Printf("[ERROR] Capture failed: amount %d exceeds authorization %d of transaction %s", amount, transaction.OriginalAmount.Amount(), id)
+		http.Error(w, "capture amount exceeds authorization", http.StatusBadRequest)
+		return
+	}
+	transaction.Status = "captured"
+	transaction.CapturedAmount = money.New(amount, transaction.Currency)
+	transaction.Amount = money.New(amount, transaction.Currency)
+	log.Printf("[INFO] Capture successful: %d captured from transaction %s", amount, id)
+	emitEvent("transaction.captured", transaction)
+	writeTransaction(w, transaction)
+}
+
+func cancelTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var req CancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err.Error() != "EOF" {
+		log.Printf("[ERROR] Invalid cancel request for transaction %s: %v", id, err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	transaction, ok := transactions[id]
+	if !ok {
+		log.Printf("[ERROR] Cancel failed: transaction %s not found", id)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if transaction.Status != "requires_capture" {
+		log.Printf("[ERROR] Cancel failed: transaction %s is %s", id, transaction.Status)
+		http.Error(w, "transaction is "+transaction.Status, http.StatusBadRequest)
+		return
+	}
+	transaction.Status = "canceled"
+	if req.Reason == "expired" {
+		transaction.Status = "expired"
+	}
+	log.Printf("[INFO] Cancel successful: authorization %s %s", id, transaction.Status)
+	emitEvent("transaction.canceled", transaction)
+	writeTransaction(w, transaction)
+}
+
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// NewRouter serves the Stripe API the provider calls.
+func NewRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/transactions", createTransactionHandler).Methods("POST")
+	r.HandleFunc("/void/{id}", voidTransactionHandler).Methods("POST")
+	r.HandleFunc("/capture/{id}", captureTransactionHandler).Methods("POST")
+	r.HandleFunc("/cancel/{id}", cancelTransactionHandler).Methods("POST")
+	r.HandleFunc("/transactions", findTransactionByReferenceHandler).Methods("GET").Queries("reference", "{reference}")
+	r.HandleFunc("/transactions/{id}", getTransactionHandler).Methods("GET")
+	r.HandleFunc("/health", healthHandler).Methods("GET")
+	return r
+}
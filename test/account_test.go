@@ -0,0 +1,281 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/account"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryAccountRepository struct {
+	mu       sync.Mutex
+	accounts map[string]account.StoredAccount
+	finds    int
+}
+
+func newMemoryAccountRepository() *memoryAccountRepository {
+	return &memoryAccountRepository{accounts: make(map[string]account.StoredAccount)}
+}
+
+func (r *memoryAccountRepository) Save(ctx context.Context, stored *account.StoredAccount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accounts[merchant.Scope{MerchantID: stored.MerchantID, Livemode: stored.Livemode}.String()+":"+stored.Provider] = *stored
+	return nil
+}
+
+func (r *memoryAccountRepository) Find(ctx context.Context, scope merchant.Scope) ([]account.StoredAccount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.finds++
+	accounts := make([]account.StoredAccount, 0)
+	for _, stored := range r.accounts {
+		if scope.Owns(stored.MerchantID, stored.Livemode) {
+			accounts = append(accounts, stored)
+		}
+	}
+
+	return accounts, nil
+}
+
+func (r *memoryAccountRepository) Delete(ctx context.Context, scope merchant.Scope, provider string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := scope.String() + ":" + provider
+	if _, ok := r.accounts[key]; !ok {
+		return account.ErrNotFound
+	}
+
+	delete(r.accounts, key)
+	return nil
+}
+
+type memoryAccountCache struct {
+	mu      sync.Mutex
+	entries map[string][]account.StoredAccount
+}
+
+func (c *memoryAccountCache) Get(ctx context.Context, scope merchant.Scope) ([]account.StoredAccount, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	accounts, ok := c.entries[scope.String()]
+	return accounts, ok, nil
+}
+
+func (c *memoryAccountCache) Set(ctx context.Context, scope merchant.Scope, accounts []account.StoredAccount, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[scope.String()] = accounts
+	return nil
+}
+
+func (c *memoryAccountCache) Invalidate(ctx context.Context, scope merchant.Scope) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, scope.String())
+	return nil
+}
+
+// accountRecordingProvider accepts every charge and keeps the account and
+// request of the last one.
+type accountRecordingProvider struct {
+	namedProvider
+	account *payment.Account
+	request payment.ChargeRequest
+}
+
+func (p *accountRecordingProvider) Charge(ctx context.Context, request payment.ChargeRequest) (payment.ChargeResult, error) {
+	p.account = payment.AccountFrom(ctx, p.name)
+	p.request = request
+	return payment.ChargeResult{ProviderPaymentID: "charge-1", Status: payment.StatusCaptured, Amount: request.Amount, CapturedAmount: request.Amount}, nil
+}
+
+func newTestAccounts(t *testing.T, repository account.Repository, providers *payment.Registry) *account.Service {
+	t.Helper()
+
+	keyring, err := vault.NewKeyring(vaultKey(t, "k1"))
+	if err != nil {
+		t.Fatalf("expected keyring, got %v", err)
+	}
+
+	return account.New(repository, &memoryAccountCache{entries: make(map[string][]account.StoredAccount)}, keyring, providers)
+}
+
+func merchantContext(merchantID string, livemode bool) context.Context {
+	return merchant.WithScope(context.Background(), merchant.Scope{MerchantID: merchantID, Livemode: livemode})
+}
+
+func TestAccountService_EncryptsAndCachesCredentials(t *testing.T) {
+	repository := newMemoryAccountRepository()
+	accounts := newTestAccounts(t, repository, payment.NewRegistry(&namedProvider{name: "stripe"}))
+	accounts.BaseURLHosts = map[string][]string{"stripe": {"stripe.example"}}
+	ctx := merchantContext("acme", true)
+
+	settings := account.Settings{BaseURL: "https://stripe.example", Credentials: payment.Credentials{APIKey: "sk_acme_secret"}, Enabled: true}
+	if _, err := accounts.Configure(ctx, "stripe", settings); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, stored := range repository.accounts {
+		if strings.Contains(string(stored.Ciphertext), "sk_acme_secret") || stored.KeyID != "k1" {
+			t.Fatalf("expected the credentials to be encrypted, got %+v", stored)
+		}
+	}
+
+	for range 2 {
+		listed, err := accounts.List(ctx)
+		if err != nil || len(listed) != 1 || listed[0].Credentials.APIKey != "sk_acme_secret" || listed[0].BaseURL != "https://stripe.example" {
+			t.Fatalf("expected the decrypted account, got %+v %v", listed, err)
+		}
+	}
+
+	if repository.finds != 1 {
+		t.Fatalf("expected the second read to hit the cache, got %d database reads", repository.finds)
+	}
+
+	settings.Enabled = false
+	if _, err := accounts.Configure(ctx, "stripe", settings); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if listed, _ := accounts.List(ctx); len(listed) != 1 || listed[0].Enabled {
+		t.Fatalf("expected the change to invalidate the cache, got %+v", listed)
+	}
+
+	if listed, _ := accounts.List(merchantContext("acme", false)); len(listed) != 0 {
+		t.Fatalf("expected test mode not to see live accounts, got %+v", listed)
+	}
+
+	if _, err := accounts.Configure(ctx, "unknown", settings); !errors.Is(err, account.ErrUnknownProvider) {
+		t.Fatalf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestAccountService_RestrictsBaseURLToAllowedHosts(t *testing.T) {
+	accounts := newTestAccounts(t, newMemoryAccountRepository(), payment.NewRegistry(&namedProvider{name: "stripe"}))
+	accounts.BaseURLHosts = map[string][]string{"stripe": {"stripe.example"}}
+	ctx := merchantContext("acme", false)
+
+	for _, baseURL := range []string{"https://169.254.169.254", "http://stripe.example", "https://user@stripe.example", "https://stripe.example.evil.test"} {
+		settings := account.Settings{BaseURL: baseURL, Credentials: payment.Credentials{APIKey: "sk_acme"}}
+		if _, err := accounts.Configure(ctx, "stripe", settings); !errors.Is(err, account.ErrBaseURLNotAllowed) {
+			t.Fatalf("expected %s to be refused, got %v", baseURL, err)
+		}
+	}
+
+	settings := account.Settings{BaseURL: "https://stripe.example/v1", Credentials: payment.Credentials{APIKey: "sk_acme"}}
+	if _, err := accounts.Configure(ctx, "stripe", settings); err != nil {
+		t.Fatalf("expected an allowed host to be accepted, got %v", err)
+	}
+
+	accounts.BaseURLHosts = nil
+	if listed, err := accounts.List(ctx); err != nil || len(listed) != 1 || listed[0].BaseURL != "" {
+		t.Fatalf("expected a host no longer allowed to fall back to the default, got %+v %v", listed, err)
+	}
+}
+
+func TestProcessPayment_MerchantAccountFailuresKeepProviderBreakerClosed(t *testing.T) {
+	primary := &countingProvider{erroringProvider: erroringProvider{name: "primary", err: networkError()}}
+	providers := payment.NewRegistry(primary)
+	accounts := newTestAccounts(t, newMemoryAccountRepository(), providers)
+
+	service := payment.New(newMemoryRepository(), providers)
+	service.Accounts = accounts
+	service.Breakers = payment.NewCircuitBreakers(testBreakerConfig)
+
+	ctx := merchantContext("acme", false)
+	if _, err := accounts.Configure(ctx, "primary", account.Settings{Credentials: payment.Credentials{APIKey: "broken-key"}, Enabled: true}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for range 3 {
+		_, _ = service.ProcessPayment(ctx, &payment.Payment{Amount: usd("10.00")})
+	}
+
+	if calls := primary.charges.Load(); calls != 2 {
+		t.Fatalf("expected the account breaker to open after 2 failed calls, got %d calls", calls)
+	}
+
+	if state := service.Breakers.For("primary").State(); state != payment.BreakerClosed {
+		t.Fatalf("expected the provider breaker to stay closed for other merchants, got %s", state)
+	}
+}
+
+func TestProcessPayment_UsesMerchantAccounts(t *testing.T) {
+	first := &accountRecordingProvider{namedProvider: namedProvider{name: "first"}}
+	second := &accountRecordingProvider{namedProvider: namedProvider{name: "second"}}
+	providers := payment.NewRegistry(first, second)
+	accounts := newTestAccounts(t, newMemoryAccountRepository(), providers)
+
+	service := payment.New(newMemoryRepository(), providers)
+	service.Accounts = accounts
+
+	ctx := merchantContext("acme", false)
+	if _, err := accounts.Configure(ctx, "first", account.Settings{Credentials: payment.Credentials{APIKey: "first-key"}, Enabled: false}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := accounts.Configure(ctx, "second", account.Settings{Credentials: payment.Credentials{APIKey: "second-key"}, Enabled: true, StatementDescriptor: "ACME STORE"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	created, err := service.ProcessPayment(ctx, &payment.Payment{Amount: usd("10.00"), Method: payment.Method{Type: "card", Card: testCard()}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if created.Provider != "second" || first.account != nil || second.account == nil || second.account.Credentials.APIKey != "second-key" {
+		t.Fatalf("expected the enabled account to charge, got %s with %+v", created.Provider, second.account)
+	}
+
+	if second.request.StatementDescriptor != "ACME STORE" {
+		t.Fatalf("expected the account statement descriptor, got %q", second.request.StatementDescriptor)
+	}
+
+	created, err = service.ProcessPayment(merchantContext("globex", false), &payment.Payment{Amount: usd("10.00"), Method: payment.Method{Type: "card", Card: testCard()}})
+	if err != nil || created.Provider != "first" || first.account != nil {
+		t.Fatalf("expected a merchant without accounts to use the defaults, got %+v %v", created, err)
+	}
+}
+
+func TestStripe_ChargesOnMerchantAccount(t *testing.T) {
+	var (
+		authorization string
+		charged       map[string]any
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&charged)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "tr_1", "status": "paid", "amount": 1000, "capturedAmount": 1000, "currency": "USD"})
+	}))
+	defer server.Close()
+
+	provider := stripeProvider.NewWithClient(server.Client())
+	ctx := payment.WithAccount(context.Background(), &payment.Account{Provider: "stripe", BaseURL: server.URL, Credentials: payment.Credentials{APIKey: "sk_acme"}})
+
+	result, err := provider.Charge(ctx, payment.ChargeRequest{Amount: usd("10.00"), Description: "Order 1", StatementDescriptor: "ACME STORE"})
+	if err != nil || result.ProviderPaymentID != "tr_1" {
+		t.Fatalf("expected the charge on the merchant account, got %+v %v", result, err)
+	}
+
+	if authorization != "Bearer sk_acme" || charged["statementDescriptor"] != "ACME STORE" {
+		t.Fatalf("expected the account credentials and descriptor, got %q %v", authorization, charged["statementDescriptor"])
+	}
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	paypalProvider "lucassaraiva5/api-pay/internal/app/providers/paypal"
+	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func authorizePayment(t *testing.T, service *payment.Service, amount string) *payment.Payment {
+	t.Helper()
+
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd(amount), AuthorizeOnly: true})
+	if err != nil {
+		t.Fatalf("expected authorization to succeed, got %v", err)
+	}
+
+	return created
+}
+
+func TestProcessPayment_AuthorizeOnlyHoldsFunds(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New()))
+	service.AuthorizationTTL = time.Hour
+
+	created := authorizePayment(t, service, "100.00")
+	if created.Status != payment.StatusAuthorized || created.CapturedAmount != usd("0.00") || created.AuthorizationExpiresAt == "" {
+		t.Fatalf("expected an authorization awaiting capture, got %+v", created)
+	}
+
+	stored, _ := service.GetPayment(context.Background(), created.ID)
+	if stored.Status != payment.StatusAuthorized || stored.AuthorizationExpiresAt != created.AuthorizationExpiresAt {
+		t.Fatalf("expected provider to agree the payment awaits capture, got %+v", stored)
+	}
+
+	if _, err := service.RefundPayment(context.Background(), created.ID, money.Decimal{}, ""); !errors.Is(err, payment.ErrNotCaptured) {
+		t.Fatalf("expected refund of an authorization to be rejected, got %v", err)
+	}
+}
+
+func TestCapturePayment_PartialCaptureBoundsRefunds(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New()))
+	created := authorizePayment(t, service, "100.00")
+
+	if _, err := service.CapturePayment(context.Background(), created.ID, dec("100.01")); !errors.Is(err, payment.ErrInvalidCaptureAmount) {
+		t.Fatalf("expected capture above the authorization to be rejected, got %v", err)
+	}
+
+	captured, err := service.CapturePayment(context.Background(), created.ID, dec("60.0"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if captured.Status != payment.StatusCaptured || captured.CapturedAmount != usd("60.00") {
+		t.Fatalf("expected 60.0 to be captured, got %+v", captured)
+	}
+
+	if _, err = service.CapturePayment(context.Background(), created.ID, dec("0")); !errors.Is(err, payment.ErrNotAuthorized) {
+		t.Fatalf("expected second capture to be rejected, got %v", err)
+	}
+
+	if _, err = service.RefundPayment(context.Background(), created.ID, dec("60.01"), ""); !errors.Is(err, payment.ErrRefundExceedsBalance) {
+		t.Fatalf("expected refund above the captured amount to be rejected, got %v", err)
+	}
+
+	refunded, err := service.RefundPayment(context.Background(), created.ID, money.Decimal{}, "")
+	if err != nil || refunded.Status != payment.StatusRefunded || refunded.RefundedAmount != usd("60.00") {
+		t.Fatalf("expected the captured amount to be refunded, got %+v %v", refunded, err)
+	}
+}
+
+func TestCancelPayment_ReleasesAuthorization(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(stripeProvider.New()))
+	created := authorizePayment(t, service, "50.00")
+
+	canceled, err := service.CancelPayment(context.Background(), created.ID)
+	if err != nil || canceled.Status != payment.StatusCanceled {
+		t.Fatalf("expected authorization to be canceled, got %+v %v", canceled, err)
+	}
+
+	stored, _ := service.GetPayment(context.Background(), created.ID)
+	if stored.Status != payment.StatusCanceled {
+		t.Fatalf("expected provider to agree the payment is canceled, got %+v", stored)
+	}
+
+	if _, err = service.CapturePayment(context.Background(), created.ID, dec("0")); !errors.Is(err, payment.ErrNotAuthorized) {
+		t.Fatalf("expected capture of a canceled payment to be rejected, got %v", err)
+	}
+}
+
+func TestCapturePayment_ExpiredAuthorization(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(stripeProvider.New()))
+	created := authorizePayment(t, service, "50.00")
+	service.AuthorizationTTL = time.Nanosecond
+
+	if _, err := service.CapturePayment(context.Background(), created.ID, dec("0")); !errors.Is(err, payment.ErrAuthorizationExpired) {
+		t.Fatalf("expected ErrAuthorizationExpired, got %v", err)
+	}
+
+	stored, _ := service.GetPayment(context.Background(), created.ID)
+	if stored.Status != payment.StatusCanceled {
+		t.Fatalf("expected payment and provider to agree the authorization expired, got %+v", stored)
+	}
+}
+
+func TestExpireAuthorizations_ExpiresStaleAuthorizations(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New()))
+	created := authorizePayment(t, service, "20.00")
+
+	if expired, _ := service.ExpireAuthorizations(context.Background(), 10); expired != 0 {
+		t.Fatalf("expected no expiry without an authorization TTL, got %d", expired)
+	}
+
+	service.AuthorizationTTL = time.Nanosecond
+	if expired, err := service.ExpireAuthorizations(context.Background(), 10); err != nil || expired != 1 {
+		t.Fatalf("expected one authorization to expire, got %d %v", expired, err)
+	}
+
+	stored, _ := service.GetPayment(context.Background(), created.ID)
+	if stored.Status != payment.StatusCanceled {
+		t.Fatalf("expected payment to be expired, got %+v", stored)
+	}
+}
+
+func TestPaymentHandler_AuthorizeAndCapture(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), paypalProvider.New())
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", cardPaymentBody("30", "USD", `,"capture":false`))
+	var created outbound.PaymentResponse
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusOK || created.Status != string(payment.StatusAuthorized) {
+		t.Fatalf("expected authorization, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = doPaymentRequest(e, http.MethodPost, "/payments/"+created.ID+"/capture", `{"amount":12.5}`)
+	var captured outbound.PaymentResponse
+	decodeBody(t, rec, &captured)
+	if rec.Code != http.StatusOK || captured.Status != string(payment.StatusCaptured) || captured.CapturedAmount.String() != "12.50" {
+		t.Fatalf("expected partial capture, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = doPaymentRequest(e, http.MethodPost, "/payments/"+created.ID+"/cancel", "")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected cancel of a captured payment to conflict, got %d %s", rec.Code, rec.Body)
+	}
+}
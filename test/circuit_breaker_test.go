@@ -0,0 +1,192 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testBreakerConfig = payment.BreakerConfig{
+	WindowSize:   4,
+	MinimumCalls: 2,
+	FailureRate:  0.5,
+	SlowCallRate: 0.5,
+	SlowCall:     time.Second,
+	OpenTimeout:  time.Hour,
+}
+
+func networkError() error {
+	return payment.NewProviderError("p", payment.ErrorNetwork, 0, errors.New("refused"))
+}
+
+func TestCircuitBreaker_OpensOnFailureRate(t *testing.T) {
+	breaker := payment.NewCircuitBreaker("p", testBreakerConfig)
+
+	breaker.Record(nil, time.Millisecond)
+	breaker.Record(networkError(), time.Millisecond)
+
+	if breaker.State() != payment.BreakerOpen || breaker.Allow() {
+		t.Fatalf("expected breaker to open and reject calls, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_OpensOnSlowCalls(t *testing.T) {
+	breaker := payment.NewCircuitBreaker("p", testBreakerConfig)
+
+	breaker.Record(nil, 2*time.Second)
+	breaker.Record(nil, 2*time.Second)
+
+	if breaker.State() != payment.BreakerOpen {
+		t.Fatalf("expected slow calls to open the breaker, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_IgnoresDeclines(t *testing.T) {
+	breaker := payment.NewCircuitBreaker("p", testBreakerConfig)
+	declined := payment.NewProviderError("p", payment.ErrorDeclined, 402, errors.New("declined"))
+
+	for i := 0; i < 4; i++ {
+		breaker.Record(declined, time.Millisecond)
+	}
+
+	if breaker.State() != payment.BreakerClosed {
+		t.Fatalf("expected declines not to open the breaker, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenTrialClosesOnSuccess(t *testing.T) {
+	config := testBreakerConfig
+	config.OpenTimeout = 10 * time.Millisecond
+	breaker := payment.NewCircuitBreaker("p", config)
+
+	breaker.Record(networkError(), time.Millisecond)
+	breaker.Record(networkError(), time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if !breaker.Allow() {
+		t.Fatalf("expected a trial call after the open timeout")
+	}
+
+	if breaker.Allow() {
+		t.Fatalf("expected a single trial call while half-open")
+	}
+
+	breaker.Record(nil, time.Millisecond)
+	if breaker.State() != payment.BreakerClosed {
+		t.Fatalf("expected a successful trial to close the breaker, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenTrialReopensOnFailure(t *testing.T) {
+	breaker := payment.NewCircuitBreaker("p", testBreakerConfig)
+
+	breaker.Record(networkError(), time.Millisecond)
+	breaker.Record(networkError(), time.Millisecond)
+	breaker.ProbeSucceeded()
+
+	if breaker.State() != payment.BreakerHalfOpen || !breaker.Allow() {
+		t.Fatalf("expected a successful probe to allow a trial call, got %s", breaker.State())
+	}
+
+	breaker.Record(networkError(), time.Millisecond)
+	if breaker.State() != payment.BreakerOpen {
+		t.Fatalf("expected a failed trial to reopen the breaker, got %s", breaker.State())
+	}
+}
+
+// countingProvider fails every charge and counts how often it was called.
+type countingProvider struct {
+	erroringProvider
+	charges atomic.Int32
+	healthy atomic.Bool
+}
+
+func (p *countingProvider) Charge(ctx context.Context, request payment.ChargeRequest) (payment.ChargeResult, error) {
+	p.charges.Add(1)
+	return p.erroringProvider.Charge(ctx, request)
+}
+
+func (p *countingProvider) Ping(ctx context.Context) error {
+	if p.healthy.Load() {
+		return nil
+	}
+
+	return p.err
+}
+
+func TestProcessPayment_SkipsProviderWithOpenBreaker(t *testing.T) {
+	primary := &countingProvider{erroringProvider: erroringProvider{name: "primary", err: networkError()}}
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(primary, stripeProvider.New()))
+	service.Breakers = payment.NewCircuitBreakers(testBreakerConfig)
+
+	for i := 0; i < 4; i++ {
+		if _, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")}); err != nil {
+			t.Fatalf("expected stripe to take the payment, got %v", err)
+		}
+	}
+
+	if calls := primary.charges.Load(); calls != 2 {
+		t.Fatalf("expected the primary to be skipped once its breaker opened, got %d calls", calls)
+	}
+}
+
+func TestProcessPayment_AllBreakersOpen(t *testing.T) {
+	primary := &countingProvider{erroringProvider: erroringProvider{name: "primary", err: networkError()}}
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(primary))
+	service.Breakers = payment.NewCircuitBreakers(testBreakerConfig)
+	service.Breakers.For("primary").Record(networkError(), time.Millisecond)
+	service.Breakers.For("primary").Record(networkError(), time.Millisecond)
+
+	_, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+	if !errors.Is(err, payment.ErrNoProviderAvailable) {
+		t.Fatalf("expected ErrNoProviderAvailable, got %v", err)
+	}
+
+	if primary.charges.Load() != 0 {
+		t.Fatalf("expected no call to a provider with an open breaker")
+	}
+}
+
+func TestHealthProber_HalfOpensRecoveredProvider(t *testing.T) {
+	primary := &countingProvider{erroringProvider: erroringProvider{name: "primary", err: networkError()}}
+	breakers := payment.NewCircuitBreakers(testBreakerConfig)
+	breakers.For("primary").Record(networkError(), time.Millisecond)
+	breakers.For("primary").Record(networkError(), time.Millisecond)
+	prober := payment.NewHealthProber(payment.NewRegistry(primary), breakers, time.Hour, time.Second)
+
+	prober.Probe(context.Background())
+	if state := breakers.For("primary").State(); state != payment.BreakerOpen {
+		t.Fatalf("expected a failed probe to keep the breaker open, got %s", state)
+	}
+
+	primary.healthy.Store(true)
+	prober.Probe(context.Background())
+	if state := breakers.For("primary").State(); state != payment.BreakerHalfOpen {
+		t.Fatalf("expected a successful probe to half-open the breaker, got %s", state)
+	}
+}
+
+func TestProviderHandler_ReportsBreakerStates(t *testing.T) {
+	primary := &countingProvider{erroringProvider: erroringProvider{name: "primary", err: networkError()}}
+	e := newPaymentServer(newMemoryRepository(), primary, stripeProvider.New())
+
+	rec := doPaymentRequest(e, http.MethodGet, "/providers/status", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var statuses []payment.BreakerStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("expected a JSON list, got %s", rec.Body)
+	}
+
+	if len(statuses) != 2 || statuses[0].Provider != "primary" || statuses[0].State != payment.BreakerClosed {
+		t.Fatalf("expected both providers to be reported closed, got %+v", statuses)
+	}
+}
@@ -0,0 +1,140 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/correlation"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeLogs captures every log line written until the test ends.
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+
+	return logs
+}
+
+func loggedAttributes(t *testing.T, entry observer.LoggedEntry) attributes.Attributes {
+	t.Helper()
+
+	attr, ok := entry.ContextMap()["Attributes"].(attributes.Attributes)
+	if !ok {
+		t.Fatalf("expected the entry %q to carry attributes", entry.Message)
+	}
+
+	return attr
+}
+
+func newCorrelatedServer(handler echo.HandlerFunc) *echo.Echo {
+	e := newPaymentServer(newMemoryRepository())
+	e.Use(middleware.ConfigRequest())
+	e.GET("/logged", handler)
+
+	return e
+}
+
+func TestCorrelation_CIDReachesLogsAndResponse(t *testing.T) {
+	logs := observeLogs(t)
+	e := newCorrelatedServer(func(c echo.Context) error {
+		logger.Info(correlation.WithPaymentID(c.Request().Context(), "payment-1"), "Handling", nil)
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/logged", nil)
+	req.Header.Set(correlation.HeaderCID, "cid-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if cid := rec.Header().Get(correlation.HeaderCID); cid != "cid-1" {
+		t.Fatalf("expected the cid to be echoed, got %q", cid)
+	}
+
+	entries := logs.FilterMessage("Handling").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected one log line, got %d", len(entries))
+	}
+
+	if attr := loggedAttributes(t, entries[0]); attr["cid"] != "cid-1" || attr["payment_id"] != "payment-1" {
+		t.Fatalf("expected the request ids in the log, got %v", attr)
+	}
+
+	for _, entry := range logs.All() {
+		if attr := loggedAttributes(t, entry); attr["cid"] != "cid-1" {
+			t.Fatalf("expected every log line of the request to carry its cid, got %v in %q", attr["cid"], entry.Message)
+		}
+	}
+}
+
+func TestCorrelation_GeneratedCIDMatchesErrorBody(t *testing.T) {
+	observeLogs(t)
+	e := newCorrelatedServer(nil)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/payments/missing-payment", nil))
+
+	var response apperror.Response
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+
+	cid := rec.Header().Get(correlation.HeaderCID)
+	if cid == "" || response.Error.CID != cid {
+		t.Fatalf("expected the generated cid in both the header and the body, got %q and %q", cid, response.Error.CID)
+	}
+}
+
+func TestCorrelation_CIDForwardedToProvider(t *testing.T) {
+	var forwarded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(correlation.HeaderCID)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	t.Setenv("STRIPE_MOCK_URL", server.URL)
+
+	ctx := correlation.WithCID(context.Background(), "cid-1")
+	_, _ = stripeProvider.NewWithClient(server.Client()).Charge(ctx, payment.ChargeRequest{Amount: usd("10.00")})
+
+	if forwarded != "cid-1" {
+		t.Fatalf("expected the cid to be forwarded, got %q", forwarded)
+	}
+}
+
+func TestTimeout_RendersErrorEnvelopeWithCID(t *testing.T) {
+	observeLogs(t)
+
+	config := middleware.DefaultTimeoutConfig
+	config.Timeout = 20 * time.Millisecond
+
+	e := newCorrelatedServer(func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+	e.Use(echoMiddleware.ContextTimeoutWithConfig(config))
+
+	req := httptest.NewRequest(http.MethodGet, "/logged", nil)
+	req.Header.Set(correlation.HeaderCID, "cid-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var response apperror.Response
+	decodeBody(t, rec, &response)
+
+	if rec.Code != http.StatusServiceUnavailable || response.Error.Code != "request_timeout" || !response.Error.Retryable || response.Error.CID != "cid-1" {
+		t.Fatalf("expected a retryable request_timeout carrying the cid, got %d %s", rec.Code, rec.Body)
+	}
+}
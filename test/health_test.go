@@ -0,0 +1,105 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/adapters/handler"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/health"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+// hanging ignores its context, like a client stuck on a dead connection.
+func hanging(ctx context.Context) error {
+	time.Sleep(time.Second)
+	return nil
+}
+
+func newHealthServer(checker *health.Checker) *echo.Echo {
+	e := echo.New()
+	handler.NewHealthHandler(&domain.Services{HealthChecker: checker}).Configure(e)
+	return e
+}
+
+func TestHealthChecker_OnlyRequiredDependenciesFailReadiness(t *testing.T) {
+	report := health.NewChecker(time.Second,
+		health.Dependency{Name: "write_db", Required: true, Ping: up},
+		health.Dependency{Name: "paypal", Ping: down},
+	).Check(context.Background())
+
+	if !report.Ready || report.Results[1].Status != health.StatusDown {
+		t.Fatalf("expected an optional dependency down to only be reported, got %+v", report)
+	}
+
+	report = health.NewChecker(time.Second,
+		health.Dependency{Name: "write_db", Required: true, Ping: down},
+	).Check(context.Background())
+
+	if report.Ready {
+		t.Fatalf("expected a required dependency down to fail readiness, got %+v", report)
+	}
+}
+
+func TestHealthChecker_TimesOutConcurrently(t *testing.T) {
+	checker := health.NewChecker(50*time.Millisecond,
+		health.Dependency{Name: "read_db", Required: true, Ping: hanging},
+		health.Dependency{Name: "redis", Ping: hanging},
+	)
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected pings to be bounded by the timeout, took %v", elapsed)
+	}
+
+	if report.Ready || report.Results[0].Status != health.StatusDown || report.Results[1].Status != health.StatusDown {
+		t.Fatalf("expected hanging dependencies to be down, got %+v", report)
+	}
+}
+
+func TestHealthHandler_ReadyAnswersUnavailableWhenRequiredIsDown(t *testing.T) {
+	e := newHealthServer(health.NewChecker(time.Second,
+		health.Dependency{Name: "write_db", Required: true, Ping: down},
+		health.Dependency{Name: "stripe", Ping: up},
+	))
+
+	rec := doPaymentRequest(e, http.MethodGet, "/health/ready", "")
+	var response outbound.HealthResponse
+	decodeBody(t, rec, &response)
+
+	if rec.Code != http.StatusServiceUnavailable || response.Healthy || len(response.Dependencies) != 2 {
+		t.Fatalf("expected status 503 with every dependency, got %d %s", rec.Code, rec.Body)
+	}
+
+	if dependency := response.Dependencies[0]; dependency.Name != "write_db" || dependency.Status != health.StatusDown || !dependency.Required {
+		t.Fatalf("expected write_db to be reported down, got %+v", dependency)
+	}
+
+	if rec = doPaymentRequest(e, http.MethodGet, "/health/live", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected liveness to ignore dependencies, got %d", rec.Code)
+	}
+}
+
+func TestNewHealthChecker_RejectsUnknownDependency(t *testing.T) {
+	providers := payment.NewRegistry(&failingProvider{})
+
+	if _, err := domain.NewHealthChecker(&database.Databases{}, providers, []string{"write_db", "failing"}, time.Second); err != nil {
+		t.Fatalf("expected databases and providers to be known, got %v", err)
+	}
+
+	if _, err := domain.NewHealthChecker(&database.Databases{}, providers, []string{"mysql"}, time.Second); err == nil {
+		t.Fatal("expected an unknown dependency to be refused")
+	}
+}
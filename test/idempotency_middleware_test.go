@@ -0,0 +1,205 @@
+package test
+
+import (
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/server"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+)
+
+func newIdempotentServer(t *testing.T, calls *int) *echo.Echo {
+	e, _ := newIdempotentServerWithHandler(t, func(c echo.Context) error {
+		*calls++
+		return c.JSON(http.StatusOK, map[string]int{"call": *calls})
+	})
+
+	return e
+}
+
+func newIdempotentServerWithHandler(t *testing.T, handler echo.HandlerFunc) (*echo.Echo, *miniredis.Miniredis) {
+	store := miniredis.RunT(t)
+
+	e := echo.New()
+	e.HTTPErrorHandler = server.HTTPErrorHandler
+	idempotency := middleware.IdempotencyWithConfig(middleware.IdempotencyConfig{
+		Redis:   database.NewRedis(&redis.Options{Addr: store.Addr()}, true),
+		TTL:     time.Minute,
+		LockTTL: time.Minute,
+	})
+
+	e.POST("/payments", handler, idempotency)
+	e.POST("/refunds", handler, idempotency)
+
+	return e, store
+}
+
+func doIdempotentRequest(e *echo.Echo, key string, body string) *httptest.ResponseRecorder {
+	return doIdempotentRequestTo(e, "/payments", key, body)
+}
+
+func doIdempotentRequestTo(e *echo.Echo, target string, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(middleware.HeaderIdempotencyKey, key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency_ReplaysOriginalResponse(t *testing.T) {
+	calls := 0
+	e := newIdempotentServer(t, &calls)
+
+	first := doIdempotentRequest(e, "key-1", `{"amount":10}`)
+	second := doIdempotentRequest(e, "key-1", `{"amount":10}`)
+
+	if calls != 1 {
+		t.Fatalf("expected handler to be called once, got %d", calls)
+	}
+
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected replayed response %d %s, got %d %s", first.Code, first.Body, second.Code, second.Body)
+	}
+
+	if second.Header().Get(middleware.HeaderIdempotencyReplayed) != "true" {
+		t.Fatalf("expected replayed header to be set")
+	}
+}
+
+func TestIdempotency_RejectsDifferentBody(t *testing.T) {
+	calls := 0
+	e := newIdempotentServer(t, &calls)
+
+	doIdempotentRequest(e, "key-2", `{"amount":10}`)
+	rec := doIdempotentRequest(e, "key-2", `{"amount":20}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected handler to be called once, got %d", calls)
+	}
+}
+
+func TestIdempotency_ReplaysRefundResponse(t *testing.T) {
+	calls := 0
+	e := newIdempotentServer(t, &calls)
+
+	first := doIdempotentRequestTo(e, "/refunds", "refund-1", `{"id":"payment-1"}`)
+	second := doIdempotentRequestTo(e, "/refunds", "refund-1", `{"id":"payment-1"}`)
+
+	if calls != 1 {
+		t.Fatalf("expected refund handler to be called once, got %d", calls)
+	}
+
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected replayed response %d %s, got %d %s", first.Code, first.Body, second.Code, second.Body)
+	}
+}
+
+func TestIdempotency_RejectsKeyReusedOnAnotherEndpoint(t *testing.T) {
+	calls := 0
+	e := newIdempotentServer(t, &calls)
+
+	doIdempotentRequestTo(e, "/payments", "key-3", `{"id":"payment-1"}`)
+	rec := doIdempotentRequestTo(e, "/refunds", "key-3", `{"id":"payment-1"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestIdempotency_RejectsConcurrentRequestInFlight(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	e, _ := newIdempotentServerWithHandler(t, func(c echo.Context) error {
+		close(entered)
+		<-release
+		return c.NoContent(http.StatusOK)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- doIdempotentRequest(e, "key-4", `{"amount":10}`)
+	}()
+	<-entered
+
+	rec := doIdempotentRequest(e, "key-4", `{"amount":10}`)
+	close(release)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", rec.Code)
+	}
+
+	if first := <-done; first.Code != http.StatusOK {
+		t.Fatalf("expected first request to succeed, got %d", first.Code)
+	}
+}
+
+func TestIdempotency_ExpiredLockIsNotReleasedByFormerOwner(t *testing.T) {
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	e, server := newIdempotentServerWithHandler(t, func(c echo.Context) error {
+		entered <- struct{}{}
+		<-release
+		return c.NoContent(http.StatusInternalServerError)
+	})
+
+	first := make(chan struct{})
+	go func() {
+		doIdempotentRequest(e, "key-5", `{"amount":10}`)
+		close(first)
+	}()
+	<-entered
+
+	// The first request outlives its lock and a retry takes it over.
+	server.FastForward(2 * time.Minute)
+	second := make(chan struct{})
+	go func() {
+		doIdempotentRequest(e, "key-5", `{"amount":10}`)
+		close(second)
+	}()
+	<-entered
+
+	release <- struct{}{}
+	<-first
+
+	if !server.Exists("idempotency:key-5:lock") {
+		t.Fatalf("expected the retry to keep its lock after the first request finished")
+	}
+
+	close(release)
+	<-second
+
+	if server.Exists("idempotency:key-5:lock") {
+		t.Fatalf("expected the retry to release its own lock")
+	}
+}
+
+func TestIdempotency_UnavailableStoreFailsClosed(t *testing.T) {
+	calls := 0
+	e, server := newIdempotentServerWithHandler(t, func(c echo.Context) error {
+		calls++
+		return c.NoContent(http.StatusOK)
+	})
+	server.Close()
+
+	rec := doIdempotentRequest(e, "key-6", `{"amount":10}`)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+
+	if calls != 0 {
+		t.Fatalf("expected handler not to be called, got %d", calls)
+	}
+}
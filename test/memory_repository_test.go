@@ -0,0 +1,254 @@
+package test
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"sync"
+	"time"
+)
+
+// memoryRepository is an in-memory payment.Repository so the service tests
+// only depend on the provider mocks.
+type memoryRepository struct {
+	mu       sync.Mutex
+	payments map[string]payment.Payment
+	attempts []payment.Attempt
+	refunds  []payment.Refund
+	history  map[string][]payment.Status
+	events   map[string]bool
+	outbox   []outboxEvent
+}
+
+// outboxEvent is a payment event stored by Update.
+type outboxEvent struct {
+	event        payment.Event
+	claimedUntil time.Time
+	published    bool
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		payments: make(map[string]payment.Payment),
+		history:  make(map[string][]payment.Status),
+		events:   make(map[string]bool),
+	}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, p *payment.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.payments[p.ID] = *p
+	r.history[p.ID] = append(r.history[p.ID], p.Status)
+	return nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, p *payment.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.payments[p.ID]
+	if !ok {
+		return payment.ErrNotFound
+	}
+
+	if !stored.Status.CanTransitionTo(p.Status) {
+		return payment.ErrInvalidTransition
+	}
+
+	if stored.Status != p.Status {
+		r.history[p.ID] = append(r.history[p.ID], p.Status)
+	}
+
+	updated := *p
+	updated.RefundedAmount.Amount = max(updated.RefundedAmount.Amount, stored.RefundedAmount.Amount)
+	r.payments[p.ID] = updated
+
+	if event, ok := payment.NewEvent(&updated); ok {
+		r.outbox = append(r.outbox, outboxEvent{event: *event})
+	}
+
+	return nil
+}
+
+func (r *memoryRepository) ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]payment.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]payment.Event, 0)
+	for i := range r.outbox {
+		if r.outbox[i].published || r.outbox[i].claimedUntil.After(now) || len(events) == limit {
+			continue
+		}
+
+		r.outbox[i].claimedUntil = now.Add(lease)
+		events = append(events, r.outbox[i].event)
+	}
+
+	return events, nil
+}
+
+// expireClaims makes every claimed event claimable again, as if its lease had
+// run out.
+func (r *memoryRepository) expireClaims() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.outbox {
+		r.outbox[i].claimedUntil = time.Time{}
+	}
+}
+
+func (r *memoryRepository) MarkEventPublished(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.outbox {
+		if r.outbox[i].event.ID == id {
+			r.outbox[i].published = true
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryRepository) FindByID(ctx context.Context, id string) (*payment.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.payments[id]
+	if !ok {
+		return nil, payment.ErrNotFound
+	}
+
+	return &stored, nil
+}
+
+func (r *memoryRepository) FindByStatus(ctx context.Context, status payment.Status, createdBefore time.Time, limit int) ([]*payment.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payments := make([]*payment.Payment, 0)
+	for _, stored := range r.payments {
+		createdAt, _ := time.Parse(time.RFC3339, stored.CreatedAt)
+		if stored.Status == status && !createdAt.After(createdBefore) && len(payments) < limit {
+			stored := stored
+			payments = append(payments, &stored)
+		}
+	}
+
+	return payments, nil
+}
+
+func (r *memoryRepository) FindStatusHistory(ctx context.Context, paymentID string) ([]payment.StatusChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := make([]payment.StatusChange, 0)
+	var from payment.Status
+	for _, status := range r.history[paymentID] {
+		changes = append(changes, payment.StatusChange{PaymentID: paymentID, From: from, To: status})
+		from = status
+	}
+
+	return changes, nil
+}
+
+func (r *memoryRepository) AddAttempt(ctx context.Context, attempt *payment.Attempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts = append(r.attempts, *attempt)
+	return nil
+}
+
+func (r *memoryRepository) FindAttempts(ctx context.Context, paymentID string) ([]payment.Attempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := make([]payment.Attempt, 0)
+	for _, attempt := range r.attempts {
+		if attempt.PaymentID == paymentID {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return attempts, nil
+}
+
+func (r *memoryRepository) ReserveRefund(ctx context.Context, refund *payment.Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.payments[refund.PaymentID]
+	if !ok {
+		return payment.ErrNotFound
+	}
+
+	balance := stored.CapturedAmount.Amount - stored.RefundedAmount.Amount
+	for _, pending := range r.refunds {
+		if pending.PaymentID == refund.PaymentID && pending.Status == payment.RefundPending {
+			balance -= pending.Amount.Amount
+		}
+	}
+
+	if refund.Amount.Amount > balance {
+		return payment.ErrRefundExceedsBalance
+	}
+
+	r.refunds = append(r.refunds, *refund)
+	return nil
+}
+
+func (r *memoryRepository) SettleRefund(ctx context.Context, refund *payment.Refund) (money.Money, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.refunds {
+		if r.refunds[i].ID == refund.ID {
+			r.refunds[i] = *refund
+		}
+	}
+
+	stored, ok := r.payments[refund.PaymentID]
+	if !ok {
+		return money.Money{}, payment.ErrNotFound
+	}
+
+	if refund.Status == payment.RefundSucceeded {
+		stored.RefundedAmount = money.New(stored.RefundedAmount.Amount+refund.Amount.Amount, stored.Amount.Currency)
+		r.payments[refund.PaymentID] = stored
+	}
+
+	return stored.RefundedAmount, nil
+}
+
+func (r *memoryRepository) FindRefunds(ctx context.Context, paymentID string) ([]payment.Refund, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	refunds := make([]payment.Refund, 0)
+	for _, refund := range r.refunds {
+		if refund.PaymentID == paymentID {
+			refunds = append(refunds, refund)
+		}
+	}
+
+	return refunds, nil
+}
+
+func (r *memoryRepository) ProviderEventSeen(ctx context.Context, provider string, eventID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.events[provider+"/"+eventID], nil
+}
+
+func (r *memoryRepository) AddProviderEvent(ctx context.Context, event *payment.ProviderEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[event.Provider+"/"+event.ID] = true
+	return nil
+}
@@ -0,0 +1,328 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/adapters/handler"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/infra/server"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type memoryMerchantRepository struct {
+	mu        sync.Mutex
+	merchants map[string]merchant.Merchant
+	keys      map[string]merchant.APIKey
+}
+
+func newMemoryMerchantRepository() *memoryMerchantRepository {
+	return &memoryMerchantRepository{
+		merchants: make(map[string]merchant.Merchant),
+		keys:      make(map[string]merchant.APIKey),
+	}
+}
+
+func (r *memoryMerchantRepository) Create(ctx context.Context, m *merchant.Merchant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *m
+	stored.Keys = nil
+	r.merchants[m.ID] = stored
+
+	for _, key := range m.Keys {
+		key.Value = ""
+		r.keys[key.ID] = key
+	}
+
+	return nil
+}
+
+func (r *memoryMerchantRepository) FindByID(ctx context.Context, id string) (*merchant.Merchant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.merchants[id]
+	if !ok {
+		return nil, merchant.ErrNotFound
+	}
+
+	return &m, nil
+}
+
+func (r *memoryMerchantRepository) CreateKey(ctx context.Context, key *merchant.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *key
+	stored.Value = ""
+	r.keys[key.ID] = stored
+	return nil
+}
+
+func (r *memoryMerchantRepository) FindKeyByHash(ctx context.Context, hash string) (*merchant.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			return &key, nil
+		}
+	}
+
+	return nil, merchant.ErrKeyNotFound
+}
+
+func (r *memoryMerchantRepository) FindKeys(ctx context.Context, merchantID string) ([]merchant.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]merchant.APIKey, 0)
+	for _, key := range r.keys {
+		if key.MerchantID == merchantID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (r *memoryMerchantRepository) RevokeKey(ctx context.Context, merchantID string, keyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[keyID]
+	if !ok || key.MerchantID != merchantID {
+		return merchant.ErrKeyNotFound
+	}
+
+	key.RevokedAt = time.Now().UTC().Format(time.RFC3339)
+	r.keys[keyID] = key
+	return nil
+}
+
+// merchantKey returns the value of the key of type and mode issued with m.
+func merchantKey(t *testing.T, m *merchant.Merchant, keyType merchant.KeyType, livemode bool) string {
+	t.Helper()
+
+	for _, key := range m.Keys {
+		if key.Type == keyType && key.Livemode == livemode {
+			return key.Value
+		}
+	}
+
+	t.Fatalf("expected merchant %s to have a %s key", m.ID, keyType)
+	return ""
+}
+
+func newMerchantServer(t *testing.T) (*echo.Echo, *merchant.Service) {
+	t.Helper()
+
+	merchants := merchant.New(newMemoryMerchantRepository(), "admin-key")
+	vaultService := newTestVault(t, newMemoryVaultRepository(), vaultKey(t, "k1"))
+	paymentService := payment.New(newMemoryRepository(), payment.NewRegistry(&cardRecordingProvider{namedProvider: namedProvider{name: "recording"}}))
+	paymentService.Vault = vaultService
+
+	services := &domain.Services{MerchantService: merchants, PaymentService: paymentService, VaultService: vaultService}
+	noop := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	anyKey := middleware.ConfigAuthentication(merchants.Authenticate)
+	secretKey := middleware.ConfigAuthentication(merchants.AuthenticateSecret)
+	admin := middleware.ConfigAuthentication(merchants.AuthenticateAdmin)
+
+	e := echo.New()
+	e.HTTPErrorHandler = server.HTTPErrorHandler
+	handler.NewMerchantHandler(services, admin, secretKey).Configure(e)
+	handler.NewPaymentHandler(services, secretKey, noop, noop).Configure(e)
+	handler.NewTokenHandler(services, anyKey, noop, noop).Configure(e)
+	return e, merchants
+}
+
+func doAuthenticatedRequest(e *echo.Echo, key string, method string, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+key)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMerchantService_CreateIssuesHashedKeys(t *testing.T) {
+	repository := newMemoryMerchantRepository()
+	service := merchant.New(repository, "")
+
+	created, err := service.Create(context.Background(), "Acme")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(created.Keys) != 4 {
+		t.Fatalf("expected a secret and a publishable key in each mode, got %d keys", len(created.Keys))
+	}
+
+	secret := merchantKey(t, created, merchant.KeySecret, true)
+	if !strings.HasPrefix(secret, "sk_live_") || !strings.HasPrefix(merchantKey(t, created, merchant.KeyPublishable, false), "pk_test_") {
+		t.Fatalf("expected keys to carry their type and mode, got %q", secret)
+	}
+
+	for _, key := range repository.keys {
+		if key.Value != "" || key.Hash == "" || strings.Contains(key.Hash, secret) {
+			t.Fatalf("expected only the key hash to be stored, got %+v", key)
+		}
+	}
+
+	ctx, err := service.Authenticate(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("expected the key to authenticate, got %v", err)
+	}
+
+	if scope := merchant.ScopeFrom(ctx); scope.MerchantID != created.ID || !scope.Livemode {
+		t.Fatalf("expected the live scope of the merchant, got %+v", scope)
+	}
+}
+
+func TestMerchantService_RejectsInvalidKeys(t *testing.T) {
+	repository := newMemoryMerchantRepository()
+	service := merchant.New(repository, "")
+	created, _ := service.Create(context.Background(), "Acme")
+
+	if _, err := service.Authenticate(context.Background(), ""); !errors.Is(err, merchant.ErrMissingAPIKey) {
+		t.Fatalf("expected ErrMissingAPIKey, got %v", err)
+	}
+
+	if _, err := service.Authenticate(context.Background(), "sk_live_unknown"); !errors.Is(err, merchant.ErrInvalidAPIKey) {
+		t.Fatalf("expected ErrInvalidAPIKey, got %v", err)
+	}
+
+	if _, err := service.AuthenticateSecret(context.Background(), merchantKey(t, created, merchant.KeyPublishable, true)); !errors.Is(err, merchant.ErrSecretKeyRequired) {
+		t.Fatalf("expected ErrSecretKeyRequired, got %v", err)
+	}
+
+	// The stored type decides, whatever the value looks like.
+	sum := sha256.Sum256([]byte("sk_test_lookalike"))
+	_ = repository.CreateKey(context.Background(), &merchant.APIKey{ID: "key-1", MerchantID: created.ID, Type: merchant.KeyPublishable, Hash: hex.EncodeToString(sum[:])})
+	if _, err := service.AuthenticateSecret(context.Background(), "sk_test_lookalike"); !errors.Is(err, merchant.ErrSecretKeyRequired) {
+		t.Fatalf("expected ErrSecretKeyRequired for a publishable key, got %v", err)
+	}
+
+	if _, err := service.AuthenticateAdmin(context.Background(), "anything"); !errors.Is(err, merchant.ErrAdminNotConfigured) {
+		t.Fatalf("expected ErrAdminNotConfigured, got %v", err)
+	}
+}
+
+func TestMerchantHandler_IsolatesMerchants(t *testing.T) {
+	e, merchants := newMerchantServer(t)
+	acme, _ := merchants.Create(context.Background(), "Acme")
+	globex, _ := merchants.Create(context.Background(), "Globex")
+	acmeKey := merchantKey(t, acme, merchant.KeySecret, true)
+	globexKey := merchantKey(t, globex, merchant.KeySecret, true)
+
+	rec := doAuthenticatedRequest(e, acmeKey, http.MethodPost, "/payments", cardPaymentBody("10", "USD", ""))
+	var created outbound.PaymentResponse
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusOK || !created.Livemode {
+		t.Fatalf("expected a live payment, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec = doAuthenticatedRequest(e, acmeKey, http.MethodGet, "/payments/"+created.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the owner to read its payment, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec = doAuthenticatedRequest(e, globexKey, http.MethodGet, "/payments/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected another merchant not to see the payment, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec = doAuthenticatedRequest(e, globexKey, http.MethodPost, "/payments/"+created.ID+"/refunds", `{"amount":10}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected another merchant not to refund the payment, got %d %s", rec.Code, rec.Body)
+	}
+
+	testKey := merchantKey(t, acme, merchant.KeySecret, false)
+	if rec = doAuthenticatedRequest(e, testKey, http.MethodGet, "/payments/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected test keys not to see live payments, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestMerchantHandler_AuthenticatesRequests(t *testing.T) {
+	e, merchants := newMerchantServer(t)
+	acme, _ := merchants.Create(context.Background(), "Acme")
+	publishable := merchantKey(t, acme, merchant.KeyPublishable, false)
+
+	if rec := doAuthenticatedRequest(e, "", http.MethodPost, "/payments", cardPaymentBody("10", "USD", "")); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a request without key to be refused, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := doAuthenticatedRequest(e, publishable, http.MethodPost, "/payments", cardPaymentBody("10", "USD", "")); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a publishable key not to charge, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec := doAuthenticatedRequest(e, publishable, http.MethodPost, "/tokens", `{"number":"4111111111111111","holder":"John Doe","cvv":"123","expiration":"12/2099"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected a publishable key to tokenize cards, got %d %s", rec.Code, rec.Body)
+	}
+
+	var token outbound.TokenResponse
+	decodeBody(t, rec, &token)
+
+	other, _ := merchants.Create(context.Background(), "Globex")
+	rec = doAuthenticatedRequest(e, merchantKey(t, other, merchant.KeySecret, false), http.MethodPost, "/payments", `{"amount":10,"currency":"USD","method":{"type":"card","token":"`+token.ID+`","card":{"installmentNumber":1}}}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "token_not_found") {
+		t.Fatalf("expected another merchant not to charge the token, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestMerchantHandler_ManagesKeys(t *testing.T) {
+	e, _ := newMerchantServer(t)
+
+	if rec := doAuthenticatedRequest(e, "wrong", http.MethodPost, "/merchants", `{"name":"Acme"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the admin key to be required, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec := doAuthenticatedRequest(e, "admin-key", http.MethodPost, "/merchants", `{"name":"Acme"}`)
+	var created outbound.MerchantResponse
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusCreated || len(created.Keys) != 4 || created.Keys[0].Key == "" {
+		t.Fatalf("expected the merchant with its keys, got %d %s", rec.Code, rec.Body)
+	}
+
+	var oldKey *outbound.APIKeyResponse
+	for _, key := range created.Keys {
+		if key.Type == string(merchant.KeySecret) && !key.Livemode {
+			oldKey = key
+		}
+	}
+
+	rec = doAuthenticatedRequest(e, oldKey.Key, http.MethodPost, "/merchant/keys", `{"type":"secret"}`)
+	var issued outbound.APIKeyResponse
+	decodeBody(t, rec, &issued)
+	if rec.Code != http.StatusCreated || issued.Livemode || !strings.HasPrefix(issued.Key, "sk_test_") {
+		t.Fatalf("expected a test secret key, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec = doAuthenticatedRequest(e, issued.Key, http.MethodDelete, "/merchant/keys/"+oldKey.ID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the old key to be revoked, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec = doAuthenticatedRequest(e, oldKey.Key, http.MethodGet, "/merchant", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked key to be refused, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = doAuthenticatedRequest(e, issued.Key, http.MethodGet, "/merchant", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), issued.Key) {
+		t.Fatalf("expected the merchant without key values, got %d %s", rec.Code, rec.Body)
+	}
+}
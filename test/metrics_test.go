@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"io"
+	"lucassaraiva5/api-pay/internal/app/adapters/handler"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+
+	e := newPaymentServer(newMemoryRepository())
+	handler.NewMetricsHandler().Configure(e)
+
+	rec := doPaymentRequest(e, http.MethodGet, "/metrics", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected metrics to be served, got %d", rec.Code)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func expectMetric(t *testing.T, scraped string, series string) {
+	t.Helper()
+
+	if !strings.Contains(scraped, series) {
+		t.Fatalf("expected the series %s to be exposed", series)
+	}
+}
+
+func TestMetrics_RecordsRequestsByRouteTemplate(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository())
+	e.Use(middleware.ConfigMetrics())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/payments/missing-payment", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the payment to be missing, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown/path", nil))
+
+	scraped := scrapeMetrics(t)
+	expectMetric(t, scraped, `api_pay_http_requests_total{method="GET",route="/payments/:id",status="404"}`)
+	expectMetric(t, scraped, `api_pay_http_requests_total{method="GET",route="unmatched",status="404"}`)
+	if strings.Contains(scraped, "missing-payment") {
+		t.Fatal("expected raw paths to stay out of the labels")
+	}
+}
+
+func TestMetrics_RecordsAttemptsFailoversAndProviderCalls(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(&failingProvider{}, stripeProvider.New()))
+
+	if _, err := service.ProcessPayment(context.Background(), &payment.Payment{
+		Amount: usd("10.00"),
+		Method: payment.Method{Type: "card"},
+	}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	scraped := scrapeMetrics(t)
+	expectMetric(t, scraped, `api_pay_payment_attempts_total{outcome="succeeded",provider="stripe"}`)
+	expectMetric(t, scraped, `api_pay_payment_failovers_total{provider="failing"}`)
+	expectMetric(t, scraped, `api_pay_provider_request_duration_seconds_count{operation="charge",outcome="succeeded",provider="stripe"}`)
+	expectMetric(t, scraped, `api_pay_provider_circuit_breaker_state{provider="stripe",state="closed"}`)
+}
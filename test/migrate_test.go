@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// migrationVersions lists the embedded migrations in the order Migrate
+// applies them.
+func migrationVersions(t *testing.T) []string {
+	entries, err := os.ReadDir("../internal/infra/database/migrations")
+	if err != nil {
+		t.Fatalf("expected migrations to be readable, got %v", err)
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".sql") {
+			versions = append(versions, strings.TrimSuffix(entry.Name(), ".sql"))
+		}
+	}
+
+	sort.Strings(versions)
+	return versions
+}
+
+func expectMigrationPreamble(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrate_AppliesPendingMigrations(t *testing.T) {
+	db, mock := newMockDatabase(t)
+
+	expectMigrationPreamble(mock)
+	for _, version := range migrationVersions(t) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+			WithArgs(version).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectBegin()
+		mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations")).
+			WithArgs(version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := database.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrate_SkipsAppliedMigrations(t *testing.T) {
+	db, mock := newMockDatabase(t)
+
+	expectMigrationPreamble(mock)
+	for _, version := range migrationVersions(t) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+			WithArgs(version).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	}
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := database.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
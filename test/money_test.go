@@ -0,0 +1,110 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
+	"testing"
+)
+
+func amountIn(value string, currency string) money.Money {
+	amount, err := money.Parse(value, currency)
+	if err != nil {
+		panic(err)
+	}
+
+	return amount
+}
+
+func usd(value string) money.Money {
+	return amountIn(value, "USD")
+}
+
+func dec(value string) money.Decimal {
+	decimal, err := money.ParseDecimal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return decimal
+}
+
+func TestMoney_ParseUsesCurrencyExponent(t *testing.T) {
+	cases := []struct {
+		value    string
+		currency string
+		minor    int64
+		text     string
+	}{
+		{"19.99", "USD", 1999, "19.99"},
+		{"0.1", "usd", 10, "0.10"},
+		{"500", "JPY", 500, "500"},
+		{"500.00", "JPY", 500, "500"},
+		{"1.234", "KWD", 1234, "1.234"},
+		{"-5", "EUR", -500, "-5.00"},
+	}
+
+	for _, c := range cases {
+		amount, err := money.Parse(c.value, c.currency)
+		if err != nil {
+			t.Fatalf("%s %s: expected no error, got %v", c.value, c.currency, err)
+		}
+		if amount.Amount != c.minor || amount.String() != c.text {
+			t.Fatalf("%s %s: expected %d (%s), got %d (%s)", c.value, c.currency, c.minor, c.text, amount.Amount, amount)
+		}
+	}
+}
+
+func TestMoney_ParseRejectsInvalidAmounts(t *testing.T) {
+	cases := []struct {
+		value    string
+		currency string
+		err      error
+	}{
+		{"19.999", "USD", money.ErrTooPrecise},
+		{"1.5", "JPY", money.ErrTooPrecise},
+		{"1e3", "USD", money.ErrInvalidAmount},
+		{"1,000", "USD", money.ErrInvalidAmount},
+		{"", "USD", money.ErrInvalidAmount},
+		{"1.", "USD", money.ErrInvalidAmount},
+		{"1234567890123456789", "USD", money.ErrInvalidAmount},
+		{"99999999999999999", "USD", money.ErrTooLarge},
+		{"-99999999999999999", "USD", money.ErrTooLarge},
+		{"10", "XYZ", money.ErrUnknownCurrency},
+	}
+
+	for _, c := range cases {
+		if _, err := money.Parse(c.value, c.currency); !errors.Is(err, c.err) {
+			t.Fatalf("%q %s: expected %v, got %v", c.value, c.currency, c.err, err)
+		}
+	}
+}
+
+func TestDecimal_CmpIgnoresScale(t *testing.T) {
+	if dec("10.50").Cmp(dec("10.5")) != 0 || dec("10.01").Cmp(dec("10.1")) >= 0 || dec("-1").Cmp(dec("0")) >= 0 {
+		t.Fatal("expected decimals to compare by value")
+	}
+}
+
+func TestDecimal_JSONAcceptsNumbersAndStrings(t *testing.T) {
+	var body struct {
+		Number money.Decimal `json:"number"`
+		Text   money.Decimal `json:"text"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"number":0.3,"text":"19.99"}`), &body); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if body.Number.String() != "0.3" || body.Text.String() != "19.99" {
+		t.Fatalf("expected exact decimals, got %s and %s", body.Number, body.Text)
+	}
+
+	encoded, _ := json.Marshal(usd("0.30").Decimal())
+	if string(encoded) != "0.30" {
+		t.Fatalf("expected a number literal, got %s", encoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"number":1e2}`), &body); !errors.Is(err, money.ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/adapters/handler"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/validation"
+	"lucassaraiva5/api-pay/internal/infra/apperror"
+	"lucassaraiva5/api-pay/internal/infra/server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newPaymentServer(repository payment.Repository, providers ...payment.Provider) *echo.Echo {
+	services := &domain.Services{PaymentService: payment.New(repository, payment.NewRegistry(providers...))}
+	noop := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+
+	e := echo.New()
+	e.HTTPErrorHandler = server.HTTPErrorHandler
+	handler.NewPaymentHandler(services, noop, noop, noop).Configure(e)
+	handler.NewProviderHandler(services, noop).Configure(e)
+	return e
+}
+
+func doPaymentRequest(e *echo.Echo, method string, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// cardPaymentBody is a create payment request that passes validation.
+func cardPaymentBody(amount string, currency string, extra string) string {
+	return `{"amount":` + amount + `,"currency":"` + currency + `"` + extra + `,"method":{"type":"card","card":{"number":"4111111111111111","holder":"John Doe","cvv":"123","expiration":"12/2099","installmentNumber":1}}}`
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, target any) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), target); err != nil {
+		t.Fatalf("expected JSON body, got %s: %v", rec.Body, err)
+	}
+}
+
+func TestPaymentHandler_GetUnknownPaymentIsNotFound(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), &failingProvider{})
+
+	rec := doPaymentRequest(e, http.MethodGet, "/payments/unknown", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestPaymentHandler_ErrorsUseEnvelopeWithCorrelationID(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), &failingProvider{})
+
+	req := httptest.NewRequest(http.MethodGet, "/payments/unknown", nil)
+	req.Header.Set("x-cid", "cid-123")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var response apperror.Response
+	decodeBody(t, rec, &response)
+	if rec.Code != http.StatusNotFound || response.Error.Code != "payment_not_found" || response.Error.CID != "cid-123" || response.Error.Retryable {
+		t.Fatalf("expected payment_not_found envelope, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec.Header().Get("x-cid") != "cid-123" {
+		t.Fatalf("expected correlation id header, got %q", rec.Header().Get("x-cid"))
+	}
+}
+
+func TestPaymentHandler_ProviderFailureHidesProviderMessage(t *testing.T) {
+	failure := payment.NewProviderError("broken", payment.ErrorProvider, 500, errors.New("Stripe mock returned status 404"))
+	e := newPaymentServer(newMemoryRepository(), &erroringProvider{name: "broken", err: failure})
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", cardPaymentBody("10", "USD", ""))
+
+	var response apperror.Response
+	decodeBody(t, rec, &response)
+	if rec.Code != http.StatusBadGateway || response.Error.Code != "provider_error" || !response.Error.Retryable {
+		t.Fatalf("expected retryable provider_error, got %d %s", rec.Code, rec.Body)
+	}
+
+	if strings.Contains(rec.Body.String(), "mock") {
+		t.Fatalf("expected provider message to be hidden, got %s", rec.Body)
+	}
+}
+
+func TestPaymentHandler_MalformedBodyIsBadRequest(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), &failingProvider{})
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", `{"amount":`)
+
+	var response apperror.Response
+	decodeBody(t, rec, &response)
+	if rec.Code != http.StatusBadRequest || response.Error.Code != "malformed_request" {
+		t.Fatalf("expected malformed_request, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPaymentHandler_RefundUnknownPaymentIsNotFound(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), &failingProvider{})
+
+	rec := doPaymentRequest(e, http.MethodPost, "/refunds", `{"id":"unknown"}`)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestPaymentHandler_RefundAboveBalanceIsUnprocessable(t *testing.T) {
+	repository := newMemoryRepository()
+	repository.payments["payment-1"] = payment.Payment{ID: "payment-1", Amount: usd("10.00"), CapturedAmount: usd("10.00"), Status: payment.StatusCaptured, Provider: "failing", ProviderPaymentID: "charge-1"}
+	e := newPaymentServer(repository, &failingProvider{})
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments/payment-1/refunds", `{"amount":10.01,"reason":"too much"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestPaymentHandler_DeclinedPaymentIsPaymentRequired(t *testing.T) {
+	declined := payment.NewProviderError("declining", payment.ErrorDeclined, 402, errors.New("card declined"))
+	e := newPaymentServer(newMemoryRepository(), &erroringProvider{name: "declining", err: declined})
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", cardPaymentBody("10", "USD", ""))
+	if rec.Code != http.StatusPaymentRequired {
+		t.Fatalf("expected status 402, got %d", rec.Code)
+	}
+}
+
+func TestPaymentHandler_AmountTooPreciseForCurrencyIsUnprocessable(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), &failingProvider{})
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", cardPaymentBody(`"10.5"`, "JPY", ""))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestPaymentHandler_InvalidPaymentListsFieldErrors(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), &failingProvider{})
+
+	body := `{"amount":-5,"currency":"USD","method":{"type":"card","card":{"number":"4111111111111112","holder":"","cvv":"12","expiration":"01/2020","installmentNumber":0}}}`
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+
+	var response struct {
+		Error struct {
+			Code   string                  `json:"code"`
+			Fields []validation.FieldError `json:"fields"`
+		} `json:"error"`
+	}
+	decodeBody(t, rec, &response)
+	if response.Error.Code != "invalid_request" {
+		t.Fatalf("expected invalid_request, got %s", rec.Body)
+	}
+
+	codes := map[string]string{}
+	for _, field := range response.Error.Fields {
+		codes[field.Field] = field.Code
+	}
+
+	expected := map[string]string{
+		"amount":                        validation.CodeTooSmall,
+		"method.card.number":            validation.CodeInvalid,
+		"method.card.holder":            validation.CodeRequired,
+		"method.card.cvv":               validation.CodeInvalid,
+		"method.card.expiration":        validation.CodeExpired,
+		"method.card.installmentNumber": validation.CodeInvalid,
+	}
+	for field, code := range expected {
+		if codes[field] != code {
+			t.Fatalf("expected %s to be %s, got %v", field, code, response.Error.Fields)
+		}
+	}
+}
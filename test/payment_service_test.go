@@ -2,12 +2,19 @@ package test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/domain/money"
 	"lucassaraiva5/api-pay/internal/app/domain/payment"
 	paypalProvider "lucassaraiva5/api-pay/internal/app/providers/paypal"
 	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
 	"lucassaraiva5/api-pay/internal/infra/logger"
+	paypalMock "lucassaraiva5/api-pay/mocks/paypal/server"
+	stripeMock "lucassaraiva5/api-pay/mocks/stripe/server"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -23,22 +30,25 @@ func TestMain(m *testing.M) {
 
 	defer logger.Sync()
 
+	// Serve the provider APIs in process, so the suite needs no running mocks
+	paypal := httptest.NewServer(paypalMock.NewRouter())
+	stripe := httptest.NewServer(stripeMock.NewRouter())
+
+	os.Setenv("PAYPAL_MOCK_URL", paypal.URL)
+	os.Setenv("STRIPE_MOCK_URL", stripe.URL)
+
 	// Run tests
-	os.Exit(m.Run())
+	code := m.Run()
+	paypal.Close()
+	stripe.Close()
+	os.Exit(code)
 }
 
 func TestProcessPayment_SuccessWithPrimaryProvider(t *testing.T) {
-	primary := paypalProvider.New()
-	secondary := stripeProvider.New()
-
-	service := &payment.Service{
-		PrimaryProvider:   primary,
-		SecondaryProvider: secondary,
-	}
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New(), stripeProvider.New()))
 
 	paymentRequest := &payment.Payment{
-		Amount:   100.0,
-		Currency: "USD",
+		Amount: usd("100.00"),
 		Method: payment.Method{
 			Type: "card",
 			Card: payment.Card{
@@ -56,7 +66,7 @@ func TestProcessPayment_SuccessWithPrimaryProvider(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if result == nil || result.Status != "authorized" {
+	if result == nil || result.Status != payment.StatusCaptured {
 		t.Fatalf("expected payment to be authorized, got %v", result)
 	}
 
@@ -65,18 +75,248 @@ func TestProcessPayment_SuccessWithPrimaryProvider(t *testing.T) {
 	}
 }
 
-func TestRefundPayment_SuccessWithPrimaryProvider(t *testing.T) {
-	primary := paypalProvider.New()
-	secondary := stripeProvider.New()
+func TestProcessPayment_PersistsPaymentAndAttempt(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(paypalProvider.New(), stripeProvider.New()))
+
+	paymentRequest := &payment.Payment{
+		Amount: usd("42.50"),
+		Method: payment.Method{Type: "card"},
+	}
+
+	result, err := service.ProcessPayment(context.Background(), paymentRequest)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stored, err := repository.FindByID(context.Background(), result.ID)
+	if err != nil {
+		t.Fatalf("expected payment to be stored, got %v", err)
+	}
+
+	if stored.Provider != "paypal" || stored.ProviderPaymentID == "" || stored.ProviderPaymentID == stored.ID {
+		t.Fatalf("expected payment to reference its paypal charge, got %+v", stored)
+	}
+
+	if stored.Status != payment.StatusCaptured || stored.Amount != usd("42.50") {
+		t.Fatalf("expected stored payment to be authorized with amount 42.5, got %+v", stored)
+	}
+
+	if len(repository.attempts) != 1 || repository.attempts[0].Status != payment.AttemptSucceeded {
+		t.Fatalf("expected one successful attempt, got %+v", repository.attempts)
+	}
+
+	history := repository.history[stored.ID]
+	if len(history) != 2 || history[0] != payment.StatusPending || history[1] != payment.StatusCaptured {
+		t.Fatalf("expected status history [pending authorized], got %v", history)
+	}
+}
+
+func TestProcessPayment_FailoverToNextProvider(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(&failingProvider{}, stripeProvider.New()))
+
+	result, err := service.ProcessPayment(context.Background(), &payment.Payment{
+		Amount: usd("10.00"),
+		Method: payment.Method{Type: "card"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Provider != "stripe" || result.Status != payment.StatusCaptured {
+		t.Fatalf("expected payment to be paid by stripe, got %+v", result)
+	}
+
+	if len(repository.attempts) != 2 || repository.attempts[0].Status != payment.AttemptFailed {
+		t.Fatalf("expected a failed attempt followed by a successful one, got %+v", repository.attempts)
+	}
+}
+
+func TestProcessPayment_DeclineDoesNotFailOver(t *testing.T) {
+	repository := newMemoryRepository()
+	declined := payment.NewProviderError("declining", payment.ErrorDeclined, 402, errors.New("card declined"))
+	service := payment.New(repository, payment.NewRegistry(&erroringProvider{name: "declining", err: declined}, stripeProvider.New()))
+
+	_, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorDeclined {
+		t.Fatalf("expected a declined error, got %v", err)
+	}
+
+	if len(repository.attempts) != 1 || repository.attempts[0].ErrorKind != payment.ErrorDeclined {
+		t.Fatalf("expected a single declined attempt, got %+v", repository.attempts)
+	}
+
+	stored, _ := repository.FindByID(context.Background(), repository.attempts[0].PaymentID)
+	if stored.Status != payment.StatusFailed {
+		t.Fatalf("expected payment to be declined, got %+v", stored)
+	}
+}
+
+func TestProcessPayment_ServerErrorFollowsFailoverPolicy(t *testing.T) {
+	serverError := payment.NewProviderError("broken", payment.ErrorProvider, 503, errors.New("unavailable"))
+	registry := payment.NewRegistry(&erroringProvider{name: "broken", err: serverError}, stripeProvider.New())
+
+	strict := payment.New(newMemoryRepository(), registry)
+	if _, err := strict.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")}); err == nil {
+		t.Fatalf("expected default policy not to fail over on a server error")
+	}
+
+	lenient := payment.New(newMemoryRepository(), registry)
+	lenient.Failover = payment.NewFailoverPolicy(payment.ErrorNetwork, payment.ErrorProvider)
+
+	result, err := lenient.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Provider != "stripe" || len(result.Attempts) != 2 || result.Attempts[0].ErrorKind != payment.ErrorProvider {
+		t.Fatalf("expected stripe to charge after a recorded server error, got %+v", result)
+	}
+}
+
+func TestFailoverPolicy_NeverAllowsUnknownOutcome(t *testing.T) {
+	policy := payment.NewFailoverPolicy(payment.ErrorNetwork, payment.ErrorTimeout)
+
+	if policy.Allows(payment.NewProviderError("p", payment.ErrorTimeout, 0, context.DeadlineExceeded)) {
+		t.Fatalf("expected timeouts after send never to fail over")
+	}
+
+	if policy.Allows(errors.New("unclassified")) {
+		t.Fatalf("expected unclassified errors never to fail over")
+	}
+
+	if !policy.Allows(fmt.Errorf("wrapped: %w", payment.NewProviderError("p", payment.ErrorNetwork, 0, errors.New("refused")))) {
+		t.Fatalf("expected network errors to fail over")
+	}
+}
+
+func TestGetPayment_ReturnsRecordedAttempts(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(&failingProvider{}, stripeProvider.New()))
 
-	service := &payment.Service{
-		PrimaryProvider:   primary,
-		SecondaryProvider: secondary,
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	result, _ := service.GetPayment(context.Background(), created.ID)
+	if len(result.Attempts) != 2 || result.Attempts[0].Provider != "failing" || result.Attempts[1].Provider != "stripe" {
+		t.Fatalf("expected both attempts on the payment, got %+v", result.Attempts)
+	}
+}
+
+func TestProcessPayment_UnknownOutcomeSkipsFailover(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(&unknownOutcomeProvider{}, stripeProvider.New()))
+
+	result, err := service.ProcessPayment(context.Background(), &payment.Payment{
+		Amount: usd("10.00"),
+		Method: payment.Method{Type: "card"},
+	})
+	if !errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected unknown outcome error, got %v", err)
+	}
+
+	if result == nil || result.Status != payment.StatusPending || result.Provider != "unknown-outcome" {
+		t.Fatalf("expected payment to await reconciliation on the first provider, got %+v", result)
+	}
+
+	if len(repository.attempts) != 1 || repository.attempts[0].Status != payment.AttemptUnknown {
+		t.Fatalf("expected a single attempt with unknown outcome, got %+v", repository.attempts)
+	}
+
+	stored, _ := repository.FindByID(context.Background(), result.ID)
+	if stored.Status != payment.StatusPending {
+		t.Fatalf("expected stored payment to be unknown, got %+v", stored)
+	}
+}
+
+func TestReconcilePending_RecoversChargedPayment(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(&unknownOutcomeProvider{charged: true}))
+
+	created, _ := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+
+	settled, err := service.ReconcilePending(context.Background(), 10)
+	if err != nil || settled != 1 {
+		t.Fatalf("expected one payment reconciled, got %d (%v)", settled, err)
+	}
+
+	stored, _ := repository.FindByID(context.Background(), created.ID)
+	if stored.Status != payment.StatusCaptured || stored.ProviderPaymentID != "charge-"+created.ID {
+		t.Fatalf("expected payment to be recovered from provider, got %+v", stored)
+	}
+}
+
+func TestReconcilePending_FailsPaymentUnknownToProvider(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(&unknownOutcomeProvider{}))
+
+	created, _ := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+
+	if _, err := service.ReconcilePending(context.Background(), 10); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stored, _ := repository.FindByID(context.Background(), created.ID)
+	if stored.Status != payment.StatusFailed {
+		t.Fatalf("expected payment to fail, got %+v", stored)
+	}
+}
+
+func TestReconcilePending_WaitsForReconcileDelay(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(&unknownOutcomeProvider{charged: true}))
+	service.ReconcileAfter = time.Hour
+
+	created, _ := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+
+	if settled, _ := service.ReconcilePending(context.Background(), 10); settled != 0 {
+		t.Fatalf("expected recent payment to be left alone, got %d reconciled", settled)
+	}
+
+	if result, _ := service.GetPayment(context.Background(), created.ID); result.Status != payment.StatusPending {
+		t.Fatalf("expected payment to stay unknown, got %+v", result)
+	}
+}
+
+func TestGetPayment_ReconcilesUnknownPayment(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(&unknownOutcomeProvider{charged: true}))
+
+	created, _ := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+
+	result, err := service.GetPayment(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Status != payment.StatusCaptured {
+		t.Fatalf("expected payment to be reconciled on read, got %+v", result)
+	}
+}
+
+func TestRefundPayment_UnknownOutcomeKeepsStoredPayment(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(&unknownOutcomeProvider{charged: true}))
+
+	created, _ := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+	service.GetPayment(context.Background(), created.ID)
+
+	result, err := service.RefundPayment(context.Background(), created.ID, money.Decimal{}, "")
+	if !errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected unknown outcome error, got %v", err)
+	}
+
+	if result == nil || result.Status != payment.StatusCaptured {
+		t.Fatalf("expected stored payment to be returned untouched, got %+v", result)
 	}
+}
+
+func TestRefundPayment_SuccessWithPrimaryProvider(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New(), stripeProvider.New()))
 
 	paymentRequest := &payment.Payment{
-		Amount:   100.0,
-		Currency: "USD",
+		Amount: usd("100.00"),
 		Method: payment.Method{
 			Type: "card",
 			Card: payment.Card{
@@ -88,30 +328,155 @@ func TestRefundPayment_SuccessWithPrimaryProvider(t *testing.T) {
 			},
 		},
 	}
-	createdPayment, _ := service.ProcessPayment(context.Background(), paymentRequest)
+	createdPayment, err := service.ProcessPayment(context.Background(), paymentRequest)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
-	result, err := service.RefundPayment(context.Background(), createdPayment.ID)
+	result, err := service.RefundPayment(context.Background(), createdPayment.ID, money.Decimal{}, "")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if result == nil || result.Status != "refunded" {
+	if result == nil || result.Status != payment.StatusRefunded {
 		t.Fatalf("expected payment to be refunded, got %v", result)
 	}
 }
 
-func TestGetPayment_SuccessWithPrimaryProvider(t *testing.T) {
-	primary := paypalProvider.New()
-	secondary := stripeProvider.New()
+func TestRefundPayment_PartialRefundsUpToCapturedAmount(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(paypalProvider.New()))
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("100.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	result, err := service.RefundPayment(context.Background(), created.ID, dec("40.0"), "damaged")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Status != payment.StatusPartiallyRefunded || result.RefundedAmount != usd("40.00") || len(result.Refunds) != 1 || result.Refunds[0].Reason != "damaged" {
+		t.Fatalf("expected a partial refund of 40.0, got %+v", result)
+	}
+
+	if _, err = service.RefundPayment(context.Background(), created.ID, dec("60.01"), ""); !errors.Is(err, payment.ErrRefundExceedsBalance) {
+		t.Fatalf("expected refund above the balance to be rejected, got %v", err)
+	}
+
+	result, err = service.RefundPayment(context.Background(), created.ID, dec("60.0"), "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Status != payment.StatusRefunded || result.RefundedAmount != usd("100.00") || len(result.Refunds) != 2 {
+		t.Fatalf("expected payment to be fully refunded, got %+v", result)
+	}
+
+	if _, err = service.RefundPayment(context.Background(), created.ID, money.Decimal{}, ""); !errors.Is(err, payment.ErrRefundExceedsBalance) {
+		t.Fatalf("expected refunded payment to reject further refunds, got %v", err)
+	}
+
+	stored, _ := service.GetPayment(context.Background(), created.ID)
+	if stored.Status != payment.StatusRefunded || stored.RefundedAmount != usd("100.00") {
+		t.Fatalf("expected provider state to agree with the refunds, got %+v", stored)
+	}
+}
+
+func TestRefundPayment_RejectsNegativeAmount(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(&cardRecordingProvider{namedProvider: namedProvider{name: "recording"}}))
+
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The overflowing amount used to become a negative refund once in cents.
+	for _, amount := range []string{"-1", "99999999999999999"} {
+		if _, err = service.RefundPayment(context.Background(), created.ID, dec(amount), ""); !errors.Is(err, payment.ErrInvalidRefundAmount) {
+			t.Fatalf("%s: expected ErrInvalidRefundAmount, got %v", amount, err)
+		}
+	}
+
+	if stored, _ := service.GetPayment(context.Background(), created.ID); stored.RefundedAmount.Amount != 0 || len(stored.Refunds) != 0 {
+		t.Fatalf("expected no refund on record, got %+v", stored)
+	}
+}
+
+func TestRefundPayment_RecordsFailedRefund(t *testing.T) {
+	repository := newMemoryRepository()
+	service := payment.New(repository, payment.NewRegistry(paypalProvider.New()))
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
-	service := &payment.Service{
-		PrimaryProvider:   primary,
-		SecondaryProvider: secondary,
+	service.Providers = payment.NewRegistry(&erroringProvider{name: "paypal", err: payment.NewProviderError("paypal", payment.ErrorProvider, 500, errors.New("boom"))})
+
+	if _, err := service.RefundPayment(context.Background(), created.ID, dec("5.0"), ""); err == nil {
+		t.Fatalf("expected refund to fail")
+	}
+
+	refunds, _ := repository.FindRefunds(context.Background(), created.ID)
+	if len(refunds) != 1 || refunds[0].Status != payment.RefundFailed || refunds[0].ErrorKind != payment.ErrorProvider {
+		t.Fatalf("expected a failed refund on record, got %+v", refunds)
+	}
+}
+
+// slowRefundProvider holds every refund until released, so another refund
+// can run while one is in flight.
+type slowRefundProvider struct {
+	cardRecordingProvider
+	refunding chan struct{}
+	release   chan struct{}
+}
+
+func (p *slowRefundProvider) Refund(ctx context.Context, request payment.RefundRequest) (payment.ChargeResult, error) {
+	p.refunding <- struct{}{}
+	<-p.release
+	return payment.ChargeResult{ProviderPaymentID: request.ProviderPaymentID}, nil
+}
+
+func TestRefundPayment_ConcurrentRefundsCannotExceedBalance(t *testing.T) {
+	provider := &slowRefundProvider{
+		cardRecordingProvider: cardRecordingProvider{namedProvider: namedProvider{name: "slow"}},
+		refunding:             make(chan struct{}, 2),
+		release:               make(chan struct{}),
+	}
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(provider))
+
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("100.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	first := make(chan error)
+	go func() {
+		_, err := service.RefundPayment(context.Background(), created.ID, dec("60.00"), "")
+		first <- err
+	}()
+	<-provider.refunding
+
+	if _, err = service.RefundPayment(context.Background(), created.ID, dec("60.00"), ""); !errors.Is(err, payment.ErrRefundExceedsBalance) {
+		t.Fatalf("expected the in-flight refund to hold its amount, got %v", err)
+	}
+
+	close(provider.release)
+	if err = <-first; err != nil {
+		t.Fatalf("expected the first refund to succeed, got %v", err)
+	}
+
+	result, err := service.RefundPayment(context.Background(), created.ID, dec("40.00"), "")
+	if err != nil || result.Status != payment.StatusRefunded || result.RefundedAmount != usd("100.00") {
+		t.Fatalf("expected the rest of the balance to be refundable, got %+v %v", result, err)
 	}
+}
+
+func TestGetPayment_SuccessWithPrimaryProvider(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New(), stripeProvider.New()))
 
 	paymentRequest := &payment.Payment{
-		Amount:   100.0,
-		Currency: "USD",
+		Amount: usd("100.00"),
 		Method: payment.Method{
 			Type: "card",
 			Card: payment.Card{
@@ -123,7 +488,10 @@ func TestGetPayment_SuccessWithPrimaryProvider(t *testing.T) {
 			},
 		},
 	}
-	createdPayment, _ := service.ProcessPayment(context.Background(), paymentRequest)
+	createdPayment, err := service.ProcessPayment(context.Background(), paymentRequest)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
 	result, err := service.GetPayment(context.Background(), createdPayment.ID)
 	if err != nil {
@@ -135,32 +503,158 @@ func TestGetPayment_SuccessWithPrimaryProvider(t *testing.T) {
 	}
 }
 
-func TestRefundPayment_FailureWithBothProviders(t *testing.T) {
-	primary := paypalProvider.New()
-	secondary := stripeProvider.New()
+func TestRefundPayment_RoutedToOwningProvider(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(stripeProvider.New(), paypalProvider.New()))
 
-	service := &payment.Service{
-		PrimaryProvider:   primary,
-		SecondaryProvider: secondary,
+	createdPayment, err := service.ProcessPayment(context.Background(), &payment.Payment{
+		Amount: usd("10.00"),
+		Method: payment.Method{Type: "card"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
 	}
 
-	_, err := service.RefundPayment(context.Background(), "invalid-id")
-	if err == nil {
-		t.Fatalf("expected an error, got nil")
+	result, err := service.RefundPayment(context.Background(), createdPayment.ID, money.Decimal{}, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.ID != createdPayment.ID || result.Provider != "stripe" || result.Status != payment.StatusRefunded {
+		t.Fatalf("expected stripe payment to be voided and refunded, got %+v", result)
 	}
 }
 
-func TestGetPayment_FailureWithBothProviders(t *testing.T) {
-	primary := paypalProvider.New()
-	secondary := stripeProvider.New()
+func TestRefundPayment_UnknownPayment(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New(), stripeProvider.New()))
 
-	service := &payment.Service{
-		PrimaryProvider:   primary,
-		SecondaryProvider: secondary,
+	_, err := service.RefundPayment(context.Background(), "invalid-id", money.Decimal{}, "")
+	if err == nil {
+		t.Fatalf("expected an error, got nil")
 	}
+}
+
+func TestGetPayment_UnknownPayment(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(paypalProvider.New(), stripeProvider.New()))
 
 	_, err := service.GetPayment(context.Background(), "invalid-id")
 	if err == nil {
 		t.Fatalf("expected an error, got nil")
 	}
 }
+
+type failingProvider struct{}
+
+func (p *failingProvider) Name() string {
+	return "failing"
+}
+
+func (p *failingProvider) Charge(ctx context.Context, request payment.ChargeRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, errProviderUnavailable
+}
+
+func (p *failingProvider) Refund(ctx context.Context, request payment.RefundRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, errProviderUnavailable
+}
+
+func (p *failingProvider) Capture(ctx context.Context, request payment.CaptureRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, errProviderUnavailable
+}
+
+func (p *failingProvider) Cancel(ctx context.Context, request payment.CancelRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, errProviderUnavailable
+}
+
+func (p *failingProvider) Get(ctx context.Context, providerPaymentID string) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, errProviderUnavailable
+}
+
+func (p *failingProvider) FindByReference(ctx context.Context, reference string) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, errProviderUnavailable
+}
+
+func (p *failingProvider) Ping(ctx context.Context) error {
+	return errProviderUnavailable
+}
+
+var errProviderUnavailable = payment.NewProviderError("failing", payment.ErrorNetwork, 0, errors.New("provider unavailable"))
+
+// erroringProvider fails every call with the given error.
+type erroringProvider struct {
+	name string
+	err  error
+}
+
+func (p *erroringProvider) Name() string {
+	return p.name
+}
+
+func (p *erroringProvider) Charge(ctx context.Context, request payment.ChargeRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, p.err
+}
+
+func (p *erroringProvider) Refund(ctx context.Context, request payment.RefundRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, p.err
+}
+
+func (p *erroringProvider) Capture(ctx context.Context, request payment.CaptureRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, p.err
+}
+
+func (p *erroringProvider) Cancel(ctx context.Context, request payment.CancelRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, p.err
+}
+
+func (p *erroringProvider) Get(ctx context.Context, providerPaymentID string) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, p.err
+}
+
+func (p *erroringProvider) FindByReference(ctx context.Context, reference string) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, p.err
+}
+
+func (p *erroringProvider) Ping(ctx context.Context) error {
+	return p.err
+}
+
+// unknownOutcomeProvider loses every charge response. When charged is set the
+// charge did reach the provider and FindByReference recovers it, captured for
+// the 10.0 the tests charge.
+type unknownOutcomeProvider struct {
+	charged bool
+}
+
+func (p *unknownOutcomeProvider) Name() string {
+	return "unknown-outcome"
+}
+
+func (p *unknownOutcomeProvider) Charge(ctx context.Context, request payment.ChargeRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, fmt.Errorf("%w: %w", payment.ErrOutcomeUnknown, context.Canceled)
+}
+
+func (p *unknownOutcomeProvider) Refund(ctx context.Context, request payment.RefundRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, fmt.Errorf("%w: %w", payment.ErrOutcomeUnknown, context.Canceled)
+}
+
+func (p *unknownOutcomeProvider) Capture(ctx context.Context, request payment.CaptureRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, fmt.Errorf("%w: %w", payment.ErrOutcomeUnknown, context.Canceled)
+}
+
+func (p *unknownOutcomeProvider) Cancel(ctx context.Context, request payment.CancelRequest) (payment.ChargeResult, error) {
+	return payment.ChargeResult{}, fmt.Errorf("%w: %w", payment.ErrOutcomeUnknown, context.Canceled)
+}
+
+func (p *unknownOutcomeProvider) Get(ctx context.Context, providerPaymentID string) (payment.ChargeResult, error) {
+	return payment.ChargeResult{ProviderPaymentID: providerPaymentID, Status: payment.StatusCaptured, CapturedAmount: usd("10.00")}, nil
+}
+
+func (p *unknownOutcomeProvider) FindByReference(ctx context.Context, reference string) (payment.ChargeResult, error) {
+	if !p.charged {
+		return payment.ChargeResult{}, payment.ErrChargeNotFound
+	}
+
+	return payment.ChargeResult{ProviderPaymentID: "charge-" + reference, Status: payment.StatusCaptured, CapturedAmount: usd("10.00")}, nil
+}
+
+func (p *unknownOutcomeProvider) Ping(ctx context.Context) error {
+	return nil
+}
@@ -1,121 +1,241 @@
 package test
 
 import (
+	"context"
+	"errors"
 	paypalProvider "lucassaraiva5/api-pay/internal/app/providers/paypal"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
-	"lucassaraiva5/api-pay/internal/app/domain/paypal"
-	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
 
 	"github.com/google/uuid"
 )
 
-func TestPayPal_CreatePayment_Success(t *testing.T) {
+func TestPayPal_Charge_Success(t *testing.T) {
 	provider := paypalProvider.New()
 
-	request := &paypal.PaymentRequest{
-		Amount:   100.0,
-		Currency: "USD",
+	request := payment.ChargeRequest{
+		Amount: usd("100.00"),
 	}
 
-	response, err := provider.CreatePayment(request)
+	result, err := provider.Charge(context.Background(), request)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if response == nil {
-		t.Fatalf("expected a valid response, got nil")
+	if result.Status != payment.StatusCaptured {
+		t.Fatalf("expected status to be captured, got %s", result.Status)
 	}
 
-	payment := response.(*outbound.PaypalPaymentResponse)
-	if payment.Status != "authorized" {
-		t.Fatalf("expected status to be authorized, got %s", payment.Status)
+	if result.Amount != usd("100.00") {
+		t.Fatalf("expected amount to be 100.0, got %v", result.Amount)
 	}
 
-	if _, err := uuid.Parse(payment.ID); err != nil {
-		t.Fatalf("expected a valid UUID, got %s", payment.ID)
+	if _, err := uuid.Parse(result.ProviderPaymentID); err != nil {
+		t.Fatalf("expected a valid UUID, got %s", result.ProviderPaymentID)
 	}
 }
 
-func TestPayPal_CreatePayment_InvalidRequest(t *testing.T) {
+func TestPayPal_Refund_Success(t *testing.T) {
 	provider := paypalProvider.New()
 
-	request := struct {
-		InvalidField string
-	}{
-		InvalidField: "invalid",
+	request := payment.ChargeRequest{
+		Amount: usd("100.00"),
 	}
+	charge, _ := provider.Charge(context.Background(), request)
 
-	_, err := provider.CreatePayment(request)
-	if err == nil {
-		t.Fatalf("expected an error, got nil")
+	refunded, err := provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: charge.ProviderPaymentID})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if err.Error() != "invalid request type for PayPal" {
-		t.Fatalf("expected error 'invalid request type for PayPal', got %v", err)
+	if refunded.Status != payment.StatusRefunded {
+		t.Fatalf("expected status to be refunded, got %s", refunded.Status)
+	}
+
+	if refunded.Amount != usd("0.00") {
+		t.Fatalf("expected remaining amount to be 0.0, got %v", refunded.Amount)
 	}
 }
 
-func TestPayPal_Refund_Success(t *testing.T) {
+func TestPayPal_Refund_Partial(t *testing.T) {
 	provider := paypalProvider.New()
+	charge, _ := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("100.00")})
 
-	request := &paypal.PaymentRequest{
-		Amount:   100.0,
-		Currency: "USD",
-	}
-	response, _ := provider.CreatePayment(request)
-	payment := response.(*outbound.PaypalPaymentResponse)
-
-	refundResponse, err := provider.Refund(payment.ID)
+	refunded, err := provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: charge.ProviderPaymentID, Amount: usd("25.50"), Reason: "damaged"})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	refundedPayment := refundResponse.(*outbound.PaypalPaymentResponse)
-	if refundedPayment.Status != "refunded" {
-		t.Fatalf("expected status to be refunded, got %s", refundedPayment.Status)
+	if refunded.Status != payment.StatusPartiallyRefunded || refunded.Amount != usd("74.50") || refunded.RefundedAmount != usd("25.50") {
+		t.Fatalf("expected 25.5 of 100.0 to be refunded, got %+v", refunded)
 	}
 
-	if refundedPayment.Amount != 0.0 {
-		t.Fatalf("expected remaining amount to be 0.0, got %f", refundedPayment.Amount)
+	refunded, err = provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: charge.ProviderPaymentID, Amount: usd("74.50")})
+	if err != nil || refunded.Status != payment.StatusRefunded || refunded.Amount != usd("0.00") {
+		t.Fatalf("expected the remaining balance to be refunded, got %+v %v", refunded, err)
+	}
+}
+
+func TestPayPal_AuthorizeAndCapture(t *testing.T) {
+	provider := paypalProvider.New()
+	authorization, err := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("100.00"), AuthorizeOnly: true})
+	if err != nil || authorization.Status != payment.StatusAuthorized || authorization.CapturedAmount != usd("0.00") {
+		t.Fatalf("expected an authorization awaiting capture, got %+v %v", authorization, err)
+	}
+
+	captured, err := provider.Capture(context.Background(), payment.CaptureRequest{ProviderPaymentID: authorization.ProviderPaymentID, Amount: usd("40.00")})
+	if err != nil || captured.Status != payment.StatusCaptured || captured.CapturedAmount != usd("40.00") || captured.RefundedAmount != usd("0.00") {
+		t.Fatalf("expected 40.0 to be captured, got %+v %v", captured, err)
+	}
+
+	_, err = provider.Cancel(context.Background(), payment.CancelRequest{ProviderPaymentID: authorization.ProviderPaymentID})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorInvalidRequest {
+		t.Fatalf("expected cancel of a captured charge to be rejected, got %v", err)
+	}
+}
+
+func TestPayPal_CancelAuthorization(t *testing.T) {
+	provider := paypalProvider.New()
+	authorization, _ := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("100.00"), AuthorizeOnly: true})
+
+	expired, err := provider.Cancel(context.Background(), payment.CancelRequest{ProviderPaymentID: authorization.ProviderPaymentID, Reason: payment.CancelReasonExpired})
+	if err != nil || expired.Status != payment.StatusCanceled {
+		t.Fatalf("expected authorization to be released as expired, got %+v %v", expired, err)
+	}
+
+	_, err = provider.Capture(context.Background(), payment.CaptureRequest{ProviderPaymentID: authorization.ProviderPaymentID})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorInvalidRequest {
+		t.Fatalf("expected capture of an expired authorization to be rejected, got %v", err)
 	}
 }
 
 func TestPayPal_Refund_PaymentNotFound(t *testing.T) {
 	provider := paypalProvider.New()
 
-	_, err := provider.Refund("invalid-id")
+	_, err := provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: "invalid-id"})
 	if err == nil {
 		t.Fatalf("expected an error, got nil")
 	}
 }
 
-func TestPayPal_GetPayment_Success(t *testing.T) {
+func TestPayPal_Get_Success(t *testing.T) {
 	provider := paypalProvider.New()
 
-	request := &paypal.PaymentRequest{
-		Amount:   100.0,
-		Currency: "USD",
+	request := payment.ChargeRequest{
+		Amount: usd("100.00"),
 	}
-	response, _ := provider.CreatePayment(request)
-	payment := response.(*outbound.PaypalPaymentResponse)
+	charge, _ := provider.Charge(context.Background(), request)
 
-	getResponse, err := provider.GetPayment(payment.ID)
+	retrieved, err := provider.Get(context.Background(), charge.ProviderPaymentID)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	retrievedPayment := getResponse.(*outbound.PaypalPaymentResponse)
-	if retrievedPayment.ID != payment.ID {
-		t.Fatalf("expected payment ID to match, got %s", retrievedPayment.ID)
+	if retrieved.ProviderPaymentID != charge.ProviderPaymentID {
+		t.Fatalf("expected payment ID to match, got %s", retrieved.ProviderPaymentID)
 	}
 }
 
-func TestPayPal_GetPayment_PaymentNotFound(t *testing.T) {
+func TestPayPal_Get_PaymentNotFound(t *testing.T) {
 	provider := paypalProvider.New()
 
-	_, err := provider.GetPayment("invalid-id")
+	_, err := provider.Get(context.Background(), "invalid-id")
 	if err == nil {
 		t.Fatalf("expected an error, got nil")
 	}
 }
+
+func TestPayPal_Charge_CancelledContextIsUnknownOutcome(t *testing.T) {
+	server := newStalledServer(t)
+	t.Setenv("PAYPAL_MOCK_URL", server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := paypalProvider.NewWithClient(server.Client()).Charge(ctx, payment.ChargeRequest{Amount: usd("10.00")})
+	if !errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected unknown outcome error, got %v", err)
+	}
+}
+
+func TestPayPal_Charge_ClientTimeoutIsUnknownOutcome(t *testing.T) {
+	server := newStalledServer(t)
+	t.Setenv("PAYPAL_MOCK_URL", server.URL)
+
+	provider := paypalProvider.NewWithClient(&http.Client{Timeout: 50 * time.Millisecond})
+
+	_, err := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("10.00")})
+	if !errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected unknown outcome error, got %v", err)
+	}
+}
+
+func TestPayPal_Charge_UnsentRequestIsPlainFailure(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	t.Setenv("PAYPAL_MOCK_URL", server.URL)
+
+	_, err := paypalProvider.New().Charge(context.Background(), payment.ChargeRequest{Amount: usd("10.00")})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorNetwork || errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected a network failure for a request never sent, got %v", err)
+	}
+}
+
+func TestPayPal_Charge_Declined(t *testing.T) {
+	_, err := paypalProvider.New().Charge(context.Background(), payment.ChargeRequest{
+		Amount: usd("10.00"),
+		Method: payment.Method{Type: "card", Card: payment.Card{Number: "4000000000000002"}},
+	})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorDeclined {
+		t.Fatalf("expected a declined error, got %v", err)
+	}
+}
+
+func TestPayPal_Charge_CancelledBeforeSendIsPlainFailure(t *testing.T) {
+	server := newStalledServer(t)
+	t.Setenv("PAYPAL_MOCK_URL", server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := paypalProvider.NewWithClient(server.Client()).Charge(ctx, payment.ChargeRequest{Amount: usd("10.00")})
+	if err == nil || errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected a plain failure for a request never sent, got %v", err)
+	}
+}
+
+func TestPayPal_FindByReference_RecoversCharge(t *testing.T) {
+	provider := paypalProvider.New()
+	reference := uuid.New().String()
+
+	charge, err := provider.Charge(context.Background(), payment.ChargeRequest{PaymentID: reference, Amount: usd("10.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	found, err := provider.FindByReference(context.Background(), reference)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if found.ProviderPaymentID != charge.ProviderPaymentID {
+		t.Fatalf("expected charge %s, got %s", charge.ProviderPaymentID, found.ProviderPaymentID)
+	}
+}
+
+func TestPayPal_FindByReference_NotFound(t *testing.T) {
+	_, err := paypalProvider.New().FindByReference(context.Background(), uuid.New().String())
+	if !errors.Is(err, payment.ErrChargeNotFound) {
+		t.Fatalf("expected ErrChargeNotFound, got %v", err)
+	}
+}
+
+func TestPayPal_Ping(t *testing.T) {
+	if err := paypalProvider.New().Ping(context.Background()); err != nil {
+		t.Fatalf("expected provider to be healthy, got %v", err)
+	}
+}
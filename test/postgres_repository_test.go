@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	paymentRepository "lucassaraiva5/api-pay/internal/app/repositories/payment"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMockDatabase builds a database.Database whose connections are served by
+// sqlmock, so the SQL issued by the repositories can be asserted without a
+// running Postgres.
+func newMockDatabase(t *testing.T) (*database.Database, sqlmock.Sqlmock) {
+	dsn := t.Name()
+	db, mock, err := sqlmock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatalf("expected sqlmock to start, got %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	mockDatabase := database.NewDatabase(&database.SqlConfig{Driver: "sqlmock", LazyConnection: true}, func(cfg *database.SqlConfig) string {
+		return dsn
+	})
+	t.Cleanup(mockDatabase.Close)
+
+	return mockDatabase, mock
+}
+
+func newMockRepository(t *testing.T) (*paymentRepository.Repository, sqlmock.Sqlmock) {
+	mockDatabase, mock := newMockDatabase(t)
+	return paymentRepository.New(&database.Databases{Read: mockDatabase, Write: mockDatabase}), mock
+}
+
+func TestPostgresRepository_CreateStoresPaymentAndHistory(t *testing.T) {
+	repository, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO payments")).
+		WithArgs("payment-1", "10.00", "USD", "", payment.StatusPending, "card", "", "", "", "", false, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO payment_status_history")).
+		WithArgs("payment-1", "", payment.StatusPending).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := repository.Create(context.Background(), &payment.Payment{
+		ID:     "payment-1",
+		Amount: usd("10.00"),
+		Status: payment.StatusPending,
+		Method: payment.Method{Type: "card"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_UpdateAppendsHistoryOnStatusChange(t *testing.T) {
+	repository, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT status FROM payments WHERE id = $1 FOR UPDATE")).
+		WithArgs("payment-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(payment.StatusPending))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE payments")).
+		WithArgs("payment-1", "authorized", "paypal", "charge-1", "0", "0").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO payment_status_history")).
+		WithArgs("payment-1", payment.StatusPending, "authorized").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO payment_events")).
+		WithArgs(sqlmock.AnyArg(), "payment-1", payment.EventAuthorized, "", sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repository.Update(context.Background(), &payment.Payment{ID: "payment-1", Status: "authorized", Provider: "paypal", ProviderPaymentID: "charge-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_UpdateKeepsHistoryWhenStatusIsUnchanged(t *testing.T) {
+	repository, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT status FROM payments WHERE id = $1 FOR UPDATE")).
+		WithArgs("payment-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("authorized"))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE payments")).
+		WithArgs("payment-1", "authorized", "paypal", "charge-1", "0", "0").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO payment_events")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repository.Update(context.Background(), &payment.Payment{ID: "payment-1", Status: "authorized", Provider: "paypal", ProviderPaymentID: "charge-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_UpdateRollsBackWhenEventIsNotStored(t *testing.T) {
+	repository, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT status FROM payments WHERE id = $1 FOR UPDATE")).
+		WithArgs("payment-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(payment.StatusPending))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE payments")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO payment_status_history")).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO payment_events")).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	err := repository.Update(context.Background(), &payment.Payment{ID: "payment-1", Status: "captured", Provider: "paypal", ProviderPaymentID: "charge-1"})
+	if err == nil {
+		t.Fatal("expected the update to fail without its event")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_ReserveRefundCountsPendingRefunds(t *testing.T) {
+	repository, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT captured_amount, refunded_amount, currency FROM payments WHERE id = $1 FOR UPDATE")).
+		WithArgs("payment-1").
+		WillReturnRows(sqlmock.NewRows([]string{"captured_amount", "refunded_amount", "currency"}).AddRow("100.0000", "20.0000", "USD"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(amount), 0) FROM payment_refunds")).
+		WithArgs("payment-1", payment.RefundPending).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("50.0000"))
+	mock.ExpectRollback()
+
+	err := repository.ReserveRefund(context.Background(), &payment.Refund{ID: "refund-1", PaymentID: "payment-1", Amount: usd("30.01"), Status: payment.RefundPending})
+	if err != payment.ErrRefundExceedsBalance {
+		t.Fatalf("expected ErrRefundExceedsBalance, got %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_UpdateUnknownPayment(t *testing.T) {
+	repository, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT status FROM payments WHERE id = $1 FOR UPDATE")).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+	mock.ExpectRollback()
+
+	err := repository.Update(context.Background(), &payment.Payment{ID: "missing", Status: "authorized"})
+	if err != payment.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"testing"
+)
+
+type namedProvider struct {
+	failingProvider
+	name string
+}
+
+func (p *namedProvider) Name() string {
+	return p.name
+}
+
+func providerNames(providers []payment.Provider) []string {
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	return names
+}
+
+func TestRegistry_KeepsRegistrationOrder(t *testing.T) {
+	registry := payment.NewRegistry(&namedProvider{name: "b"}, &namedProvider{name: "a"}, &namedProvider{name: "c"})
+
+	names := providerNames(registry.Ordered())
+	if len(names) != 3 || names[0] != "b" || names[1] != "a" || names[2] != "c" {
+		t.Fatalf("expected order [b a c], got %v", names)
+	}
+}
+
+func TestRegistry_DropsDuplicateNamesAndNil(t *testing.T) {
+	first := &namedProvider{name: "a"}
+	registry := payment.NewRegistry(first, nil, &namedProvider{name: "a"})
+
+	if names := providerNames(registry.Ordered()); len(names) != 1 {
+		t.Fatalf("expected a single provider, got %v", names)
+	}
+
+	if provider, ok := registry.Get("a"); !ok || provider != first {
+		t.Fatalf("expected the first registration to win, got %v", provider)
+	}
+}
+
+func TestRegistry_GetUnknownProvider(t *testing.T) {
+	registry := payment.NewRegistry(&namedProvider{name: "a"})
+
+	if _, ok := registry.Get("b"); ok {
+		t.Fatalf("expected unknown provider lookup to fail")
+	}
+}
+
+func TestRegistry_OrderedReturnsCopy(t *testing.T) {
+	registry := payment.NewRegistry(&namedProvider{name: "a"}, &namedProvider{name: "b"})
+
+	registry.Ordered()[0] = &namedProvider{name: "z"}
+
+	if names := providerNames(registry.Ordered()); names[0] != "a" {
+		t.Fatalf("expected registry to be unaffected by callers, got %v", names)
+	}
+}
+
+func TestNewProviderRegistry_FollowsConfiguredOrder(t *testing.T) {
+	registry, err := domain.NewProviderRegistry([]string{"stripe", "paypal"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	names := providerNames(registry.Ordered())
+	if len(names) != 2 || names[0] != "stripe" || names[1] != "paypal" {
+		t.Fatalf("expected order [stripe paypal], got %v", names)
+	}
+}
+
+func TestNewProviderRegistry_RejectsEmptyConfiguration(t *testing.T) {
+	if _, err := domain.NewProviderRegistry(nil); !errors.Is(err, domain.ErrNoProvidersConfigured) {
+		t.Fatalf("expected ErrNoProvidersConfigured, got %v", err)
+	}
+}
+
+func TestNewProviderRegistry_RejectsUnknownProvider(t *testing.T) {
+	if _, err := domain.NewProviderRegistry([]string{"paypal", "acme"}); err == nil {
+		t.Fatalf("expected an error for an unknown provider")
+	}
+}
+
+func TestProcessPayment_NoProviders(t *testing.T) {
+	service := payment.New(newMemoryRepository(), payment.NewRegistry())
+
+	if _, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("1.00")}); err == nil {
+		t.Fatalf("expected an error without providers")
+	}
+}
+
+func TestNewFailoverPolicy_RejectsUnknownKind(t *testing.T) {
+	if _, err := domain.NewFailoverPolicy([]string{"network", "timeout"}); err == nil {
+		t.Fatalf("expected timeouts not to be configurable for failover")
+	}
+
+	policy, err := domain.NewFailoverPolicy([]string{"network", "provider_error"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !policy.Allows(payment.NewProviderError("p", payment.ErrorProvider, 500, errors.New("boom"))) {
+		t.Fatalf("expected configured kinds to fail over")
+	}
+}
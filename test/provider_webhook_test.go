@@ -0,0 +1,153 @@
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/webhook"
+	paypalProvider "lucassaraiva5/api-pay/internal/app/providers/paypal"
+	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// chargedPayment stores a captured payment of 10.00 USD charged by provider.
+func chargedPayment(t *testing.T, repository *memoryRepository, provider string) *payment.Payment {
+	t.Helper()
+
+	stored := &payment.Payment{
+		ID:                "payment-" + provider,
+		Amount:            usd("10.00"),
+		CapturedAmount:    usd("10.00"),
+		RefundedAmount:    usd("0"),
+		Status:            payment.StatusCaptured,
+		Provider:          provider,
+		ProviderPaymentID: "charge-1",
+		CreatedAt:         time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if err := repository.Create(context.Background(), stored); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	return stored
+}
+
+func postWebhook(e *echo.Echo, target string, header http.Header, body string) (*httptest.ResponseRecorder, outbound.ProviderWebhookResponse) {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for key := range header {
+		req.Header.Set(key, header.Get(key))
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var response outbound.ProviderWebhookResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec, response
+}
+
+func stripeWebhook(secret string, body string) http.Header {
+	return http.Header{"Stripe-Signature": {webhook.Sign(secret, time.Now(), []byte(body))}}
+}
+
+func paypalWebhook(secret string, webhookID string, body string) http.Header {
+	transmissionID, transmissionTime := "transmission-1", time.Now().UTC().Format(time.RFC3339)
+	signature := paypalProvider.Sign(secret, transmissionID, transmissionTime, webhookID, []byte(body))
+
+	return http.Header{
+		"Paypal-Transmission-Id":   {transmissionID},
+		"Paypal-Transmission-Time": {transmissionTime},
+		"Paypal-Transmission-Sig":  {base64.StdEncoding.EncodeToString(signature)},
+	}
+}
+
+func TestStripeWebhook_AppliesAsyncRefundOnce(t *testing.T) {
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_stripe")
+	repository := newMemoryRepository()
+	stored := chargedPayment(t, repository, "stripe")
+	e := newPaymentServer(repository, stripeProvider.New())
+
+	body := `{"id":"evt_1","type":"transaction.voided","created":0,"data":{"object":{"id":"charge-1","status":"voided","amount":0,"capturedAmount":1000,"currency":"USD","reference":"` + stored.ID + `"}}}`
+
+	rec, response := postWebhook(e, "/webhooks/stripe", stripeWebhook("whsec_stripe", body), body)
+	if rec.Code != http.StatusOK || response.Outcome != payment.ProviderEventProcessed {
+		t.Fatalf("expected the event to be processed, got %d %s", rec.Code, rec.Body)
+	}
+
+	refunded, _ := repository.FindByID(context.Background(), stored.ID)
+	if refunded.Status != payment.StatusRefunded || refunded.RefundedAmount != usd("10.00") {
+		t.Fatalf("expected the payment to be refunded, got %s %v", refunded.Status, refunded.RefundedAmount)
+	}
+
+	if _, response = postWebhook(e, "/webhooks/stripe", stripeWebhook("whsec_stripe", body), body); response.Outcome != payment.ProviderEventDuplicate {
+		t.Fatalf("expected the replayed event to be a duplicate, got %s", response.Outcome)
+	}
+}
+
+func TestStripeWebhook_RejectsBadSignatures(t *testing.T) {
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_stripe")
+	repository := newMemoryRepository()
+	stored := chargedPayment(t, repository, "stripe")
+	e := newPaymentServer(repository, stripeProvider.New())
+
+	body := `{"id":"evt_1","type":"transaction.voided","data":{"object":{"id":"charge-1","status":"voided","currency":"USD","reference":"` + stored.ID + `"}}}`
+
+	if rec, _ := postWebhook(e, "/webhooks/stripe", stripeWebhook("whsec_other", body), body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected another secret to be refused, got %d", rec.Code)
+	}
+
+	stale := http.Header{"Stripe-Signature": {webhook.Sign("whsec_stripe", time.Now().Add(-time.Hour), []byte(body))}}
+	if rec, _ := postWebhook(e, "/webhooks/stripe", stale, body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a replayed signature to be refused, got %d", rec.Code)
+	}
+
+	if unchanged, _ := repository.FindByID(context.Background(), stored.ID); unchanged.Status != payment.StatusCaptured {
+		t.Fatalf("expected the payment to be untouched, got %s", unchanged.Status)
+	}
+}
+
+func TestPaypalWebhook_VerifiesTransmissionSignature(t *testing.T) {
+	t.Setenv("PAYPAL_WEBHOOK_SECRET", "paypal-secret")
+	t.Setenv("PAYPAL_WEBHOOK_ID", "webhook-1")
+	repository := newMemoryRepository()
+	stored := chargedPayment(t, repository, "paypal")
+	e := newPaymentServer(repository, paypalProvider.New())
+
+	body := `{"id":"WH-1","event_type":"CHARGE.REFUNDED","resource":{"id":"charge-1","status":"partially_refunded","currentAmount":400,"capturedAmount":1000,"currency":"USD","reference":"` + stored.ID + `"}}`
+
+	if rec, _ := postWebhook(e, "/webhooks/paypal", paypalWebhook("paypal-secret", "webhook-2", body), body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a signature for another webhook to be refused, got %d", rec.Code)
+	}
+
+	rec, response := postWebhook(e, "/webhooks/paypal", paypalWebhook("paypal-secret", "webhook-1", body), body)
+	if rec.Code != http.StatusOK || response.Outcome != payment.ProviderEventProcessed {
+		t.Fatalf("expected the event to be processed, got %d %s", rec.Code, rec.Body)
+	}
+
+	refunded, _ := repository.FindByID(context.Background(), stored.ID)
+	if refunded.Status != payment.StatusPartiallyRefunded || refunded.RefundedAmount != usd("6.00") {
+		t.Fatalf("expected a partial refund of 6.00, got %s %v", refunded.Status, refunded.RefundedAmount)
+	}
+
+	unknown := strings.Replace(strings.Replace(body, stored.ID, "missing", 1), "WH-1", "WH-2", 1)
+	if _, response = postWebhook(e, "/webhooks/paypal", paypalWebhook("paypal-secret", "webhook-1", unknown), unknown); response.Outcome != payment.ProviderEventIgnored {
+		t.Fatalf("expected an event for an unknown payment to be ignored, got %s", response.Outcome)
+	}
+}
+
+func TestProviderWebhook_RefusedWithoutSecret(t *testing.T) {
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "")
+	e := newPaymentServer(newMemoryRepository(), stripeProvider.New())
+
+	if rec, _ := postWebhook(e, "/webhooks/stripe", nil, `{}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected webhooks to be refused without a secret, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/ratelimit"
+	"lucassaraiva5/api-pay/internal/infra/server"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+)
+
+// newRateLimitedServer limits requests to two a minute per X-Merchant header.
+func newRateLimitedServer(t *testing.T) (*echo.Echo, *miniredis.Miniredis) {
+	store := miniredis.RunT(t)
+
+	e := echo.New()
+	e.HTTPErrorHandler = server.HTTPErrorHandler
+	rateLimit := middleware.RateLimitWithConfig(middleware.RateLimitConfig{
+		Limiter: ratelimit.New(database.NewRedis(&redis.Options{Addr: store.Addr()}, true)),
+		Limits: []middleware.RateLimit{{
+			Name:   "merchant",
+			Limit:  2,
+			Window: time.Minute,
+			Key: func(c echo.Context) (string, error) {
+				return c.Request().Header.Get("X-Merchant"), nil
+			},
+		}},
+	})
+
+	e.POST("/payments", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, rateLimit)
+
+	return e, store
+}
+
+func doRateLimitedRequest(e *echo.Echo, merchantID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/payments", nil)
+	req.Header.Set("X-Merchant", merchantID)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit_RefusesRequestsOverTheLimit(t *testing.T) {
+	e, _ := newRateLimitedServer(t)
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := doRateLimitedRequest(e, "acme")
+		if rec.Code != http.StatusOK || rec.Header().Get(middleware.HeaderRateLimitRemaining) != remaining || rec.Header().Get(middleware.HeaderRateLimitLimit) != "2" {
+			t.Fatalf("expected request %d to be admitted with %s remaining, got %d %v", i+1, remaining, rec.Code, rec.Header())
+		}
+	}
+
+	rec := doRateLimitedRequest(e, "acme")
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), `"rate_limited"`) {
+		t.Fatalf("expected 429 rate_limited, got %d %s", rec.Code, rec.Body)
+	}
+
+	if retryAfter := rec.Header().Get(middleware.HeaderRetryAfter); retryAfter != "60" || rec.Header().Get(middleware.HeaderRateLimitReset) != "60" {
+		t.Fatalf("expected to retry once the window moved, got Retry-After %q", retryAfter)
+	}
+
+	if rec := doRateLimitedRequest(e, "globex"); rec.Code != http.StatusOK {
+		t.Fatalf("expected another merchant to keep its own limit, got %d", rec.Code)
+	}
+
+	if rec := doRateLimitedRequest(e, ""); rec.Code != http.StatusOK || rec.Header().Get(middleware.HeaderRateLimitLimit) != "" {
+		t.Fatalf("expected requests without a key not to be limited, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRateLimit_LimitsInMemoryWhileRedisIsDown(t *testing.T) {
+	e, store := newRateLimitedServer(t)
+	store.Close()
+
+	for range 2 {
+		if rec := doRateLimitedRequest(e, "acme"); rec.Code != http.StatusOK {
+			t.Fatalf("expected the request to be admitted without Redis, got %d %s", rec.Code, rec.Body)
+		}
+	}
+
+	if rec := doRateLimitedRequest(e, "acme"); rec.Code != http.StatusTooManyRequests || rec.Header().Get(middleware.HeaderRetryAfter) == "" {
+		t.Fatalf("expected the in-memory limit to refuse the request, got %d", rec.Code)
+	}
+}
+
+func TestLimiter_SlidesTheWindow(t *testing.T) {
+	store := miniredis.RunT(t)
+	limiter := ratelimit.New(database.NewRedis(&redis.Options{Addr: store.Addr()}, true))
+
+	for range 2 {
+		if result := limiter.Allow(context.Background(), "card:abc", 2, 100*time.Millisecond); !result.Allowed {
+			t.Fatalf("expected the request to be admitted, got %+v", result)
+		}
+	}
+
+	if result := limiter.Allow(context.Background(), "card:abc", 2, 100*time.Millisecond); result.Allowed || result.Reset <= 0 {
+		t.Fatalf("expected the request to be refused until the window moves, got %+v", result)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	store.FastForward(150 * time.Millisecond)
+
+	if result := limiter.Allow(context.Background(), "card:abc", 2, 100*time.Millisecond); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("expected the window to have moved on, got %+v", result)
+	}
+}
+
+func TestLimiter_StaysInMemoryUntilRedisRetryIsDue(t *testing.T) {
+	store := miniredis.RunT(t)
+	limiter := ratelimit.New(database.NewRedis(&redis.Options{Addr: store.Addr()}, true))
+	limiter.RetryBackoff = 200 * time.Millisecond
+
+	store.Close()
+	if result := limiter.Allow(context.Background(), "ip:1", 5, time.Minute); !result.Allowed || result.Remaining != 4 {
+		t.Fatalf("expected the request to be limited in memory, got %+v", result)
+	}
+
+	if err := store.Restart(); err != nil {
+		t.Fatalf("expected redis to restart, got %v", err)
+	}
+
+	if result := limiter.Allow(context.Background(), "ip:1", 5, time.Minute); result.Remaining != 3 || len(store.Keys()) != 0 {
+		t.Fatalf("expected redis to be left alone during the backoff, got %+v", result)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if result := limiter.Allow(context.Background(), "ip:1", 5, time.Minute); result.Remaining != 4 || len(store.Keys()) != 1 {
+		t.Fatalf("expected redis to be used again after the backoff, got %+v", result)
+	}
+}
+
+func TestIPExtractor_TrustsForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	request := func(remoteAddr string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+		return req
+	}
+
+	if ip := server.IPExtractor(nil)(request("198.51.100.1:1234")); ip != "198.51.100.1" {
+		t.Fatalf("expected the connection address without trusted proxies, got %s", ip)
+	}
+
+	extract := server.IPExtractor([]string{"10.0.0.0/8"})
+	if ip := extract(request("10.1.2.3:1234")); ip != "203.0.113.7" {
+		t.Fatalf("expected the forwarded address from a trusted proxy, got %s", ip)
+	}
+
+	if ip := extract(request("192.168.1.1:1234")); ip != "192.168.1.1" {
+		t.Fatalf("expected the forwarded address to be ignored from another proxy, got %s", ip)
+	}
+}
+
+func TestVault_CardFingerprintMatchesAcrossTokens(t *testing.T) {
+	service := newTestVault(t, newMemoryVaultRepository(), vaultKey(t, "k1"))
+	ctx := merchantContext("acme", false)
+
+	first, _ := service.Tokenize(ctx, testCard())
+	second, _ := service.Tokenize(ctx, testCard())
+
+	byNumber, err := service.CardFingerprint(ctx, testCard().Number, "")
+	if err != nil || byNumber == "" {
+		t.Fatalf("expected a fingerprint, got %q %v", byNumber, err)
+	}
+
+	for _, token := range []*vault.Token{first, second} {
+		if fingerprint, err := service.CardFingerprint(ctx, "", token.ID); err != nil || fingerprint != byNumber {
+			t.Fatalf("expected token %s to have the card fingerprint, got %q %v", token.ID, fingerprint, err)
+		}
+	}
+
+	if fingerprint, err := service.CardFingerprint(merchantContext("globex", false), "", first.ID); err != nil || fingerprint != "" {
+		t.Fatalf("expected another merchant's token not to be found, got %q %v", fingerprint, err)
+	}
+
+	if fingerprint, err := service.CardFingerprint(ctx, "", "tok_missing"); err != nil || fingerprint != "" {
+		t.Fatalf("expected a missing token to have no fingerprint, got %q %v", fingerprint, err)
+	}
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"lucassaraiva5/api-pay/internal/infra/logger"
+	"lucassaraiva5/api-pay/internal/infra/logger/attributes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedact_MasksCardNumbersInText(t *testing.T) {
+	clean := logger.Redact(attributes.Attributes{
+		"message": "charge for 4111 1111 1111 1111 failed",
+		"id":      "1234567890123",
+	}.WithError(errors.New(`provider rejected {"number":"5555555555554444","cvv":"123"}`)))
+
+	if clean["message"] != "charge for ************1111 failed" {
+		t.Fatalf("expected the number to be masked, got %v", clean["message"])
+	}
+
+	if clean["id"] != "1234567890123" {
+		t.Fatalf("expected digits failing the Luhn check to be kept, got %v", clean["id"])
+	}
+
+	if message := clean["exception.message"].(string); strings.Contains(message, "5555555555554444") || strings.Contains(message, "123\"") {
+		t.Fatalf("expected the error message to be scrubbed, got %s", message)
+	}
+}
+
+func TestRedact_DropsSensitiveKeysAtAnyDepth(t *testing.T) {
+	request := inbound.CreatePaymentRequest{
+		Currency: "USD",
+		Method:   inbound.Method{Type: "card", Card: inbound.Card{Number: "4111111111111111", CVV: "123", Holder: "John Doe"}},
+	}
+
+	clean := logger.Redact(attributes.Attributes{
+		"request":       request,
+		"Authorization": "Bearer secret",
+		"attempts":      3,
+	})
+
+	encoded := fmt.Sprint(clean)
+	if strings.Contains(encoded, "4111111111111111") || strings.Contains(encoded, "123") || strings.Contains(encoded, "Bearer") {
+		t.Fatalf("expected card data and credentials to be redacted, got %s", encoded)
+	}
+
+	if !strings.Contains(encoded, "John Doe") || clean["attempts"] != 3 {
+		t.Fatalf("expected other values to be kept, got %s", encoded)
+	}
+}
+
+func TestPaymentHandler_ResponseMasksCard(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), &cardRecordingProvider{namedProvider: namedProvider{name: "recording"}})
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", cardPaymentBody("10", "USD", ""))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Contains(body, "4111111111111111") || strings.Contains(body, `"cvv"`) {
+		t.Fatalf("expected a masked card, got %d %s", rec.Code, body)
+	}
+
+	if !strings.Contains(body, `"brand":"visa"`) || !strings.Contains(body, `"last4":"1111"`) {
+		t.Fatalf("expected brand and last4, got %s", body)
+	}
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func routingRegistry() *payment.Registry {
+	return payment.NewRegistry(&namedProvider{name: "paypal"}, &namedProvider{name: "stripe"}, &namedProvider{name: "adyen"})
+}
+
+func cardPayment(amount string, currency string, number string, installments int) *payment.Payment {
+	return &payment.Payment{
+		Amount: amountIn(amount, currency),
+		Method: payment.Method{
+			Type: "card",
+			Card: payment.Card{Number: number, InstallmentNumber: installments},
+		},
+	}
+}
+
+func newTestRouter(t *testing.T, config payment.RoutingConfig) *payment.Router {
+	t.Helper()
+
+	router, err := payment.NewRouter(routingRegistry(), config)
+	if err != nil {
+		t.Fatalf("expected valid routing config, got %v", err)
+	}
+
+	return router
+}
+
+func TestRouter_DefaultsToRegistryOrder(t *testing.T) {
+	router := newTestRouter(t, payment.RoutingConfig{})
+
+	route := router.Route(cardPayment("10", "USD", "4111111111111111", 1))
+	if names := providerNames(route.Providers); route.Rule != "default" || len(names) != 3 || names[0] != "paypal" {
+		t.Fatalf("expected default registry order, got %s %v", route.Rule, names)
+	}
+}
+
+func TestRouter_MatchesCriteria(t *testing.T) {
+	router := newTestRouter(t, payment.RoutingConfig{Rules: []payment.RoutingRule{
+		{Name: "brl", Currencies: []string{"BRL"}, Strategy: payment.RouteOrdered, Providers: []string{"adyen"}},
+		{Name: "large", MinAmount: dec("1000"), Strategy: payment.RouteOrdered, Providers: []string{"stripe"}},
+		{Name: "bin", BINs: []string{"555555"}, Strategy: payment.RouteOrdered, Providers: []string{"adyen", "stripe"}},
+		{Name: "amex", Brands: []string{payment.BrandAmex}, Strategy: payment.RouteOrdered, Providers: []string{"stripe", "paypal"}},
+		{Name: "installments", MinInstallments: 2, MaxInstallments: 12, Strategy: payment.RouteOrdered, Providers: []string{"paypal"}},
+	}})
+
+	tests := []struct {
+		payment *payment.Payment
+		rule    string
+		first   string
+	}{
+		{cardPayment("10", "brl", "4111111111111111", 1), "brl", "adyen"},
+		{cardPayment("5000", "USD", "4111111111111111", 1), "large", "stripe"},
+		{cardPayment("10", "USD", "5555555555554444", 1), "bin", "adyen"},
+		{cardPayment("10", "USD", "378282246310005", 1), "amex", "stripe"},
+		{cardPayment("10", "USD", "4111111111111111", 6), "installments", "paypal"},
+		{cardPayment("10", "USD", "4111111111111111", 24), "default", "paypal"},
+	}
+
+	for _, test := range tests {
+		route := router.Route(test.payment)
+		if route.Rule != test.rule || len(route.Providers) == 0 || route.Providers[0].Name() != test.first {
+			t.Errorf("expected rule %s starting with %s, got %s %v", test.rule, test.first, route.Rule, providerNames(route.Providers))
+		}
+	}
+}
+
+func TestRouter_CostPicksCheapestProvider(t *testing.T) {
+	router := newTestRouter(t, payment.RoutingConfig{
+		Fees: map[string]payment.Fee{
+			"paypal": {Percent: 3, Fixed: 0.1},
+			"stripe": {Percent: 2, Fixed: 5},
+			"adyen":  {Percent: 2.5, Fixed: 1},
+		},
+		Rules: []payment.RoutingRule{{Name: "cost", Strategy: payment.RouteCost}},
+	})
+
+	// paypal 0.4, adyen 1.25, stripe 5.2
+	if names := providerNames(router.Route(cardPayment("10", "USD", "4111111111111111", 1)).Providers); names[0] != "paypal" || names[2] != "stripe" {
+		t.Fatalf("expected paypal first and stripe last for small amounts, got %v", names)
+	}
+
+	// stripe 205, adyen 251, paypal 300.1
+	if names := providerNames(router.Route(cardPayment("10000", "USD", "4111111111111111", 1)).Providers); names[0] != "stripe" || names[2] != "paypal" {
+		t.Fatalf("expected stripe first and paypal last for large amounts, got %v", names)
+	}
+}
+
+func TestRouter_WeightedSplit(t *testing.T) {
+	router := newTestRouter(t, payment.RoutingConfig{Rules: []payment.RoutingRule{
+		{Name: "split", Strategy: payment.RouteWeighted, Weights: map[string]int{"paypal": 70, "stripe": 30}},
+	}})
+
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		route := router.Route(cardPayment("10", "USD", "4111111111111111", 1))
+		if len(route.Providers) != 2 || route.Providers[0].Name() == route.Providers[1].Name() {
+			t.Fatalf("expected both weighted providers, got %v", providerNames(route.Providers))
+		}
+		counts[route.Providers[0].Name()]++
+	}
+
+	if counts["paypal"] < 1200 || counts["paypal"] > 1600 {
+		t.Fatalf("expected about 70%% of payments on paypal, got %v", counts)
+	}
+}
+
+func TestRouter_ZeroWeightIsOnlyAFallback(t *testing.T) {
+	router := newTestRouter(t, payment.RoutingConfig{Rules: []payment.RoutingRule{
+		{Name: "split", Strategy: payment.RouteWeighted, Weights: map[string]int{"paypal": 0, "stripe": 100}},
+	}})
+
+	for i := 0; i < 100; i++ {
+		if names := providerNames(router.Route(cardPayment("10", "USD", "4111111111111111", 1)).Providers); names[0] != "stripe" || names[1] != "paypal" {
+			t.Fatalf("expected stripe first and paypal as fallback, got %v", names)
+		}
+	}
+}
+
+func TestNewRouter_RejectsInvalidRules(t *testing.T) {
+	configs := map[string]payment.RoutingConfig{
+		"unknown provider": {Rules: []payment.RoutingRule{{Name: "r", Strategy: payment.RouteOrdered, Providers: []string{"square"}}}},
+		"unknown strategy": {Rules: []payment.RoutingRule{{Name: "r", Strategy: "random", Providers: []string{"paypal"}}}},
+		"missing name":     {Rules: []payment.RoutingRule{{Strategy: payment.RouteOrdered, Providers: []string{"paypal"}}}},
+		"no weights":       {Rules: []payment.RoutingRule{{Name: "r", Strategy: payment.RouteWeighted}}},
+		"unknown fee":      {Fees: map[string]payment.Fee{"square": {Percent: 1}}},
+	}
+
+	for name, config := range configs {
+		if _, err := payment.NewRouter(routingRegistry(), config); err == nil {
+			t.Errorf("%s: expected routing config to be rejected", name)
+		}
+	}
+}
+
+func TestCardBrand(t *testing.T) {
+	brands := map[string]string{
+		"4111111111111111": payment.BrandVisa,
+		"5555555555554444": payment.BrandMastercard,
+		"2221000000000009": payment.BrandMastercard,
+		"378282246310005":  payment.BrandAmex,
+		"6011111111111117": payment.BrandDiscover,
+		"6362970000457013": payment.BrandElo,
+		"9999999999999999": payment.BrandUnknown,
+	}
+
+	for number, brand := range brands {
+		if got := payment.CardBrand(number); got != brand {
+			t.Errorf("expected %s for %s, got %s", brand, number, got)
+		}
+	}
+}
+
+func TestProcessPayment_UsesRoutedProviders(t *testing.T) {
+	primary := &countingProvider{erroringProvider: erroringProvider{name: "paypal", err: networkError()}}
+	registry := payment.NewRegistry(primary, &namedProvider{name: "stripe"})
+	router, err := payment.NewRouter(registry, payment.RoutingConfig{Rules: []payment.RoutingRule{
+		{Name: "stripe-only", Currencies: []string{"EUR"}, Strategy: payment.RouteOrdered, Providers: []string{"stripe"}},
+	}})
+	if err != nil {
+		t.Fatalf("expected valid routing config, got %v", err)
+	}
+
+	service := payment.New(newMemoryRepository(), registry)
+	service.Router = router
+
+	_, _ = service.ProcessPayment(context.Background(), cardPayment("10", "EUR", "4111111111111111", 1))
+
+	if calls := primary.charges.Load(); calls != 0 {
+		t.Fatalf("expected paypal to be skipped by the routing rule, got %d charges", calls)
+	}
+}
+
+func TestNewRouter_LoadsExampleConfig(t *testing.T) {
+	registry, err := domain.NewProviderRegistry([]string{"paypal", "stripe"})
+	if err != nil {
+		t.Fatalf("expected registry, got %v", err)
+	}
+
+	if _, err = domain.NewRouter(registry, filepath.Join("..", "routing.example.json")); err != nil {
+		t.Fatalf("expected example routing config to load, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "routing.json")
+	if err = os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = domain.NewRouter(registry, path); err == nil {
+		t.Fatalf("expected malformed routing config to be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	paypalProvider "lucassaraiva5/api-pay/internal/app/providers/paypal"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"net/http"
+	"testing"
+)
+
+func TestStatus_Transitions(t *testing.T) {
+	cases := []struct {
+		from, to payment.Status
+		allowed  bool
+	}{
+		{payment.StatusPending, payment.StatusCaptured, true},
+		{payment.StatusPending, payment.StatusFailed, true},
+		{payment.StatusAuthorized, payment.StatusCaptured, true},
+		{payment.StatusAuthorized, payment.StatusCanceled, true},
+		{payment.StatusCaptured, payment.StatusDisputed, true},
+		{payment.StatusDisputed, payment.StatusCaptured, true},
+		{payment.StatusPartiallyRefunded, payment.StatusPartiallyRefunded, true},
+		{payment.StatusCaptured, payment.StatusAuthorized, false},
+		{payment.StatusRefunded, payment.StatusCaptured, false},
+		{payment.StatusCanceled, payment.StatusCaptured, false},
+		{payment.StatusFailed, payment.StatusAuthorized, false},
+		{payment.StatusAuthorized, payment.StatusPending, false},
+	}
+
+	for _, c := range cases {
+		if allowed := c.from.CanTransitionTo(c.to); allowed != c.allowed {
+			t.Errorf("expected %s to %s allowed to be %v", c.from, c.to, c.allowed)
+		}
+	}
+
+	refunded := &payment.Payment{Status: payment.StatusRefunded}
+	if err := refunded.Transition(payment.StatusCaptured); !errors.Is(err, payment.ErrInvalidTransition) || refunded.Status != payment.StatusRefunded {
+		t.Fatalf("expected the transition to be refused, got %v and %s", err, refunded.Status)
+	}
+}
+
+func TestHandleProviderEvent_IgnoresStatusGoingBackwards(t *testing.T) {
+	repository := newMemoryRepository()
+	stored := chargedPayment(t, repository, "stripe")
+	stored.Status = payment.StatusRefunded
+	stored.RefundedAmount = usd("10.00")
+	repository.payments[stored.ID] = *stored
+
+	service := payment.New(repository, payment.NewRegistry())
+	outcome, err := service.HandleProviderEvent(context.Background(), payment.ProviderEvent{
+		ID:        "evt_late",
+		Provider:  "stripe",
+		PaymentID: stored.ID,
+		Result:    payment.ChargeResult{ProviderPaymentID: "charge-1", Status: payment.StatusCaptured, CapturedAmount: usd("10.00"), RefundedAmount: usd("0")},
+	})
+	if err != nil || outcome != payment.ProviderEventIgnored {
+		t.Fatalf("expected the late capture to be ignored, got %s %v", outcome, err)
+	}
+
+	if unchanged, _ := repository.FindByID(context.Background(), stored.ID); unchanged.Status != payment.StatusRefunded {
+		t.Fatalf("expected the payment to stay refunded, got %s", unchanged.Status)
+	}
+}
+
+func TestMemoryRepository_UpdateRefusesInvalidTransition(t *testing.T) {
+	repository := newMemoryRepository()
+	stored := chargedPayment(t, repository, "paypal")
+	stored.Status = payment.StatusPending
+
+	if err := repository.Update(context.Background(), stored); !errors.Is(err, payment.ErrInvalidTransition) {
+		t.Fatalf("expected the update to be refused, got %v", err)
+	}
+}
+
+func TestPaymentHandler_StatusHistory(t *testing.T) {
+	e := newPaymentServer(newMemoryRepository(), paypalProvider.New())
+
+	rec := doPaymentRequest(e, http.MethodPost, "/payments", cardPaymentBody("30", "USD", `,"capture":false`))
+	var created outbound.PaymentResponse
+	decodeBody(t, rec, &created)
+
+	if rec = doPaymentRequest(e, http.MethodPost, "/payments/"+created.ID+"/capture", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected capture to succeed, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec = doPaymentRequest(e, http.MethodPost, "/payments/"+created.ID+"/refunds", `{"amount":10}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected refund to succeed, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = doPaymentRequest(e, http.MethodGet, "/payments/"+created.ID+"/events", "")
+	var history []outbound.StatusChange
+	decodeBody(t, rec, &history)
+
+	expected := []outbound.StatusChange{
+		{To: "pending"},
+		{From: "pending", To: "authorized"},
+		{From: "authorized", To: "captured"},
+		{From: "captured", To: "partially_refunded"},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d status changes, got %s", len(expected), rec.Body)
+	}
+
+	for i, change := range history {
+		if change.From != expected[i].From || change.To != expected[i].To {
+			t.Fatalf("expected change %d to be %+v, got %+v", i, expected[i], change)
+		}
+	}
+
+	if rec = doPaymentRequest(e, http.MethodGet, "/payments/missing/events", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
@@ -1,121 +1,258 @@
 package test
 
 import (
+	"context"
+	"errors"
+	"io"
 	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
-	"lucassaraiva5/api-pay/internal/app/domain/stripe"
-	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
 
 	"github.com/google/uuid"
 )
 
-func TestStripe_CreatePayment_Success(t *testing.T) {
+func TestStripe_Charge_Success(t *testing.T) {
 	provider := stripeProvider.New()
 
-	request := &stripe.PaymentRequest{
-		Amount:   100.0,
-		Currency: "USD",
+	request := payment.ChargeRequest{
+		Amount: usd("100.00"),
 	}
 
-	response, err := provider.CreatePayment(request)
+	result, err := provider.Charge(context.Background(), request)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if response == nil {
-		t.Fatalf("expected a valid response, got nil")
+	if result.Status != payment.StatusCaptured {
+		t.Fatalf("expected status to be captured, got %s", result.Status)
 	}
 
-	payment := response.(*outbound.StripePaymentResponse)
-	if payment.Status != "paid" {
-		t.Fatalf("expected status to be paid, got %s", payment.Status)
+	if result.Amount != usd("100.00") {
+		t.Fatalf("expected amount to be 100.0, got %v", result.Amount)
 	}
 
-	if _, err := uuid.Parse(payment.ID); err != nil {
-		t.Fatalf("expected a valid UUID, got %s", payment.ID)
+	if _, err := uuid.Parse(result.ProviderPaymentID); err != nil {
+		t.Fatalf("expected a valid UUID, got %s", result.ProviderPaymentID)
 	}
 }
 
-func TestStripe_CreatePayment_InvalidRequest(t *testing.T) {
+func TestStripe_Refund_Success(t *testing.T) {
 	provider := stripeProvider.New()
 
-	request := struct {
-		InvalidField string
-	}{
-		InvalidField: "invalid",
+	request := payment.ChargeRequest{
+		Amount: usd("100.00"),
 	}
+	charge, _ := provider.Charge(context.Background(), request)
 
-	_, err := provider.CreatePayment(request)
-	if err == nil {
-		t.Fatalf("expected an error, got nil")
+	refunded, err := provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: charge.ProviderPaymentID})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if err.Error() != "invalid request type for Stripe" {
-		t.Fatalf("expected error 'invalid request type for Stripe', got %v", err)
+	if refunded.Status != payment.StatusRefunded {
+		t.Fatalf("expected voided status to map to refunded, got %s", refunded.Status)
+	}
+
+	if refunded.Amount != usd("0.00") {
+		t.Fatalf("expected remaining amount to be 0.0, got %v", refunded.Amount)
 	}
 }
 
-func TestStripe_Refund_Success(t *testing.T) {
+func TestStripe_Refund_Partial(t *testing.T) {
 	provider := stripeProvider.New()
+	charge, _ := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("100.00")})
 
-	request := &stripe.PaymentRequest{
-		Amount:   100.0,
-		Currency: "USD",
-	}
-	response, _ := provider.CreatePayment(request)
-	payment := response.(*outbound.StripePaymentResponse)
-
-	refundResponse, err := provider.Refund(payment.ID)
+	refunded, err := provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: charge.ProviderPaymentID, Amount: usd("30.00"), Reason: "damaged"})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	refundedPayment := refundResponse.(*outbound.StripePaymentResponse)
-	if refundedPayment.Status != "voided" {
-		t.Fatalf("expected status to be voided, got %s", refundedPayment.Status)
+	if refunded.Status != payment.StatusPartiallyRefunded || refunded.Amount != usd("70.00") || refunded.RefundedAmount != usd("30.00") {
+		t.Fatalf("expected 30.0 of 100.0 to be voided, got %+v", refunded)
+	}
+
+	_, err = provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: charge.ProviderPaymentID, Amount: usd("80.00")})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorInvalidRequest {
+		t.Fatalf("expected voiding more than the balance to be rejected, got %v", err)
+	}
+}
+
+func TestStripe_AuthorizeAndCapture(t *testing.T) {
+	provider := stripeProvider.New()
+	authorization, err := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("100.00"), AuthorizeOnly: true})
+	if err != nil || authorization.Status != payment.StatusAuthorized || authorization.CapturedAmount != usd("0.00") {
+		t.Fatalf("expected an authorization awaiting capture, got %+v %v", authorization, err)
+	}
+
+	captured, err := provider.Capture(context.Background(), payment.CaptureRequest{ProviderPaymentID: authorization.ProviderPaymentID, Amount: usd("40.00")})
+	if err != nil || captured.Status != payment.StatusCaptured || captured.CapturedAmount != usd("40.00") || captured.RefundedAmount != usd("0.00") {
+		t.Fatalf("expected 40.0 to be captured, got %+v %v", captured, err)
 	}
 
-	if refundedPayment.Amount != 0.0 {
-		t.Fatalf("expected remaining amount to be 0.0, got %f", refundedPayment.Amount)
+	_, err = provider.Cancel(context.Background(), payment.CancelRequest{ProviderPaymentID: authorization.ProviderPaymentID})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorInvalidRequest {
+		t.Fatalf("expected cancel of a captured charge to be rejected, got %v", err)
+	}
+}
+
+func TestStripe_CancelAuthorization(t *testing.T) {
+	provider := stripeProvider.New()
+	authorization, _ := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("100.00"), AuthorizeOnly: true})
+
+	expired, err := provider.Cancel(context.Background(), payment.CancelRequest{ProviderPaymentID: authorization.ProviderPaymentID, Reason: payment.CancelReasonExpired})
+	if err != nil || expired.Status != payment.StatusCanceled {
+		t.Fatalf("expected authorization to be released as expired, got %+v %v", expired, err)
+	}
+
+	_, err = provider.Capture(context.Background(), payment.CaptureRequest{ProviderPaymentID: authorization.ProviderPaymentID})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorInvalidRequest {
+		t.Fatalf("expected capture of an expired authorization to be rejected, got %v", err)
 	}
 }
 
 func TestStripe_Refund_PaymentNotFound(t *testing.T) {
 	provider := stripeProvider.New()
 
-	_, err := provider.Refund("invalid-id")
+	_, err := provider.Refund(context.Background(), payment.RefundRequest{ProviderPaymentID: "invalid-id"})
 	if err == nil {
 		t.Fatalf("expected an error, got nil")
 	}
 }
 
-func TestStripe_GetPayment_Success(t *testing.T) {
+func TestStripe_Get_Success(t *testing.T) {
 	provider := stripeProvider.New()
 
-	request := &stripe.PaymentRequest{
-		Amount:   100.0,
-		Currency: "USD",
+	request := payment.ChargeRequest{
+		Amount: usd("100.00"),
 	}
-	response, _ := provider.CreatePayment(request)
-	payment := response.(*outbound.StripePaymentResponse)
+	charge, _ := provider.Charge(context.Background(), request)
 
-	getResponse, err := provider.GetPayment(payment.ID)
+	retrieved, err := provider.Get(context.Background(), charge.ProviderPaymentID)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	retrievedPayment := getResponse.(*outbound.StripePaymentResponse)
-	if retrievedPayment.ID != payment.ID {
-		t.Fatalf("expected payment ID to match, got %s", retrievedPayment.ID)
+	if retrieved.ProviderPaymentID != charge.ProviderPaymentID {
+		t.Fatalf("expected payment ID to match, got %s", retrieved.ProviderPaymentID)
 	}
 }
 
-func TestStripe_GetPayment_PaymentNotFound(t *testing.T) {
+func TestStripe_Get_PaymentNotFound(t *testing.T) {
 	provider := stripeProvider.New()
 
-	_, err := provider.GetPayment("invalid-id")
+	_, err := provider.Get(context.Background(), "invalid-id")
 	if err == nil {
 		t.Fatalf("expected an error, got nil")
 	}
 }
+
+// newStalledServer accepts requests but never answers them until the test
+// ends, like a provider that received the charge and hangs.
+func newStalledServer(t *testing.T) *httptest.Server {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-release
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+
+	return server
+}
+
+func TestStripe_Charge_CancelledContextIsUnknownOutcome(t *testing.T) {
+	server := newStalledServer(t)
+	t.Setenv("STRIPE_MOCK_URL", server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := stripeProvider.NewWithClient(server.Client()).Charge(ctx, payment.ChargeRequest{Amount: usd("10.00")})
+	if !errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected unknown outcome error, got %v", err)
+	}
+}
+
+func TestStripe_Charge_ClientTimeoutIsUnknownOutcome(t *testing.T) {
+	server := newStalledServer(t)
+	t.Setenv("STRIPE_MOCK_URL", server.URL)
+
+	provider := stripeProvider.NewWithClient(&http.Client{Timeout: 50 * time.Millisecond})
+
+	_, err := provider.Charge(context.Background(), payment.ChargeRequest{Amount: usd("10.00")})
+	if !errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected unknown outcome error, got %v", err)
+	}
+}
+
+func TestStripe_Charge_UnsentRequestIsPlainFailure(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	t.Setenv("STRIPE_MOCK_URL", server.URL)
+
+	_, err := stripeProvider.New().Charge(context.Background(), payment.ChargeRequest{Amount: usd("10.00")})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorNetwork || errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected a network failure for a request never sent, got %v", err)
+	}
+}
+
+func TestStripe_Charge_Declined(t *testing.T) {
+	_, err := stripeProvider.New().Charge(context.Background(), payment.ChargeRequest{
+		Amount: usd("10.00"),
+		Method: payment.Method{Type: "card", Card: payment.Card{Number: "4000000000000002"}},
+	})
+	if kind, _ := payment.ErrorKindOf(err); kind != payment.ErrorDeclined {
+		t.Fatalf("expected a declined error, got %v", err)
+	}
+}
+
+func TestStripe_Charge_CancelledBeforeSendIsPlainFailure(t *testing.T) {
+	server := newStalledServer(t)
+	t.Setenv("STRIPE_MOCK_URL", server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := stripeProvider.NewWithClient(server.Client()).Charge(ctx, payment.ChargeRequest{Amount: usd("10.00")})
+	if err == nil || errors.Is(err, payment.ErrOutcomeUnknown) {
+		t.Fatalf("expected a plain failure for a request never sent, got %v", err)
+	}
+}
+
+func TestStripe_FindByReference_RecoversCharge(t *testing.T) {
+	provider := stripeProvider.New()
+	reference := uuid.New().String()
+
+	charge, err := provider.Charge(context.Background(), payment.ChargeRequest{PaymentID: reference, Amount: usd("10.00")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	found, err := provider.FindByReference(context.Background(), reference)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if found.ProviderPaymentID != charge.ProviderPaymentID {
+		t.Fatalf("expected charge %s, got %s", charge.ProviderPaymentID, found.ProviderPaymentID)
+	}
+}
+
+func TestStripe_FindByReference_NotFound(t *testing.T) {
+	_, err := stripeProvider.New().FindByReference(context.Background(), uuid.New().String())
+	if !errors.Is(err, payment.ErrChargeNotFound) {
+		t.Fatalf("expected ErrChargeNotFound, got %v", err)
+	}
+}
+
+func TestStripe_Ping(t *testing.T) {
+	if err := stripeProvider.New().Ping(context.Background()); err != nil {
+		t.Fatalf("expected provider to be healthy, got %v", err)
+	}
+}
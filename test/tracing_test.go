@@ -0,0 +1,119 @@
+package test
+
+import (
+	"context"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	stripeProvider "lucassaraiva5/api-pay/internal/app/providers/stripe"
+	"lucassaraiva5/api-pay/internal/infra/server/middleware"
+	"lucassaraiva5/api-pay/internal/infra/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider keeping every ended span in memory
+// until the test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	if err := tracing.Init(&tracing.Option{ServiceName: "api-pay-test", Exporter: tracing.ExporterNone, SampleRatio: 1}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	previous := otel.GetTracerProvider()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func endedSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	t.Fatalf("expected a span named %s", name)
+	return nil
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestTracing_ServerSpanContinuesCallerTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	e := newPaymentServer(newMemoryRepository())
+	e.Use(middleware.ConfigTracing())
+
+	req := httptest.NewRequest(http.MethodGet, "/payments/missing-payment", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	span := endedSpan(t, recorder, "GET /payments/:id")
+	if span.SpanKind() != trace.SpanKindServer || span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected a server span in the caller trace, got %s in %s", span.SpanKind(), span.SpanContext().TraceID())
+	}
+
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("expected the caller span as parent, got %s", span.Parent().SpanID())
+	}
+
+	if status := spanAttribute(span, "http.response.status_code").AsInt64(); status != http.StatusNotFound {
+		t.Fatalf("expected the rendered error status, got %d", status)
+	}
+}
+
+func TestTracing_ProviderAttemptPropagatesTraceparent(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	t.Setenv("STRIPE_MOCK_URL", server.URL)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(stripeProvider.NewWithClient(server.Client())))
+	_, err := service.ProcessPayment(ctx, &payment.Payment{Amount: usd("10.00"), Method: payment.Method{Type: "card"}})
+	parent.End()
+
+	attempt := endedSpan(t, recorder, "payment.attempt")
+	if attempt.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("expected the attempt to be a child of the request span")
+	}
+
+	if outcome := spanAttribute(attempt, "outcome").AsString(); outcome == "" || outcome != payment.Outcome(err) || attempt.Status().Code != codes.Error {
+		t.Fatalf("expected a failed attempt with its outcome, got %q %v", outcome, attempt.Status())
+	}
+
+	call := endedSpan(t, recorder, "stripe charge")
+	if call.Parent().SpanID() != attempt.SpanContext().SpanID() || call.SpanKind() != trace.SpanKindClient {
+		t.Fatal("expected the provider call to be a client span of the attempt")
+	}
+
+	expected := "00-" + call.SpanContext().TraceID().String() + "-" + call.SpanContext().SpanID().String() + "-01"
+	if traceparent != expected {
+		t.Fatalf("expected traceparent %s, got %q", expected, traceparent)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/transport/inbound"
+	"lucassaraiva5/api-pay/internal/app/transport/validation"
+	"testing"
+)
+
+func validPaymentRequest() *inbound.CreatePaymentRequest {
+	return &inbound.CreatePaymentRequest{
+		Amount:   dec("19.99"),
+		Currency: "USD",
+		Method: inbound.Method{
+			Type: "card",
+			Card: inbound.Card{Number: "4111111111111111", Holder: "John Doe", CVV: "123", Expiration: "12/2099", InstallmentNumber: 1},
+		},
+	}
+}
+
+func fieldCode(t *testing.T, err error, field string) string {
+	t.Helper()
+
+	var fields validation.Errors
+	if !errors.As(err, &fields) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	for _, f := range fields {
+		if f.Field == field {
+			return f.Code
+		}
+	}
+
+	t.Fatalf("expected an error on %s, got %v", field, fields)
+	return ""
+}
+
+func TestLuhnValid(t *testing.T) {
+	for number, valid := range map[string]bool{
+		"4111111111111111": true,
+		"378282246310005":  true,
+		"5555555555554444": true,
+		"4111111111111112": false,
+		"4111-1111-1111":   false,
+		"":                 false,
+	} {
+		if payment.LuhnValid(number) != valid {
+			t.Fatalf("expected LuhnValid(%q) to be %v", number, valid)
+		}
+	}
+}
+
+func TestCreatePaymentValidation_AcceptsValidRequest(t *testing.T) {
+	if err := validation.CreatePayment(validPaymentRequest()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	request := validPaymentRequest()
+	request.Method.Card = inbound.Card{Number: "378282246310005", Holder: "John Doe", CVV: "1234", Expiration: "12/99", InstallmentNumber: 12}
+	if err := validation.CreatePayment(request); err != nil {
+		t.Fatalf("expected amex with four digit cvv to pass, got %v", err)
+	}
+}
+
+func TestCreatePaymentValidation_RejectsInvalidFields(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*inbound.CreatePaymentRequest)
+		field  string
+		code   string
+	}{
+		{"missing currency", func(r *inbound.CreatePaymentRequest) { r.Currency = "" }, "currency", validation.CodeRequired},
+		{"unknown currency", func(r *inbound.CreatePaymentRequest) { r.Currency = "XYZ" }, "currency", validation.CodeUnsupported},
+		{"zero amount", func(r *inbound.CreatePaymentRequest) { r.Amount = dec("0") }, "amount", validation.CodeTooSmall},
+		{"below currency minimum", func(r *inbound.CreatePaymentRequest) { r.Amount = dec("0.10") }, "amount", validation.CodeTooSmall},
+		{"above currency maximum", func(r *inbound.CreatePaymentRequest) { r.Amount = dec("1000000") }, "amount", validation.CodeTooLarge},
+		{"too precise for JPY", func(r *inbound.CreatePaymentRequest) { r.Amount, r.Currency = dec("100.5"), "JPY" }, "amount", validation.CodeTooPrecise},
+		{"missing method", func(r *inbound.CreatePaymentRequest) { r.Method.Type = "" }, "method.type", validation.CodeRequired},
+		{"unsupported method", func(r *inbound.CreatePaymentRequest) { r.Method.Type = "pix" }, "method.type", validation.CodeUnsupported},
+		{"luhn failure", func(r *inbound.CreatePaymentRequest) { r.Method.Card.Number = "4111111111111112" }, "method.card.number", validation.CodeInvalid},
+		{"amex cvv", func(r *inbound.CreatePaymentRequest) { r.Method.Card.Number = "378282246310005" }, "method.card.cvv", validation.CodeInvalid},
+		{"bad expiration", func(r *inbound.CreatePaymentRequest) { r.Method.Card.Expiration = "13/2099" }, "method.card.expiration", validation.CodeInvalid},
+		{"expired card", func(r *inbound.CreatePaymentRequest) { r.Method.Card.Expiration = "01/2020" }, "method.card.expiration", validation.CodeExpired},
+		{"too many installments", func(r *inbound.CreatePaymentRequest) { r.Method.Card.InstallmentNumber = 13 }, "method.card.installmentNumber", validation.CodeInvalid},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			request := validPaymentRequest()
+			c.modify(request)
+
+			if code := fieldCode(t, validation.CreatePayment(request), c.field); code != c.code {
+				t.Fatalf("expected %s, got %s", c.code, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"lucassaraiva5/api-pay/internal/app/adapters/handler"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/vault"
+	vaultRepository "lucassaraiva5/api-pay/internal/app/repositories/vault"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/infra/database"
+	"lucassaraiva5/api-pay/internal/infra/server"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+)
+
+type memoryVaultRepository struct {
+	mu    sync.Mutex
+	cards map[string]vault.StoredCard
+}
+
+func newMemoryVaultRepository() *memoryVaultRepository {
+	return &memoryVaultRepository{cards: make(map[string]vault.StoredCard)}
+}
+
+func (r *memoryVaultRepository) Create(ctx context.Context, card *vault.StoredCard) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cards[card.ID] = *card
+	return nil
+}
+
+func (r *memoryVaultRepository) FindByID(ctx context.Context, id string) (*vault.StoredCard, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	card, ok := r.cards[id]
+	if !ok {
+		return nil, vault.ErrTokenNotFound
+	}
+
+	return &card, nil
+}
+
+func (r *memoryVaultRepository) UpdateEncryption(ctx context.Context, card *vault.StoredCard) error {
+	return r.Create(ctx, card)
+}
+
+// cardRecordingProvider accepts every charge and keeps the last card it saw.
+type cardRecordingProvider struct {
+	namedProvider
+	card payment.Card
+}
+
+func (p *cardRecordingProvider) Charge(ctx context.Context, request payment.ChargeRequest) (payment.ChargeResult, error) {
+	p.card = request.Method.Card
+	return payment.ChargeResult{ProviderPaymentID: "charge-1", Status: payment.StatusCaptured, Amount: request.Amount, CapturedAmount: request.Amount}, nil
+}
+
+func vaultKey(t *testing.T, id string) vault.Key {
+	t.Helper()
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	return vault.Key{ID: id, Secret: secret}
+}
+
+func newTestVault(t *testing.T, repository vault.Repository, keys ...vault.Key) *vault.Service {
+	t.Helper()
+
+	keyring, err := vault.NewKeyring(keys...)
+	if err != nil {
+		t.Fatalf("expected keyring, got %v", err)
+	}
+
+	store := miniredis.RunT(t)
+	cvvs := vaultRepository.NewCVVStore(database.NewRedis(&redis.Options{Addr: store.Addr()}, true))
+	return vault.New(repository, cvvs, keyring, []byte("fingerprint-key-for-tests-only!!"))
+}
+
+func testCard() payment.Card {
+	return payment.Card{Number: "4111111111111111", Holder: "John Doe", CVV: "123", Expiration: "12/2099"}
+}
+
+func TestKeyring_RejectsShortKeys(t *testing.T) {
+	if _, err := vault.NewKeyring(vault.Key{ID: "short", Secret: []byte("too short")}); err == nil {
+		t.Fatal("expected an error for a key that is not 32 bytes")
+	}
+}
+
+func TestKeyring_CiphertextIsBoundToAdditionalData(t *testing.T) {
+	keyring, _ := vault.NewKeyring(vaultKey(t, "k1"))
+
+	keyID, ciphertext, err := keyring.Encrypt([]byte("4111111111111111"), []byte("tok_a"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if plaintext, err := keyring.Decrypt(keyID, ciphertext, []byte("tok_a")); err != nil || string(plaintext) != "4111111111111111" {
+		t.Fatalf("expected round trip, got %q %v", plaintext, err)
+	}
+
+	if _, err := keyring.Decrypt(keyID, ciphertext, []byte("tok_b")); err == nil {
+		t.Fatal("expected ciphertext to fail for another token")
+	}
+
+	if _, err := keyring.Decrypt("k2", ciphertext, []byte("tok_a")); !errors.Is(err, vault.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestVault_TokenizeStoresOnlyEncryptedNumber(t *testing.T) {
+	repository := newMemoryVaultRepository()
+	service := newTestVault(t, repository, vaultKey(t, "k1"))
+
+	token, err := service.Tokenize(context.Background(), testCard())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(token.ID, "tok_") || token.Last4 != "1111" || token.Brand != payment.BrandVisa || token.Fingerprint == "" {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	stored := repository.cards[token.ID]
+	if stored.KeyID != "k1" || bytes.Contains(stored.Ciphertext, []byte("4111111111111111")) {
+		t.Fatalf("expected the number to be encrypted with k1, got %+v", stored)
+	}
+
+	again, _ := service.Tokenize(context.Background(), testCard())
+	if again.ID == token.ID || again.Fingerprint != token.Fingerprint {
+		t.Fatalf("expected a new token with the same fingerprint, got %+v and %+v", token, again)
+	}
+}
+
+func TestVault_DetokenizeHandsOutCVVOnce(t *testing.T) {
+	service := newTestVault(t, newMemoryVaultRepository(), vaultKey(t, "k1"))
+	token, _ := service.Tokenize(context.Background(), testCard())
+
+	card, err := service.Detokenize(context.Background(), token.ID)
+	if err != nil || card.Number != "4111111111111111" || card.CVV != "123" || card.Expiration != "12/2099" {
+		t.Fatalf("expected the stored card, got %+v %v", card, err)
+	}
+
+	if card, _ = service.Detokenize(context.Background(), token.ID); card.CVV != "" {
+		t.Fatalf("expected the cvv to be gone after the first charge, got %q", card.CVV)
+	}
+
+	if _, err = service.Detokenize(context.Background(), "tok_missing"); !errors.Is(err, vault.ErrTokenNotFound) {
+		t.Fatalf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestVault_RotatedKeyReencryptsOnUse(t *testing.T) {
+	repository := newMemoryVaultRepository()
+	oldKey, newKey := vaultKey(t, "k1"), vaultKey(t, "k2")
+
+	token, _ := newTestVault(t, repository, oldKey).Tokenize(context.Background(), testCard())
+
+	rotated := newTestVault(t, repository, newKey, oldKey)
+	if card, err := rotated.Detokenize(context.Background(), token.ID); err != nil || card.Number != "4111111111111111" {
+		t.Fatalf("expected the old key to still decrypt, got %+v %v", card, err)
+	}
+
+	if keyID := repository.cards[token.ID].KeyID; keyID != "k2" {
+		t.Fatalf("expected the card to be re-encrypted with k2, got %s", keyID)
+	}
+
+	if card, err := newTestVault(t, repository, newKey).Detokenize(context.Background(), token.ID); err != nil || card.Number != "4111111111111111" {
+		t.Fatalf("expected the card to decrypt without the old key, got %+v %v", card, err)
+	}
+}
+
+func TestProcessPayment_ChargesTokenizedCard(t *testing.T) {
+	vaultService := newTestVault(t, newMemoryVaultRepository(), vaultKey(t, "k1"))
+	token, _ := vaultService.Tokenize(context.Background(), testCard())
+
+	provider := &cardRecordingProvider{namedProvider: namedProvider{name: "recording"}}
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(provider))
+	service.Vault = vaultService
+
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{
+		Amount: usd("10.00"),
+		Method: payment.Method{Type: "card", Token: token.ID, Card: payment.Card{InstallmentNumber: 3}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if provider.card.Number != "4111111111111111" || provider.card.CVV != "123" || provider.card.InstallmentNumber != 3 || created.Method.Token != token.ID {
+		t.Fatalf("expected the provider to receive the vaulted card, got %+v", provider.card)
+	}
+
+	if _, err = service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00"), Method: payment.Method{Type: "card", Token: "tok_missing"}}); !errors.Is(err, vault.ErrTokenNotFound) {
+		t.Fatalf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestTokenHandler_TokenizeThenPay(t *testing.T) {
+	vaultService := newTestVault(t, newMemoryVaultRepository(), vaultKey(t, "k1"))
+	paymentService := payment.New(newMemoryRepository(), payment.NewRegistry(&cardRecordingProvider{namedProvider: namedProvider{name: "recording"}}))
+	paymentService.Vault = vaultService
+
+	services := &domain.Services{PaymentService: paymentService, VaultService: vaultService}
+	noop := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+
+	e := echo.New()
+	e.HTTPErrorHandler = server.HTTPErrorHandler
+	handler.NewPaymentHandler(services, noop, noop, noop).Configure(e)
+	handler.NewTokenHandler(services, noop, noop, noop).Configure(e)
+
+	rec := doPaymentRequest(e, http.MethodPost, "/tokens", `{"number":"4111111111111111","holder":"John Doe","cvv":"123","expiration":"12/2099"}`)
+	var token outbound.TokenResponse
+	decodeBody(t, rec, &token)
+	if rec.Code != http.StatusCreated || token.Last4 != "1111" || strings.Contains(rec.Body.String(), "4111111111111111") || strings.Contains(rec.Body.String(), `"123"`) {
+		t.Fatalf("expected a token without card data, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = doPaymentRequest(e, http.MethodPost, "/payments", `{"amount":10,"currency":"USD","method":{"type":"card","token":"`+token.ID+`","card":{"installmentNumber":1}}}`)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "4111111111111111") || !strings.Contains(rec.Body.String(), token.ID) {
+		t.Fatalf("expected a tokenized payment without card data, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = doPaymentRequest(e, http.MethodPost, "/payments", `{"amount":10,"currency":"USD","method":{"type":"card","token":"`+token.ID+`","card":{"number":"4111111111111111","installmentNumber":1}}}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected card data next to a token to be rejected, got %d", rec.Code)
+	}
+
+	rec = doPaymentRequest(e, http.MethodPost, "/tokens", `{"number":"4111111111111112","holder":"John Doe","cvv":"123","expiration":"12/2099"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected an invalid card to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestCVVStore_ExpiresCodes(t *testing.T) {
+	store := miniredis.RunT(t)
+	cvvs := vaultRepository.NewCVVStore(database.NewRedis(&redis.Options{Addr: store.Addr()}, true))
+
+	if err := cvvs.Save(context.Background(), "tok_a", "123", time.Minute); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	store.FastForward(2 * time.Minute)
+
+	if cvv, err := cvvs.Take(context.Background(), "tok_a"); err != nil || cvv != "" {
+		t.Fatalf("expected the cvv to expire, got %q %v", cvv, err)
+	}
+}
@@ -0,0 +1,465 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"lucassaraiva5/api-pay/internal/app/adapters/handler"
+	"lucassaraiva5/api-pay/internal/app/domain"
+	"lucassaraiva5/api-pay/internal/app/domain/merchant"
+	"lucassaraiva5/api-pay/internal/app/domain/payment"
+	"lucassaraiva5/api-pay/internal/app/domain/webhook"
+	"lucassaraiva5/api-pay/internal/app/transport/outbound"
+	"lucassaraiva5/api-pay/internal/app/transport/presenter"
+	"lucassaraiva5/api-pay/internal/infra/server"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type memoryWebhookRepository struct {
+	mu         sync.Mutex
+	endpoints  []webhook.Endpoint
+	deleted    []webhook.Endpoint
+	events     map[string]webhook.Event
+	deliveries map[string]webhook.Delivery
+	attempts   []webhook.Attempt
+}
+
+func newMemoryWebhookRepository() *memoryWebhookRepository {
+	return &memoryWebhookRepository{
+		events:     make(map[string]webhook.Event),
+		deliveries: make(map[string]webhook.Delivery),
+	}
+}
+
+func (r *memoryWebhookRepository) CreateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.endpoints = append(r.endpoints, *endpoint)
+	return nil
+}
+
+func (r *memoryWebhookRepository) FindEndpoint(ctx context.Context, id string) (*webhook.Endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, endpoint := range slices.Concat(r.endpoints, r.deleted) {
+		if endpoint.ID == id {
+			return &endpoint, nil
+		}
+	}
+
+	return nil, webhook.ErrEndpointNotFound
+}
+
+func (r *memoryWebhookRepository) FindEndpoints(ctx context.Context, scope merchant.Scope) ([]webhook.Endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var endpoints []webhook.Endpoint
+	for _, endpoint := range r.endpoints {
+		if endpoint.MerchantID == scope.MerchantID && endpoint.Livemode == scope.Livemode {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (r *memoryWebhookRepository) DeleteEndpoint(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, endpoint := range r.endpoints {
+		if endpoint.ID == id {
+			r.deleted = append(r.deleted, endpoint)
+			r.endpoints = append(r.endpoints[:i], r.endpoints[i+1:]...)
+			return nil
+		}
+	}
+
+	return webhook.ErrEndpointNotFound
+}
+
+func (r *memoryWebhookRepository) CreateEvent(ctx context.Context, event *webhook.Event, deliveries []webhook.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[event.ID]; ok {
+		return nil
+	}
+
+	r.events[event.ID] = *event
+	for _, delivery := range deliveries {
+		r.deliveries[delivery.ID] = delivery
+	}
+
+	return nil
+}
+
+func (r *memoryWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := make([]webhook.Job, 0)
+	for id, delivery := range r.deliveries {
+		next, _ := time.Parse(time.RFC3339, delivery.NextAttemptAt)
+		if delivery.Status != webhook.DeliveryPending || next.After(now) || len(jobs) == limit {
+			continue
+		}
+
+		delivery.NextAttemptAt = now.Add(lease).Format(time.RFC3339)
+		r.deliveries[id] = delivery
+
+		for _, endpoint := range r.endpoints {
+			if endpoint.ID == delivery.EndpointID {
+				jobs = append(jobs, webhook.Job{Delivery: delivery, Event: r.events[delivery.EventID], URL: endpoint.URL, Secret: endpoint.Secret})
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+func (r *memoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery *webhook.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deliveries[delivery.ID]; !ok {
+		return webhook.ErrDeliveryNotFound
+	}
+
+	stored := *delivery
+	stored.Log = nil
+	r.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (r *memoryWebhookRepository) FindDelivery(ctx context.Context, id string) (*webhook.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, webhook.ErrDeliveryNotFound
+	}
+
+	return &delivery, nil
+}
+
+func (r *memoryWebhookRepository) FindDeliveries(ctx context.Context, endpointID string, limit int) ([]webhook.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := make([]webhook.Delivery, 0)
+	for _, delivery := range r.deliveries {
+		if delivery.EndpointID == endpointID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt > deliveries[j].CreatedAt })
+	return deliveries[:min(limit, len(deliveries))], nil
+}
+
+func (r *memoryWebhookRepository) AddAttempt(ctx context.Context, attempt *webhook.Attempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts = append(r.attempts, *attempt)
+	return nil
+}
+
+func (r *memoryWebhookRepository) FindAttempts(ctx context.Context, deliveryID string) ([]webhook.Attempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := make([]webhook.Attempt, 0)
+	for _, attempt := range r.attempts {
+		if attempt.DeliveryID == deliveryID {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return attempts, nil
+}
+
+// onlyDelivery returns the single delivery stored in the repository.
+func (r *memoryWebhookRepository) onlyDelivery(t *testing.T) webhook.Delivery {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.deliveries) != 1 {
+		t.Fatalf("expected a single delivery, got %d", len(r.deliveries))
+	}
+
+	for _, delivery := range r.deliveries {
+		return delivery
+	}
+
+	return webhook.Delivery{}
+}
+
+// dueNow makes the delivery due again, as if its backoff had elapsed.
+func (r *memoryWebhookRepository) dueNow(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery := r.deliveries[id]
+	delivery.NextAttemptAt = time.Now().UTC().Format(time.RFC3339)
+	r.deliveries[id] = delivery
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver answers every delivery with status and keeps what it got.
+func webhookReceiver(t *testing.T, status int) (*httptest.Server, chan receivedWebhook) {
+	t.Helper()
+
+	received := make(chan receivedWebhook, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.Close)
+
+	return receiver, received
+}
+
+func newTestWebhooks(repository webhook.Repository) *webhook.Service {
+	service := webhook.New(repository, &http.Client{Timeout: time.Second})
+	service.Render = func(p *payment.Payment) any { return presenter.Payment(p) }
+	return service
+}
+
+func TestWebhooks_SignedEventDeliveredToSubscribedEndpoints(t *testing.T) {
+	receiver, received := webhookReceiver(t, http.StatusOK)
+	repository := newMemoryWebhookRepository()
+	webhooks := newTestWebhooks(repository)
+
+	subscribed, _ := webhooks.CreateEndpoint(context.Background(), receiver.URL, []string{payment.EventSucceeded})
+	_, _ = webhooks.CreateEndpoint(context.Background(), receiver.URL, []string{payment.EventRefunded})
+
+	service := payment.New(newMemoryRepository(), payment.NewRegistry(&cardRecordingProvider{namedProvider: namedProvider{name: "recording"}}))
+	service.Events = webhooks
+
+	created, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00"), Method: payment.Method{Type: "card", Card: testCard()}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if published, err := service.PublishEvents(context.Background(), 10); err != nil || published != 1 {
+		t.Fatalf("expected the stored event to be published, got %d %v", published, err)
+	}
+
+	if delivered, err := webhooks.Dispatch(context.Background(), 10); err != nil || delivered != 1 {
+		t.Fatalf("expected one delivery, got %d %v", delivered, err)
+	}
+
+	got := <-received
+	if err = webhook.Verify(subscribed.Secret, got.header.Get(webhook.SignatureHeader), got.body, time.Minute, time.Now()); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+
+	var event struct {
+		Type string                   `json:"type"`
+		Data outbound.PaymentResponse `json:"data"`
+	}
+	if err = json.Unmarshal(got.body, &event); err != nil || event.Type != payment.EventSucceeded || event.Data.ID != created.ID || event.Data.Method.Card.Last4 != "1111" {
+		t.Fatalf("unexpected event %s: %v", got.body, err)
+	}
+
+	if delivery := repository.onlyDelivery(t); delivery.Status != webhook.DeliverySucceeded || delivery.Attempts != 1 || delivery.EndpointID != subscribed.ID {
+		t.Fatalf("expected a succeeded delivery, got %+v", delivery)
+	}
+}
+
+func TestWebhooks_PaymentEventsPublishedOnce(t *testing.T) {
+	receiver, _ := webhookReceiver(t, http.StatusOK)
+	repository := newMemoryWebhookRepository()
+	webhooks := newTestWebhooks(repository)
+	_, _ = webhooks.CreateEndpoint(context.Background(), receiver.URL, nil)
+
+	payments := newMemoryRepository()
+	service := payment.New(payments, payment.NewRegistry(&cardRecordingProvider{namedProvider: namedProvider{name: "recording"}}))
+	service.Events = webhooks
+
+	if _, err := service.ProcessPayment(context.Background(), &payment.Payment{Amount: usd("10.00"), Method: payment.Method{Type: "card", Card: testCard()}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events, _ := payments.ClaimEvents(context.Background(), time.Now().Add(time.Hour), time.Minute, 10)
+	if len(events) != 1 || events[0].Payment.Method.Card.Number != "411111******1111" || events[0].Payment.Method.Card.CVV != "" {
+		t.Fatalf("expected one stored event with a masked card, got %+v", events)
+	}
+
+	// The event was claimed above, as if its publisher had stopped before
+	// marking it published. Once the claim runs out it is published again.
+	_ = webhooks.Publish(context.Background(), &events[0])
+	if published, _ := service.PublishEvents(context.Background(), 10); published != 0 {
+		t.Fatalf("expected the claimed event to be left alone, got %d", published)
+	}
+
+	payments.expireClaims()
+	if published, _ := service.PublishEvents(context.Background(), 10); published != 1 {
+		t.Fatalf("expected the event to be published again, got %d", published)
+	}
+
+	if published, _ := service.PublishEvents(context.Background(), 10); published != 0 {
+		t.Fatalf("expected a published event not to be published again, got %d", published)
+	}
+
+	repository.onlyDelivery(t)
+}
+
+func TestWebhooks_FailedDeliveryBacksOffUntilAttemptsRunOut(t *testing.T) {
+	receiver, _ := webhookReceiver(t, http.StatusInternalServerError)
+	repository := newMemoryWebhookRepository()
+	webhooks := newTestWebhooks(repository)
+	webhooks.MaxAttempts = 2
+	webhooks.Backoff = time.Hour
+
+	_, _ = webhooks.CreateEndpoint(context.Background(), receiver.URL, nil)
+	_ = webhooks.Publish(context.Background(), &payment.Event{ID: "event-1", Type: payment.EventFailed, Payment: &payment.Payment{ID: "payment-1", Amount: usd("10.00")}})
+
+	_, _ = webhooks.Dispatch(context.Background(), 10)
+	delivery := repository.onlyDelivery(t)
+	next, _ := time.Parse(time.RFC3339, delivery.NextAttemptAt)
+	if delivery.Status != webhook.DeliveryPending || delivery.Attempts != 1 || time.Until(next) < 59*time.Minute || delivery.LastError == "" {
+		t.Fatalf("expected a retry in an hour, got %+v", delivery)
+	}
+
+	if delivered, _ := webhooks.Dispatch(context.Background(), 10); delivered != 0 || repository.onlyDelivery(t).Attempts != 1 {
+		t.Fatal("expected no send before the backoff elapsed")
+	}
+
+	repository.dueNow(delivery.ID)
+	_, _ = webhooks.Dispatch(context.Background(), 10)
+	if delivery = repository.onlyDelivery(t); delivery.Status != webhook.DeliveryFailed || delivery.Attempts != 2 {
+		t.Fatalf("expected the delivery to fail after 2 attempts, got %+v", delivery)
+	}
+
+	logged, _ := webhooks.Delivery(context.Background(), delivery.ID)
+	if len(logged.Log) != 2 || logged.Log[0].ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("expected both attempts in the log, got %+v", logged.Log)
+	}
+
+	redelivered, err := webhooks.Redeliver(context.Background(), delivery.ID)
+	if err != nil || redelivered.Status != webhook.DeliveryPending || redelivered.Attempts != 0 {
+		t.Fatalf("expected the delivery to be queued again, got %+v %v", redelivered, err)
+	}
+}
+
+func TestWebhookClient_RefusesNonPublicAddresses(t *testing.T) {
+	receiver, received := webhookReceiver(t, http.StatusOK)
+	repository := newMemoryWebhookRepository()
+	webhooks := newTestWebhooks(repository)
+	webhooks.Client = webhook.NewClient(time.Second)
+
+	_, _ = webhooks.CreateEndpoint(context.Background(), receiver.URL, nil)
+	_ = webhooks.Publish(context.Background(), &payment.Event{ID: "event-1", Type: payment.EventSucceeded, Payment: &payment.Payment{ID: "payment-1", Amount: usd("10.00")}})
+
+	if delivered, _ := webhooks.Dispatch(context.Background(), 10); delivered != 0 || len(received) != 0 {
+		t.Fatal("expected no delivery to a loopback address")
+	}
+
+	if delivery := repository.onlyDelivery(t); !strings.Contains(delivery.LastError, webhook.ErrPrivateAddress.Error()) {
+		t.Fatalf("expected the address to be refused, got %q", delivery.LastError)
+	}
+
+	for _, url := range []string{"http://10.0.0.1/hooks", "http://169.254.169.254/latest/meta-data", "http://[::1]/hooks", "http://0.0.0.0/hooks"} {
+		request, _ := http.NewRequest(http.MethodPost, url, nil)
+		if _, err := webhooks.Client.Do(request); !errors.Is(err, webhook.ErrPrivateAddress) {
+			t.Fatalf("expected %s to be refused, got %v", url, err)
+		}
+	}
+}
+
+func TestWebhookSignature_RejectsTamperingAndReplays(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	signedAt := time.Now()
+	header := webhook.Sign("whsec_test", signedAt, body)
+
+	if err := webhook.Verify("whsec_test", header, []byte(`{"id":"evt_2"}`), time.Minute, signedAt); err == nil {
+		t.Fatal("expected a changed body to be rejected")
+	}
+
+	if err := webhook.Verify("whsec_other", header, body, time.Minute, signedAt); err == nil {
+		t.Fatal("expected another secret to be rejected")
+	}
+
+	if err := webhook.Verify("whsec_test", header, body, time.Minute, signedAt.Add(10*time.Minute)); err == nil {
+		t.Fatal("expected an old signature to be rejected")
+	}
+}
+
+func TestWebhookHandler_ManagesEndpointsAndRedeliveries(t *testing.T) {
+	repository := newMemoryWebhookRepository()
+	webhooks := newTestWebhooks(repository)
+
+	e := echo.New()
+	e.HTTPErrorHandler = server.HTTPErrorHandler
+	handler.NewWebhookHandler(&domain.Services{WebhookService: webhooks}, func(next echo.HandlerFunc) echo.HandlerFunc { return next }).Configure(e)
+
+	rec := doPaymentRequest(e, http.MethodPost, "/webhooks/endpoints", `{"url":"https://merchant.example/hooks","events":["payment.refunded"]}`)
+	var endpoint outbound.WebhookEndpointResponse
+	decodeBody(t, rec, &endpoint)
+	if rec.Code != http.StatusCreated || endpoint.Secret == "" || len(endpoint.Events) != 1 {
+		t.Fatalf("expected the endpoint with its secret, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = doPaymentRequest(e, http.MethodGet, "/webhooks/endpoints", "")
+	var endpoints []outbound.WebhookEndpointResponse
+	decodeBody(t, rec, &endpoints)
+	if len(endpoints) != 1 || endpoints[0].Secret != "" {
+		t.Fatalf("expected the endpoint listed without its secret, got %s", rec.Body)
+	}
+
+	rec = doPaymentRequest(e, http.MethodPost, "/webhooks/endpoints", `{"url":"ftp://merchant.example","events":["payment.unknown"]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected invalid endpoints to be rejected, got %d", rec.Code)
+	}
+
+	rec = doPaymentRequest(e, http.MethodPost, "/webhooks/endpoints", `{"url":"http://merchant.example/hooks"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected plain http endpoints to be rejected, got %d", rec.Code)
+	}
+
+	_ = webhooks.Publish(context.Background(), &payment.Event{ID: "event-1", Type: payment.EventRefunded, Payment: &payment.Payment{ID: "payment-1", Amount: usd("10.00")}})
+	delivery := repository.onlyDelivery(t)
+
+	rec = doPaymentRequest(e, http.MethodGet, "/webhooks/endpoints/"+endpoint.ID+"/deliveries", "")
+	var deliveries []outbound.WebhookDeliveryResponse
+	decodeBody(t, rec, &deliveries)
+	if len(deliveries) != 1 || deliveries[0].ID != delivery.ID {
+		t.Fatalf("expected the endpoint delivery, got %s", rec.Body)
+	}
+
+	if rec = doPaymentRequest(e, http.MethodPost, "/webhooks/deliveries/"+delivery.ID+"/redeliver", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected redelivery to be accepted, got %d", rec.Code)
+	}
+
+	if rec = doPaymentRequest(e, http.MethodPost, "/webhooks/deliveries/missing/redeliver", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown delivery to be not found, got %d", rec.Code)
+	}
+
+	if rec = doPaymentRequest(e, http.MethodDelete, "/webhooks/endpoints/"+endpoint.ID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the endpoint to be deleted, got %d", rec.Code)
+	}
+}